@@ -0,0 +1,273 @@
+package rencode
+
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// benchNode is used to benchmark deeply nested values
+type benchNode struct {
+	Value int64      `rencode:"value"`
+	Child *benchNode `rencode:"child"`
+}
+
+type benchCase struct {
+	name  string
+	value interface{}
+}
+
+// benchCases returns the values used by the benchmarks, all of which can also be
+// encoded with encoding/json and encoding/gob
+func benchCases() []benchCase {
+	fixed := make([]int64, LIST_FIXED_COUNT-1)
+	for i := range fixed {
+		fixed[i] = int64(i * 1000)
+	}
+	open := make([]int64, 1000)
+	for i := range open {
+		open[i] = int64(i * 1000)
+	}
+	large := make([]byte, 1<<20)
+	for i := range large {
+		large[i] = byte(i)
+	}
+	var nested *benchNode
+	for i := 0; i < 100; i++ {
+		nested = &benchNode{Value: int64(i), Child: nested}
+	}
+
+	return []benchCase{
+		{"Int", int64(123456)},
+		{"Float", 1234.5},
+		{"Bool", true},
+		{"String", "fööbar"},
+		{"FixedList", fixed},
+		{"OpenList", open},
+		{"LargeBytes", large},
+		{"Nested", nested},
+		{"TorrentStatus", benchTorrentStatus()},
+	}
+}
+
+// benchTorrentStatus returns a payload similar to the torrent status sent by Deluge
+func benchTorrentStatus() torrentStatus {
+	status := torrentStatus{
+		Name:     "debian-12.5.0-amd64-netinst.iso",
+		Size:     659554304,
+		Ratio:    2.75,
+		Trackers: map[string]int16{},
+		Labels:   []string{"linux", "iso"},
+		Parent:   &torrentStatus{Name: "debian"},
+		Hash:     [4]byte{0xde, 0xad, 0xbe, 0xef},
+		Files:    map[int8][]string{},
+	}
+	for i := 0; i < 50; i++ {
+		status.Peers = append(status.Peers, peer{IP: "10.0.0.1", Progress: float32(i) / 50, Client: []byte("Deluge 2.1.1")})
+	}
+	for _, tracker := range []string{"udp://tracker.debian.org:6969", "http://bttracker.debian.org:6969/announce"} {
+		status.Trackers[tracker] = int16(len(tracker))
+	}
+	for i := int8(0); i < 10; i++ {
+		status.Files[i] = []string{"debian", "pool", "main"}
+	}
+	return status
+}
+
+func BenchmarkEncode(b *testing.B) {
+	for _, c := range benchCases() {
+		c := c
+		b.Run(c.name+"/rencode", func(b *testing.B) {
+			var buf bytes.Buffer
+			e := NewEncoder(&buf)
+			benchmarkEncode(b, &buf, func() error { return e.Encode(c.value) })
+		})
+		b.Run(c.name+"/json", func(b *testing.B) {
+			var buf bytes.Buffer
+			e := json.NewEncoder(&buf)
+			benchmarkEncode(b, &buf, func() error { return e.Encode(c.value) })
+		})
+		b.Run(c.name+"/gob", func(b *testing.B) {
+			var buf bytes.Buffer
+			e := gob.NewEncoder(&buf)
+			benchmarkEncode(b, &buf, func() error { return e.Encode(c.value) })
+		})
+	}
+}
+
+// benchmarkEncode calls encode b.N times, reporting the throughput of the bytes written on buf by each call
+func benchmarkEncode(b *testing.B, buf *bytes.Buffer, encode func() error) {
+	err := encode()
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(buf.Len()))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		err = encode()
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecode(b *testing.B) {
+	for _, c := range benchCases() {
+		c := c
+		typ := reflect.TypeOf(c.value)
+		b.Run(c.name+"/rencode", func(b *testing.B) {
+			data, err := Marshal(c.value)
+			if err != nil {
+				b.Fatal(err)
+			}
+			benchmarkDecode(b, len(data), func() error {
+				return Unmarshal(data, reflect.New(typ).Interface())
+			})
+		})
+		b.Run(c.name+"/json", func(b *testing.B) {
+			data, err := json.Marshal(c.value)
+			if err != nil {
+				b.Fatal(err)
+			}
+			benchmarkDecode(b, len(data), func() error {
+				return json.Unmarshal(data, reflect.New(typ).Interface())
+			})
+		})
+		b.Run(c.name+"/gob", func(b *testing.B) {
+			// each value is a whole gob stream, type information included
+			var buf bytes.Buffer
+			err := gob.NewEncoder(&buf).Encode(c.value)
+			if err != nil {
+				b.Fatal(err)
+			}
+			data := buf.Bytes()
+			benchmarkDecode(b, len(data), func() error {
+				return gob.NewDecoder(bytes.NewReader(data)).Decode(reflect.New(typ).Interface())
+			})
+		})
+	}
+}
+
+// BenchmarkDecodeNext measures decoding into the generic types (List, Dictionary, etc.)
+func BenchmarkDecodeNext(b *testing.B) {
+	for _, c := range benchCases() {
+		data, err := Marshal(c.value)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(c.name, func(b *testing.B) {
+			r := bytes.NewReader(data)
+			d := NewDecoder(r)
+			benchmarkDecode(b, len(data), func() error {
+				r.Reset(data)
+				d.Reset(r)
+				_, err := d.DecodeNext()
+				return err
+			})
+		})
+	}
+}
+
+// BenchmarkEncodeContainers compares lists and dictionaries with the length embedded in the
+// typecode to the shortest ones terminated by CHR_TERM
+func BenchmarkEncodeContainers(b *testing.B) {
+	var fixedList, openList List
+	for i := 0; i < LIST_FIXED_COUNT; i++ {
+		if i < LIST_FIXED_COUNT-1 {
+			fixedList.Add(int8(i))
+		}
+		openList.Add(int8(i))
+	}
+	var fixedDict, openDict Dictionary
+	for i := 0; i < DICT_FIXED_COUNT; i++ {
+		if i < DICT_FIXED_COUNT-1 {
+			fixedDict.Add(int8(i), int8(i))
+		}
+		openDict.Add(int8(i), int8(i))
+	}
+
+	for _, c := range []benchCase{
+		{"FixedList", fixedList},
+		{"OpenList", openList},
+		{"FixedDict", fixedDict},
+		{"OpenDict", openDict},
+	} {
+		c := c
+		b.Run(c.name, func(b *testing.B) {
+			var buf bytes.Buffer
+			e := NewEncoder(&buf)
+			benchmarkEncode(b, &buf, func() error { return e.Encode(c.value) })
+		})
+	}
+}
+
+// BenchmarkHooks measures the cost of reporting values to a Metrics collector
+func BenchmarkHooks(b *testing.B) {
+	value := benchTorrentStatus()
+	data, err := Marshal(value)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, c := range []struct {
+		name string
+		hook func() Hook
+	}{
+		{"None", func() Hook { return nil }},
+		{"Metrics", new(Metrics).Hook},
+	} {
+		c := c
+		b.Run(c.name+"/Encode", func(b *testing.B) {
+			var buf bytes.Buffer
+			e := NewEncoder(&buf)
+			e.SetHook(c.hook())
+			benchmarkEncode(b, &buf, func() error { return e.Encode(value) })
+		})
+		b.Run(c.name+"/DecodeNext", func(b *testing.B) {
+			r := bytes.NewReader(data)
+			d := NewDecoder(r)
+			d.SetHook(c.hook())
+			benchmarkDecode(b, len(data), func() error {
+				r.Reset(data)
+				d.Reset(r)
+				_, err := d.DecodeNext()
+				return err
+			})
+		})
+	}
+}
+
+// benchmarkDecode calls decode b.N times, reporting the throughput over size bytes per call
+func benchmarkDecode(b *testing.B, size int, decode func() error) {
+	b.SetBytes(int64(size))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := decode()
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
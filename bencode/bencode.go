@@ -0,0 +1,402 @@
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+/*
+Package bencode implements bencode (https://en.wikipedia.org/wiki/Bencode) using the same
+value model as package rencode: rencode.List, rencode.Dictionary, []byte and integers.
+
+Values can be converted between the two formats with RencodeToBencode and BencodeToRencode,
+which stream tokens from one format to the other without decoding whole values.
+*/
+package bencode
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strconv"
+
+	"github.com/gdm85/go-rencode"
+)
+
+// Encoder implements a bencode encoder
+type Encoder struct {
+	w io.Writer
+}
+
+// NewEncoder returns a bencode encoder that writes on specified Writer
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w}
+}
+
+// Encode encodes any of the following supported types:
+// * rencode.List, *rencode.List
+// * rencode.Dictionary, *rencode.Dictionary (keys must be strings or byte slices and are written sorted)
+// * []byte, string
+// * int8, int16, int32, int64, int, uint8, uint16, uint32, uint64, uint
+// * big.Int, *big.Int
+func (e *Encoder) Encode(data interface{}) error {
+	switch x := data.(type) {
+	case *rencode.List:
+		return e.Encode(*x)
+	case *rencode.Dictionary:
+		return e.Encode(*x)
+	case rencode.List:
+		_, err := e.w.Write([]byte{'l'})
+		if err != nil {
+			return err
+		}
+		for _, v := range x.Values() {
+			err = e.Encode(v)
+			if err != nil {
+				return err
+			}
+		}
+		return e.writeEnd()
+	case rencode.Dictionary:
+		pairs := make([]pair, x.Length())
+		keys := x.Keys()
+		for i, v := range x.Values() {
+			key, err := keyBytes(keys[i])
+			if err != nil {
+				return err
+			}
+			pairs[i] = pair{key, v}
+		}
+		err := sortPairs(pairs)
+		if err != nil {
+			return err
+		}
+
+		_, err = e.w.Write([]byte{'d'})
+		if err != nil {
+			return err
+		}
+		for _, p := range pairs {
+			err = e.writeBytes(p.key)
+			if err != nil {
+				return err
+			}
+			err = e.Encode(p.value)
+			if err != nil {
+				return err
+			}
+		}
+		return e.writeEnd()
+	case []byte:
+		return e.writeBytes(x)
+	case string:
+		return e.writeBytes([]byte(x))
+	case int8, int16, int32, int64, int, uint8, uint16, uint32, uint64, uint:
+		return e.writeInt(fmt.Sprintf("%d", x))
+	case big.Int:
+		return e.writeInt(x.String())
+	case *big.Int:
+		return e.writeInt(x.String())
+	}
+
+	return fmt.Errorf("could not encode data of type %T", data)
+}
+
+type pair struct {
+	key   []byte
+	value interface{}
+}
+
+// sortPairs sorts pairs by raw key bytes, as required by bencode, and rejects duplicate keys
+func sortPairs(pairs []pair) error {
+	sort.Slice(pairs, func(i, j int) bool {
+		return bytes.Compare(pairs[i].key, pairs[j].key) < 0
+	})
+	for i := 1; i < len(pairs); i++ {
+		if bytes.Equal(pairs[i-1].key, pairs[i].key) {
+			return rencode.ErrKeyAlreadyExists
+		}
+	}
+	return nil
+}
+
+func keyBytes(key interface{}) ([]byte, error) {
+	switch x := key.(type) {
+	case []byte:
+		return x, nil
+	case string:
+		return []byte(x), nil
+	}
+	return nil, fmt.Errorf("dictionary key of type %T cannot be represented in bencode", key)
+}
+
+func (e *Encoder) writeBytes(b []byte) error {
+	_, err := fmt.Fprintf(e.w, "%d:", len(b))
+	if err != nil {
+		return err
+	}
+	_, err = e.w.Write(b)
+	return err
+}
+
+func (e *Encoder) writeInt(s string) error {
+	_, err := fmt.Fprintf(e.w, "i%se", s)
+	return err
+}
+
+func (e *Encoder) writeEnd() error {
+	_, err := e.w.Write([]byte{'e'})
+	return err
+}
+
+// Decoder implements a bencode decoder
+type Decoder struct {
+	r        *bufio.Reader
+	depth    int
+	maxDepth int
+}
+
+// DefaultMaxDepth is the default maximum nesting depth of lists and dictionaries accepted by a decoder
+const DefaultMaxDepth = rencode.DefaultMaxDepth
+
+const (
+	// maxIntLength is the maximum length of an integer, sign included, accepted by the decoder;
+	// longer integers could not be transcoded to rencode either
+	maxIntLength = rencode.MAX_INT_LENGTH - 1
+	// maxLengthDigits is the maximum number of digits of a string length
+	maxLengthDigits = 10
+)
+
+// NewDecoder returns a bencode decoder that sources all bytes from the specified reader;
+// the reader is buffered, so bytes past the last decoded value might be consumed.
+func NewDecoder(r io.Reader) *Decoder {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &Decoder{r: br}
+}
+
+// SetMaxDepth changes the maximum nesting depth of lists and dictionaries that the decoder accepts
+// before failing, which protects against stack exhaustion; zero restores DefaultMaxDepth
+func (d *Decoder) SetMaxDepth(n int) {
+	d.maxDepth = n
+}
+
+// enter accounts for a list or dictionary being started, failing if the maximum depth would be exceeded
+func (d *Decoder) enter() error {
+	max := d.maxDepth
+	if max <= 0 {
+		max = DefaultMaxDepth
+	}
+	if d.depth >= max {
+		return fmt.Errorf("maximum nesting depth of %d exceeded", max)
+	}
+	d.depth++
+	return nil
+}
+
+// DecodeNext returns the next available object stored in the bencode stream as one of:
+// * rencode.List
+// * rencode.Dictionary
+// * []byte
+// * int64, or *big.Int for integers that do not fit in 64 bits
+// If no more objects are available, an io.EOF error will be returned.
+func (d *Decoder) DecodeNext() (interface{}, error) {
+	tok, err := d.Token()
+	if err != nil {
+		return nil, err
+	}
+	return d.value(tok)
+}
+
+func (d *Decoder) value(tok rencode.Token) (interface{}, error) {
+	switch tok.(type) {
+	case rencode.ListStart:
+		var l rencode.List
+		for {
+			tok, err := d.Token()
+			if err != nil {
+				return nil, unexpectedEOF(err)
+			}
+			if _, ok := tok.(rencode.End); ok {
+				return l, nil
+			}
+			v, err := d.value(tok)
+			if err != nil {
+				return nil, err
+			}
+			l.Add(v)
+		}
+	case rencode.DictStart:
+		var dict rencode.Dictionary
+		for {
+			tok, err := d.Token()
+			if err != nil {
+				return nil, unexpectedEOF(err)
+			}
+			if _, ok := tok.(rencode.End); ok {
+				return dict, nil
+			}
+			key, ok := tok.([]byte)
+			if !ok {
+				return nil, fmt.Errorf("invalid dictionary key of type %T", tok)
+			}
+			tok, err = d.Token()
+			if err != nil {
+				return nil, unexpectedEOF(err)
+			}
+			if _, ok := tok.(rencode.End); ok {
+				return nil, fmt.Errorf("incomplete key-value pair in dictionary data")
+			}
+			v, err := d.value(tok)
+			if err != nil {
+				return nil, err
+			}
+			err = dict.Add(key, v)
+			if err != nil {
+				return nil, err
+			}
+		}
+	case rencode.End:
+		return nil, fmt.Errorf("unexpected end of container")
+	}
+	return tok, nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// Token returns the next token in the bencode stream, using the same token types of rencode.Decoder.Token;
+// lists and dictionaries always start with a length of -1.
+// If no more tokens are available, an io.EOF error will be returned.
+func (d *Decoder) Token() (rencode.Token, error) {
+	c, err := d.r.ReadByte()
+	if err != nil {
+		if d.depth > 0 {
+			err = unexpectedEOF(err)
+		}
+		return nil, err
+	}
+
+	switch {
+	case c == 'l':
+		err = d.enter()
+		if err != nil {
+			return nil, err
+		}
+		return rencode.ListStart{Length: -1}, nil
+	case c == 'd':
+		err = d.enter()
+		if err != nil {
+			return nil, err
+		}
+		return rencode.DictStart{Length: -1}, nil
+	case c == 'e':
+		if d.depth == 0 {
+			return nil, fmt.Errorf("unexpected end of container")
+		}
+		d.depth--
+		return rencode.End{}, nil
+	case c == 'i':
+		return d.readInt()
+	case '0' <= c && c <= '9':
+		// the first digit is part of the length
+		_ = d.r.UnreadByte()
+		return d.readBytes()
+	}
+
+	return nil, fmt.Errorf("invalid bencode character %q", c)
+}
+
+// readUntil reads up to the terminator byte, which is discarded, failing if more than max bytes precede it
+func (d *Decoder) readUntil(term byte, max int, what string) (string, error) {
+	var s []byte
+	for {
+		c, err := d.r.ReadByte()
+		if err != nil {
+			return "", unexpectedEOF(err)
+		}
+		if c == term {
+			return string(s), nil
+		}
+		if len(s) == max {
+			return "", fmt.Errorf("%s is longer than %d characters", what, max)
+		}
+		s = append(s, c)
+	}
+}
+
+func (d *Decoder) readInt() (interface{}, error) {
+	s, err := d.readUntil('e', maxIntLength, "integer")
+	if err != nil {
+		return nil, err
+	}
+
+	// reject leading zeroes and negative zero
+	digits := s
+	if len(digits) > 0 && digits[0] == '-' {
+		digits = digits[1:]
+		if digits == "0" {
+			return nil, fmt.Errorf("invalid integer %q", s)
+		}
+	}
+	if len(digits) == 0 || (digits[0] == '0' && len(digits) > 1) {
+		return nil, fmt.Errorf("invalid integer %q", s)
+	}
+	for i := 0; i < len(digits); i++ {
+		if digits[i] < '0' || digits[i] > '9' {
+			return nil, fmt.Errorf("invalid integer %q", s)
+		}
+	}
+
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err == nil {
+		return i, nil
+	}
+	var b big.Int
+	if _, ok := b.SetString(s, 10); !ok {
+		return nil, fmt.Errorf("invalid integer %q", s)
+	}
+	return &b, nil
+}
+
+func (d *Decoder) readBytes() ([]byte, error) {
+	s, err := d.readUntil(':', maxLengthDigits, "string length")
+	if err != nil {
+		return nil, err
+	}
+	if len(s) > 1 && s[0] == '0' {
+		return nil, fmt.Errorf("invalid string length %q", s)
+	}
+	n, err := strconv.ParseUint(s, 10, 31)
+	if err != nil {
+		return nil, fmt.Errorf("invalid string length %q", s)
+	}
+
+	var b bytes.Buffer
+	_, err = io.CopyN(&b, d.r, int64(n))
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	return b.Bytes(), nil
+}
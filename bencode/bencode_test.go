@@ -0,0 +1,258 @@
+package bencode
+
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/gdm85/go-rencode"
+)
+
+const torrent = "d8:announce18:http://tracker/ann4:infod6:lengthi1048576e4:name10:debian.iso12:piece lengthi262144ee4:listl" +
+	"i-5ei0ei123456789012345678901234567890e0:ee"
+
+func TestDecodeEncode(t *testing.T) {
+	d := NewDecoder(strings.NewReader(torrent))
+	v, err := d.DecodeNext()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = d.DecodeNext()
+	if err != io.EOF {
+		t.Fatalf("expected end of stream but %v found", err)
+	}
+
+	dict := v.(rencode.Dictionary)
+	info, err := dict.Get("info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	infoDict := info.(rencode.Dictionary)
+	name, err := infoDict.Get("name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(name.([]byte)) != "debian.iso" {
+		t.Fatalf("unexpected name %q", name)
+	}
+	length, err := infoDict.Get("length")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if length != int64(1048576) {
+		t.Fatalf("unexpected length %v", length)
+	}
+
+	list, err := dict.Get("list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := list.(rencode.List)
+	bigValue, err := l.Get(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bigValue.(*big.Int).String() != "123456789012345678901234567890" {
+		t.Fatalf("unexpected big number %v", bigValue)
+	}
+
+	var b bytes.Buffer
+	e := NewEncoder(&b)
+	err = e.Encode(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != torrent {
+		t.Fatalf("expected %s but %s found", torrent, b.String())
+	}
+}
+
+func TestEncodeSortsKeys(t *testing.T) {
+	var d rencode.Dictionary
+	for _, k := range []string{"zeta", "alpha", "Beta", "al"} {
+		err := d.Add(k, len(k))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var b bytes.Buffer
+	err := NewEncoder(&b).Encode(&d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "d4:Betai4e2:ali2e5:alphai5e4:zetai4ee"
+	if b.String() != expected {
+		t.Fatalf("expected %s but %s found", expected, b.String())
+	}
+
+	var invalid rencode.Dictionary
+	err = invalid.Add(int8(1), "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = NewEncoder(&b).Encode(invalid)
+	if err == nil {
+		t.Fatal("expected an error for an integer key")
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	for _, input := range []string{
+		"i-0e", "i03e", "ie", "i1x2e", "03:abc", "5:abc", "l", "d1:ae", "di1ei2ee", "e", "x", "li1e",
+	} {
+		_, err := NewDecoder(strings.NewReader(input)).DecodeNext()
+		if err == nil || err == io.EOF {
+			t.Errorf("input %q: expected an error but %v found", input, err)
+		}
+	}
+}
+
+func TestTranscode(t *testing.T) {
+	// bencode -> rencode
+	var r bytes.Buffer
+	re := rencode.NewEncoder(&r)
+	err := BencodeToRencode(&re, NewDecoder(strings.NewReader(torrent)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// integers are narrowed by rencode, compare the values once encoded back to bencode
+	found, err := rencode.NewDecoder(bytes.NewReader(r.Bytes())).DecodeNext()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	err = NewEncoder(&b).Encode(found)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != torrent {
+		t.Fatalf("expected %s but %s found", torrent, b.String())
+	}
+
+	// rencode -> bencode, from the tree encoding that uses fixed-length containers
+	var tree bytes.Buffer
+	e := rencode.NewEncoder(&tree)
+	err = e.Encode(found)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, src := range [][]byte{r.Bytes(), tree.Bytes()} {
+		b.Reset()
+		err = RencodeToBencode(NewEncoder(&b), rencode.NewDecoder(bytes.NewReader(src)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != torrent {
+			t.Fatalf("expected %s but %s found", torrent, b.String())
+		}
+	}
+}
+
+func TestTranscodeUnsupported(t *testing.T) {
+	for _, v := range []interface{}{true, float32(1), nil} {
+		var l rencode.List
+		l.Add(v)
+		var r bytes.Buffer
+		e := rencode.NewEncoder(&r)
+		err := e.Encode(l)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = RencodeToBencode(NewEncoder(ioutil.Discard), rencode.NewDecoder(&r))
+		if err == nil {
+			t.Fatalf("expected an error for value of type %T", v)
+		}
+	}
+}
+
+func TestDecodeHostile(t *testing.T) {
+	for _, input := range []string{
+		strings.Repeat("l", DefaultMaxDepth+1),
+		strings.Repeat("d1:a", DefaultMaxDepth+1),
+		"i" + strings.Repeat("1", 1<<20),
+		strings.Repeat("1", 1<<20),
+		"i" + strings.Repeat("9", rencode.MAX_INT_LENGTH) + "e",
+		"12345678901:x",
+	} {
+		_, err := NewDecoder(strings.NewReader(input)).DecodeNext()
+		if err == nil || err == io.EOF || err == io.ErrUnexpectedEOF {
+			t.Errorf("input %.16q: expected an error but %v found", input, err)
+		}
+	}
+
+	d := NewDecoder(strings.NewReader("lllleeee"))
+	d.SetMaxDepth(3)
+	_, err := d.DecodeNext()
+	if err == nil {
+		t.Fatal("expected maximum depth to be exceeded")
+	}
+	d = NewDecoder(strings.NewReader("llleee"))
+	d.SetMaxDepth(3)
+	_, err = d.DecodeNext()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTranscodeDiscardsFailedValue(t *testing.T) {
+	var r bytes.Buffer
+	re := rencode.NewEncoder(&r)
+	err := BencodeToRencode(&re, NewDecoder(strings.NewReader("li1ed1:ai2e")))
+	if err == nil {
+		t.Fatal("expected an error for truncated input")
+	}
+	if re.Buffered() != 0 || r.Len() != 0 {
+		t.Fatalf("expected failed value to be discarded but %d buffered and %d written", re.Buffered(), r.Len())
+	}
+
+	// the encoder is still usable
+	err = re.Encode(int8(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(r.Bytes(), []byte{1}) {
+		t.Fatalf("unexpected output %x", r.Bytes())
+	}
+}
+
+func TestRencodeToBencodeDiscardsFailedValue(t *testing.T) {
+	var r bytes.Buffer
+	re := rencode.NewEncoder(&r)
+	err := re.Encode(rencode.NewList(1, 2.5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	err = RencodeToBencode(NewEncoder(&b), rencode.NewDecoder(&r))
+	if err == nil {
+		t.Fatal("expected an error for a float")
+	}
+	if b.Len() != 0 {
+		t.Fatalf("expected nothing to be written but %q found", b.Bytes())
+	}
+}
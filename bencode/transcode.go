@@ -0,0 +1,208 @@
+package bencode
+
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/gdm85/go-rencode"
+)
+
+// RencodeToBencode transcodes the next value of the rencode stream to bencode.
+// Values are read token by token and the converted value is written on dst with a single
+// Write call once complete, so that nothing is written if the conversion fails.
+// Booleans, floats and None values cannot be represented in bencode and cause an error.
+func RencodeToBencode(dst *Encoder, src *rencode.Decoder) error {
+	tok, err := src.Token()
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	err = rencodeToBencode(NewEncoder(&buf), src, tok)
+	if err != nil {
+		return err
+	}
+	n, err := dst.w.Write(buf.Bytes())
+	if err == nil && n < buf.Len() {
+		err = io.ErrShortWrite
+	}
+	return err
+}
+
+func rencodeToBencode(dst *Encoder, src *rencode.Decoder, tok rencode.Token) error {
+	switch tok.(type) {
+	case rencode.ListStart:
+		_, err := dst.w.Write([]byte{'l'})
+		if err != nil {
+			return err
+		}
+		for {
+			tok, err = src.Token()
+			if err != nil {
+				return unexpectedEOF(err)
+			}
+			if _, ok := tok.(rencode.End); ok {
+				return dst.writeEnd()
+			}
+			err = rencodeToBencode(dst, src, tok)
+			if err != nil {
+				return err
+			}
+		}
+	case rencode.DictStart:
+		var pairs []pair
+		for {
+			tok, err := src.Token()
+			if err != nil {
+				return unexpectedEOF(err)
+			}
+			if _, ok := tok.(rencode.End); ok {
+				break
+			}
+			key, err := keyBytes(tok)
+			if err != nil {
+				return err
+			}
+
+			tok, err = src.Token()
+			if err != nil {
+				return unexpectedEOF(err)
+			}
+			if _, ok := tok.(rencode.End); ok {
+				return fmt.Errorf("incomplete key-value pair in dictionary data")
+			}
+			var value bytes.Buffer
+			err = rencodeToBencode(NewEncoder(&value), src, tok)
+			if err != nil {
+				return err
+			}
+			pairs = append(pairs, pair{key, value.Bytes()})
+		}
+
+		err := sortPairs(pairs)
+		if err != nil {
+			return err
+		}
+		_, err = dst.w.Write([]byte{'d'})
+		if err != nil {
+			return err
+		}
+		for _, p := range pairs {
+			err = dst.writeBytes(p.key)
+			if err != nil {
+				return err
+			}
+			_, err = dst.w.Write(p.value.([]byte))
+			if err != nil {
+				return err
+			}
+		}
+		return dst.writeEnd()
+	case rencode.End:
+		return fmt.Errorf("unexpected end of container")
+	case nil, bool, float32, float64:
+		return fmt.Errorf("value of type %T cannot be represented in bencode", tok)
+	}
+
+	return dst.Encode(tok)
+}
+
+// BencodeToRencode transcodes the next value of the bencode stream to rencode;
+// lists and dictionaries are streamed token by token and thus always written as terminated containers.
+// If the conversion fails, the partially converted value is discarded from dst.
+func BencodeToRencode(dst *rencode.Encoder, src *Decoder) error {
+	tok, err := src.Token()
+	if err != nil {
+		return err
+	}
+	return dst.Encode(encoderFunc(func(e *rencode.Encoder) error {
+		return bencodeToRencode(e, src, tok)
+	}))
+}
+
+// encoderFunc is a rencode.Marshaler encoding a value with a function, so that a failed
+// conversion is discarded by rencode.Encoder.Encode
+type encoderFunc func(e *rencode.Encoder) error
+
+func (f encoderFunc) EncodeRencode(e *rencode.Encoder) error {
+	return f(e)
+}
+
+func bencodeToRencode(dst *rencode.Encoder, src *Decoder, tok rencode.Token) error {
+	switch tok.(type) {
+	case rencode.ListStart:
+		err := dst.EncodeListStart(-1)
+		if err != nil {
+			return err
+		}
+		for {
+			tok, err = src.Token()
+			if err != nil {
+				return err
+			}
+			if _, ok := tok.(rencode.End); ok {
+				return dst.EncodeListEnd(-1)
+			}
+			err = bencodeToRencode(dst, src, tok)
+			if err != nil {
+				return err
+			}
+		}
+	case rencode.DictStart:
+		err := dst.EncodeDictStart(-1)
+		if err != nil {
+			return err
+		}
+		for {
+			tok, err = src.Token()
+			if err != nil {
+				return err
+			}
+			if _, ok := tok.(rencode.End); ok {
+				return dst.EncodeDictEnd(-1)
+			}
+			if _, ok := tok.([]byte); !ok {
+				return fmt.Errorf("invalid dictionary key of type %T", tok)
+			}
+			err = dst.Encode(tok)
+			if err != nil {
+				return err
+			}
+
+			tok, err = src.Token()
+			if err != nil {
+				return err
+			}
+			if _, ok := tok.(rencode.End); ok {
+				return fmt.Errorf("incomplete key-value pair in dictionary data")
+			}
+			err = bencodeToRencode(dst, src, tok)
+			if err != nil {
+				return err
+			}
+		}
+	case rencode.End:
+		return fmt.Errorf("unexpected end of container")
+	}
+
+	return dst.Encode(tok)
+}
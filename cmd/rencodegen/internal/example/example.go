@@ -0,0 +1,55 @@
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+// Package example contains types with methods generated by rencodegen.
+package example
+
+//go:generate go run github.com/gdm85/go-rencode/cmd/rencodegen -type Peer,Status example.go
+
+// Priority is a named type encoded through rencode.Encoder.Encode
+type Priority int
+
+// Peer is a peer of a torrent
+type Peer struct {
+	IP       string  `rencode:"ip"`
+	Port     uint16  `rencode:"port"`
+	Progress float32 `rencode:"progress"`
+	Client   []byte  `rencode:"client,omitempty"`
+	Flags    []int8  `rencode:"flags"`
+	Raw      []uint8 `rencode:"raw,omitempty"`
+}
+
+// Status is the status of a torrent
+type Status struct {
+	Name     string           `rencode:"name"`
+	Size     uint64           `rencode:"total_size"`
+	Done     int64            `rencode:"total_done"`
+	Ratio    float64          `rencode:"ratio"`
+	Paused   bool             `rencode:"paused"`
+	Priority Priority         `rencode:"priority"`
+	Peers    []Peer           `rencode:"peers"`
+	Trackers map[string]int16 `rencode:"trackers"`
+	Labels   []string         `rencode:"labels,omitempty"`
+	Parent   *Status          `rencode:"parent,omitempty"`
+	Hash     [4]byte          `rencode:"hash"`
+	Extra    interface{}      `rencode:"extra"`
+	Queue    int32
+	Ignored  int `rencode:"-"`
+	internal int
+}
@@ -0,0 +1,333 @@
+// Code generated by rencodegen from example.go; DO NOT EDIT.
+
+package example
+
+import (
+	"github.com/gdm85/go-rencode"
+)
+
+// EncodeRencode implements rencode.Marshaler
+func (x Peer) EncodeRencode(e *rencode.Encoder) error {
+	n := 6
+	if len(x.Client) == 0 {
+		n--
+	}
+	if len(x.Raw) == 0 {
+		n--
+	}
+	err := e.EncodeDictStart(n)
+	if err != nil {
+		return err
+	}
+	err = e.EncodeString("ip")
+	if err != nil {
+		return err
+	}
+	err = e.EncodeString(x.IP)
+	if err != nil {
+		return err
+	}
+	err = e.EncodeString("port")
+	if err != nil {
+		return err
+	}
+	err = e.EncodeInt(int64(x.Port))
+	if err != nil {
+		return err
+	}
+	err = e.EncodeString("progress")
+	if err != nil {
+		return err
+	}
+	err = e.EncodeFloat32(x.Progress)
+	if err != nil {
+		return err
+	}
+	if len(x.Client) != 0 {
+		err = e.EncodeString("client")
+		if err != nil {
+			return err
+		}
+		err = e.EncodeBytes(x.Client)
+		if err != nil {
+			return err
+		}
+	}
+	err = e.EncodeString("flags")
+	if err != nil {
+		return err
+	}
+	if x.Flags == nil {
+		err = e.EncodeNone()
+	} else {
+		err = e.EncodeListStart(len(x.Flags))
+		if err != nil {
+			return err
+		}
+		for _, v := range x.Flags {
+			err = e.EncodeInt(int64(v))
+			if err != nil {
+				return err
+			}
+		}
+		err = e.EncodeListEnd(len(x.Flags))
+	}
+	if err != nil {
+		return err
+	}
+	if len(x.Raw) != 0 {
+		err = e.EncodeString("raw")
+		if err != nil {
+			return err
+		}
+		err = e.EncodeBytes(x.Raw)
+		if err != nil {
+			return err
+		}
+	}
+	return e.EncodeDictEnd(n)
+}
+
+// DecodeRencode implements rencode.Unmarshaler
+func (x *Peer) DecodeRencode(d *rencode.Decoder) error {
+	ok, err := d.DecodeDictStart()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		*x = Peer{}
+		return nil
+	}
+	for {
+		more, err := d.More()
+		if err != nil || !more {
+			return err
+		}
+		key, err := d.DecodeBytes()
+		if err != nil {
+			return err
+		}
+		switch string(key) {
+		case "ip":
+			x.IP, err = d.DecodeString()
+		case "port":
+			var u uint64
+			u, err = d.DecodeUint(16)
+			x.Port = uint16(u)
+		case "progress":
+			var f float64
+			f, err = d.DecodeFloat(32)
+			x.Progress = float32(f)
+		case "client":
+			x.Client, err = d.DecodeBytes()
+		case "flags":
+			err = d.Decode(&x.Flags)
+		case "raw":
+			x.Raw, err = d.DecodeBytes()
+		default:
+			err = d.Skip()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// EncodeRencode implements rencode.Marshaler
+func (x Status) EncodeRencode(e *rencode.Encoder) error {
+	n := 13
+	if len(x.Labels) == 0 {
+		n--
+	}
+	if x.Parent == nil {
+		n--
+	}
+	err := e.EncodeDictStart(n)
+	if err != nil {
+		return err
+	}
+	err = e.EncodeString("name")
+	if err != nil {
+		return err
+	}
+	err = e.EncodeString(x.Name)
+	if err != nil {
+		return err
+	}
+	err = e.EncodeString("total_size")
+	if err != nil {
+		return err
+	}
+	err = e.Encode(uint64(x.Size))
+	if err != nil {
+		return err
+	}
+	err = e.EncodeString("total_done")
+	if err != nil {
+		return err
+	}
+	err = e.EncodeInt(int64(x.Done))
+	if err != nil {
+		return err
+	}
+	err = e.EncodeString("ratio")
+	if err != nil {
+		return err
+	}
+	err = e.EncodeFloat64(x.Ratio)
+	if err != nil {
+		return err
+	}
+	err = e.EncodeString("paused")
+	if err != nil {
+		return err
+	}
+	err = e.EncodeBool(x.Paused)
+	if err != nil {
+		return err
+	}
+	err = e.EncodeString("priority")
+	if err != nil {
+		return err
+	}
+	err = e.Encode(x.Priority)
+	if err != nil {
+		return err
+	}
+	err = e.EncodeString("peers")
+	if err != nil {
+		return err
+	}
+	err = e.Encode(x.Peers)
+	if err != nil {
+		return err
+	}
+	err = e.EncodeString("trackers")
+	if err != nil {
+		return err
+	}
+	err = e.Encode(x.Trackers)
+	if err != nil {
+		return err
+	}
+	if len(x.Labels) != 0 {
+		err = e.EncodeString("labels")
+		if err != nil {
+			return err
+		}
+		if x.Labels == nil {
+			err = e.EncodeNone()
+		} else {
+			err = e.EncodeListStart(len(x.Labels))
+			if err != nil {
+				return err
+			}
+			for _, v := range x.Labels {
+				err = e.EncodeString(v)
+				if err != nil {
+					return err
+				}
+			}
+			err = e.EncodeListEnd(len(x.Labels))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if x.Parent != nil {
+		err = e.EncodeString("parent")
+		if err != nil {
+			return err
+		}
+		err = e.Encode(x.Parent)
+		if err != nil {
+			return err
+		}
+	}
+	err = e.EncodeString("hash")
+	if err != nil {
+		return err
+	}
+	err = e.Encode(x.Hash)
+	if err != nil {
+		return err
+	}
+	err = e.EncodeString("extra")
+	if err != nil {
+		return err
+	}
+	err = e.Encode(x.Extra)
+	if err != nil {
+		return err
+	}
+	err = e.EncodeString("Queue")
+	if err != nil {
+		return err
+	}
+	err = e.EncodeInt(int64(x.Queue))
+	if err != nil {
+		return err
+	}
+	return e.EncodeDictEnd(n)
+}
+
+// DecodeRencode implements rencode.Unmarshaler
+func (x *Status) DecodeRencode(d *rencode.Decoder) error {
+	ok, err := d.DecodeDictStart()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		*x = Status{}
+		return nil
+	}
+	for {
+		more, err := d.More()
+		if err != nil || !more {
+			return err
+		}
+		key, err := d.DecodeBytes()
+		if err != nil {
+			return err
+		}
+		switch string(key) {
+		case "name":
+			x.Name, err = d.DecodeString()
+		case "total_size":
+			var u uint64
+			u, err = d.DecodeUint(64)
+			x.Size = uint64(u)
+		case "total_done":
+			var i int64
+			i, err = d.DecodeInt(64)
+			x.Done = int64(i)
+		case "ratio":
+			x.Ratio, err = d.DecodeFloat(64)
+		case "paused":
+			x.Paused, err = d.DecodeBool()
+		case "priority":
+			err = d.Decode(&x.Priority)
+		case "peers":
+			err = d.Decode(&x.Peers)
+		case "trackers":
+			err = d.Decode(&x.Trackers)
+		case "labels":
+			err = d.Decode(&x.Labels)
+		case "parent":
+			err = d.Decode(&x.Parent)
+		case "hash":
+			err = d.Decode(&x.Hash)
+		case "extra":
+			err = d.Decode(&x.Extra)
+		case "Queue":
+			var i int64
+			i, err = d.DecodeInt(32)
+			x.Queue = int32(i)
+		default:
+			err = d.Skip()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
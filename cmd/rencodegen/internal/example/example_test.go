@@ -0,0 +1,156 @@
+package example
+
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/gdm85/go-rencode"
+)
+
+// plainPeer and plainStatus have no methods and are thus handled by reflection
+type plainPeer Peer
+type plainStatus Status
+
+func statusFixture() Status {
+	var extra rencode.List
+	extra.Add(int8(1))
+	extra.Add([]byte("two"))
+
+	return Status{
+		Name:     "debian.iso",
+		Size:     1 << 40,
+		Done:     -3,
+		Ratio:    1.5,
+		Paused:   true,
+		Priority: 2,
+		Peers: []Peer{
+			{IP: "10.0.0.1", Port: 6881, Progress: 0.5, Client: []byte("Deluge"), Flags: []int8{1, -2}, Raw: []uint8{0, 200}},
+			{IP: "10.0.0.2", Progress: 1},
+		},
+		Trackers: map[string]int16{"udp://a": 3, "udp://b": -400},
+		Labels:   []string{"linux"},
+		Parent:   &Status{Name: "parent", Queue: 70000},
+		Hash:     [4]byte{1, 2, 3, 4},
+		Extra:    extra,
+		Queue:    -1,
+	}
+}
+
+func TestGeneratedMatchesReflection(t *testing.T) {
+	for _, value := range []Status{statusFixture(), {}} {
+		generated, err := rencode.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reflected, err := rencode.Marshal(plainStatus(value))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(generated, reflected) {
+			t.Fatalf("generated encoding %v differs from reflection %v", generated, reflected)
+		}
+
+		var found Status
+		err = rencode.Unmarshal(generated, &found)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var plain plainStatus
+		err = rencode.Unmarshal(reflected, &plain)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(found, Status(plain)) {
+			t.Fatalf("generated decoding %+v differs from reflection %+v", found, plain)
+		}
+		if !reflect.DeepEqual(found, value) {
+			t.Fatalf("expected %+v but %+v found", value, found)
+		}
+	}
+
+	// []uint8 is encoded as a byte string like []byte
+	peer := Peer{IP: "::1", Port: 1, Flags: []int8{}, Raw: []uint8("raw")}
+	generated, err := rencode.Marshal(peer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reflected, err := rencode.Marshal(plainPeer(peer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(generated, reflected) {
+		t.Fatalf("generated encoding %v differs from reflection %v", generated, reflected)
+	}
+}
+
+func TestGeneratedDecodeForeign(t *testing.T) {
+	// a dictionary with different integer widths, unknown keys and None values
+	var d rencode.Dictionary
+	for _, kv := range [][2]interface{}{
+		{"ip", []byte("10.0.0.1")},
+		{"unknown", []interface{}{1, "two", map[string]int{"three": 3}}},
+		{"port", int64(6881)},
+		{"progress", 0.25},
+		{"client", nil},
+		{"flags", []int64{1, 2}},
+	} {
+		err := d.Add(kv[0], kv[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := rencode.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := Peer{Client: []byte("previous")}
+	err = rencode.Unmarshal(data, &found)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain := plainPeer{Client: []byte("previous")}
+	err = rencode.Unmarshal(data, &plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(found, Peer(plain)) {
+		t.Fatalf("generated decoding %+v differs from reflection %+v", found, plain)
+	}
+	expected := Peer{IP: "10.0.0.1", Port: 6881, Progress: 0.25, Flags: []int8{1, 2}}
+	if !reflect.DeepEqual(found, expected) {
+		t.Fatalf("expected %+v but %+v found", expected, found)
+	}
+
+	// overflows are reported like with reflection
+	d = rencode.Dictionary{}
+	d.Add("port", 70000)
+	data, err = rencode.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rencode.Unmarshal(data, &found)
+	if err == nil {
+		t.Fatal("expected an overflow error")
+	}
+}
@@ -0,0 +1,357 @@
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+/*
+Command rencodegen generates reflection-free EncodeRencode and DecodeRencode methods
+for the struct types defined in a Go source file, implementing rencode.Marshaler and
+rencode.Unmarshaler with the same encoding as rencode.Marshal.
+
+Usage
+
+	rencodegen [-type T1,T2] [-output file_rencode.go] file.go
+
+All struct types of the file are processed unless -type is specified; the output defaults
+to the input file name with a "_rencode.go" suffix. It is meant to be used with go generate:
+
+	//go:generate go run github.com/gdm85/go-rencode/cmd/rencodegen -type Status file.go
+
+Fields of basic types (bool, integers, floats, string, []byte) and slices of basic types
+are encoded and decoded inline; fields of any other type go through Encoder.Encode and
+Decoder.Decode. The omitempty tag option is only supported for fields whose emptiness can
+be determined from their declaration: basic types, pointers, interfaces, slices, maps and arrays.
+*/
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+func main() {
+	typeNames := flag.String("type", "", "comma-separated list of struct type names; all struct types if empty")
+	output := flag.String("output", "", "output file name; default <file>_rencode.go")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: rencodegen [-type T1,T2] [-output file] file.go\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	input := flag.Arg(0)
+	var names []string
+	if *typeNames != "" {
+		names = strings.Split(*typeNames, ",")
+	}
+
+	src, err := generate(input, names)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "rencodegen: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *output == "" {
+		*output = strings.TrimSuffix(input, ".go") + "_rencode.go"
+	}
+	err = ioutil.WriteFile(*output, src, 0644)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "rencodegen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// structField is a field of a struct type as it will be encoded
+type structField struct {
+	goName    string
+	key       string
+	typ       string
+	omitEmpty bool
+}
+
+type structType struct {
+	name   string
+	fields []structField
+}
+
+// generate returns the formatted source code with the methods of the specified types
+func generate(filename string, typeNames []string) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := map[string]bool{}
+	for _, name := range typeNames {
+		wanted[name] = true
+	}
+
+	var structs []structType
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			ts := spec.(*ast.TypeSpec)
+			st, ok := ts.Type.(*ast.StructType)
+			if !ok || (len(wanted) > 0 && !wanted[ts.Name.Name]) {
+				continue
+			}
+			delete(wanted, ts.Name.Name)
+
+			s, err := parseStruct(ts.Name.Name, st)
+			if err != nil {
+				return nil, err
+			}
+			structs = append(structs, s)
+		}
+	}
+	for name := range wanted {
+		return nil, fmt.Errorf("struct type %s not found in %s", name, filename)
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by rencodegen from %s; DO NOT EDIT.\n\n", filepath.Base(filename))
+	fmt.Fprintf(&b, "package %s\n\n", file.Name.Name)
+	fmt.Fprintf(&b, "import (\n\t\"github.com/gdm85/go-rencode\"\n)\n")
+	for _, s := range structs {
+		s.writeEncode(&b)
+		s.writeDecode(&b)
+	}
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %v", err)
+	}
+	return src, nil
+}
+
+func parseStruct(name string, st *ast.StructType) (structType, error) {
+	s := structType{name: name}
+	for _, f := range st.Fields.List {
+		typ := normalizeType(types.ExprString(f.Type))
+		names := make([]string, len(f.Names))
+		for i, n := range f.Names {
+			names[i] = n.Name
+		}
+		if len(names) == 0 {
+			// embedded fields are named after their type
+			embedded := strings.TrimPrefix(typ, "*")
+			if i := strings.LastIndex(embedded, "."); i >= 0 {
+				embedded = embedded[i+1:]
+			}
+			names = []string{embedded}
+		}
+
+		var tag string
+		if f.Tag != nil {
+			unquoted, err := strconv.Unquote(f.Tag.Value)
+			if err != nil {
+				return s, err
+			}
+			tag = reflect.StructTag(unquoted).Get("rencode")
+		}
+		if tag == "-" {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+
+		for _, goName := range names {
+			if !ast.IsExported(goName) {
+				continue
+			}
+			sf := structField{goName: goName, key: goName, typ: typ}
+			if parts[0] != "" {
+				sf.key = parts[0]
+			}
+			for _, opt := range parts[1:] {
+				if opt == "omitempty" {
+					sf.omitEmpty = true
+				}
+			}
+			if empty, _ := sf.isEmpty(); sf.omitEmpty && empty == "" {
+				return s, fmt.Errorf("%s.%s: omitempty is not supported for fields of type %s", name, goName, typ)
+			}
+			s.fields = append(s.fields, sf)
+		}
+	}
+	return s, nil
+}
+
+// isEmpty returns the conditions for a field to be empty and non-empty when omitempty is set,
+// matching the empty values used by rencode.Marshal
+func (f structField) isEmpty() (empty, nonEmpty string) {
+	v := "x." + f.goName
+	switch {
+	case f.typ == "bool":
+		return "!" + v, v
+	case f.typ == "string":
+		return v + ` == ""`, v + ` != ""`
+	case isNumeric(f.typ):
+		return v + " == 0", v + " != 0"
+	case strings.HasPrefix(f.typ, "*"), strings.HasPrefix(f.typ, "interface"):
+		return v + " == nil", v + " != nil"
+	case strings.HasPrefix(f.typ, "["), strings.HasPrefix(f.typ, "map["):
+		return "len(" + v + ") == 0", "len(" + v + ") != 0"
+	}
+	return "", ""
+}
+
+var intBits = map[string]int{
+	"int": 64, "int8": 8, "int16": 16, "int32": 32, "int64": 64,
+	"uint": 64, "uint8": 8, "byte": 8, "uint16": 16, "uint32": 32, "uint64": 64,
+}
+
+func isNumeric(typ string) bool {
+	_, ok := intBits[typ]
+	return ok || typ == "float32" || typ == "float64"
+}
+
+// normalizeType rewrites slices of uint8 as []byte, at any nesting level, since rencode.Marshal
+// encodes both as byte strings
+func normalizeType(typ string) string {
+	elem := strings.TrimPrefix(typ, "[]")
+	if elem == typ {
+		return typ
+	}
+	if elem == "uint8" || elem == "byte" {
+		return "[]byte"
+	}
+	return "[]" + normalizeType(elem)
+}
+
+// isBasic returns true for the types that are encoded inline
+func isBasic(typ string) bool {
+	return typ == "bool" || typ == "string" || typ == "[]byte" || isNumeric(typ)
+}
+
+// encodeExpr returns the call that encodes the value v of a basic type
+func encodeExpr(typ, v string) string {
+	switch typ {
+	case "bool":
+		return "e.EncodeBool(" + v + ")"
+	case "string":
+		return "e.EncodeString(" + v + ")"
+	case "[]byte":
+		return "e.EncodeBytes(" + v + ")"
+	case "float32":
+		return "e.EncodeFloat32(" + v + ")"
+	case "float64":
+		return "e.EncodeFloat64(" + v + ")"
+	case "uint", "uint64":
+		// same representation as Encoder.Encode
+		return "e.Encode(uint64(" + v + "))"
+	}
+	return "e.EncodeInt(int64(" + v + "))"
+}
+
+func (s structType) writeEncode(b *bytes.Buffer) {
+	fmt.Fprintf(b, "\n// EncodeRencode implements rencode.Marshaler\n")
+	fmt.Fprintf(b, "func (x %s) EncodeRencode(e *rencode.Encoder) error {\n", s.name)
+	fmt.Fprintf(b, "n := %d\n", len(s.fields))
+	for _, f := range s.fields {
+		if f.omitEmpty {
+			empty, _ := f.isEmpty()
+			fmt.Fprintf(b, "if %s {\nn--\n}\n", empty)
+		}
+	}
+	fmt.Fprintf(b, "err := e.EncodeDictStart(n)\nif err != nil {\nreturn err\n}\n")
+
+	for _, f := range s.fields {
+		if f.omitEmpty {
+			_, nonEmpty := f.isEmpty()
+			fmt.Fprintf(b, "if %s {\n", nonEmpty)
+		}
+		fmt.Fprintf(b, "err = e.EncodeString(%q)\nif err != nil {\nreturn err\n}\n", f.key)
+		v := "x." + f.goName
+		elem := strings.TrimPrefix(f.typ, "[]")
+		switch {
+		case isBasic(f.typ):
+			fmt.Fprintf(b, "err = %s\nif err != nil {\nreturn err\n}\n", encodeExpr(f.typ, v))
+		case strings.HasPrefix(f.typ, "[]") && isBasic(elem):
+			fmt.Fprintf(b, "if %s == nil {\nerr = e.EncodeNone()\n} else {\n", v)
+			fmt.Fprintf(b, "err = e.EncodeListStart(len(%s))\nif err != nil {\nreturn err\n}\n", v)
+			fmt.Fprintf(b, "for _, v := range %s {\nerr = %s\nif err != nil {\nreturn err\n}\n}\n", v, encodeExpr(elem, "v"))
+			fmt.Fprintf(b, "err = e.EncodeListEnd(len(%s))\n}\nif err != nil {\nreturn err\n}\n", v)
+		default:
+			fmt.Fprintf(b, "err = e.Encode(%s)\nif err != nil {\nreturn err\n}\n", v)
+		}
+		if f.omitEmpty {
+			fmt.Fprintf(b, "}\n")
+		}
+	}
+
+	fmt.Fprintf(b, "return e.EncodeDictEnd(n)\n}\n")
+}
+
+// decodeStmt returns the statements that decode a value of a basic type in v
+func decodeStmt(typ, v string) string {
+	switch typ {
+	case "bool":
+		return v + ", err = d.DecodeBool()"
+	case "string":
+		return v + ", err = d.DecodeString()"
+	case "[]byte":
+		return v + ", err = d.DecodeBytes()"
+	case "float32":
+		return "var f float64\nf, err = d.DecodeFloat(32)\n" + v + " = float32(f)"
+	case "float64":
+		return v + ", err = d.DecodeFloat(64)"
+	}
+	bits := intBits[typ]
+	if strings.HasPrefix(typ, "u") || typ == "byte" {
+		return fmt.Sprintf("var u uint64\nu, err = d.DecodeUint(%d)\n%s = %s(u)", bits, v, typ)
+	}
+	return fmt.Sprintf("var i int64\ni, err = d.DecodeInt(%d)\n%s = %s(i)", bits, v, typ)
+}
+
+func (s structType) writeDecode(b *bytes.Buffer) {
+	fmt.Fprintf(b, "\n// DecodeRencode implements rencode.Unmarshaler\n")
+	fmt.Fprintf(b, "func (x *%s) DecodeRencode(d *rencode.Decoder) error {\n", s.name)
+	fmt.Fprintf(b, "ok, err := d.DecodeDictStart()\nif err != nil {\nreturn err\n}\n")
+	fmt.Fprintf(b, "if !ok {\n*x = %s{}\nreturn nil\n}\n", s.name)
+	fmt.Fprintf(b, "for {\nmore, err := d.More()\nif err != nil || !more {\nreturn err\n}\n")
+	fmt.Fprintf(b, "key, err := d.DecodeBytes()\nif err != nil {\nreturn err\n}\n")
+	fmt.Fprintf(b, "switch string(key) {\n")
+	for _, f := range s.fields {
+		fmt.Fprintf(b, "case %q:\n", f.key)
+		if isBasic(f.typ) {
+			fmt.Fprintf(b, "%s\n", decodeStmt(f.typ, "x."+f.goName))
+		} else {
+			fmt.Fprintf(b, "err = d.Decode(&x.%s)\n", f.goName)
+		}
+	}
+	fmt.Fprintf(b, "default:\nerr = d.Skip()\n}\n")
+	fmt.Fprintf(b, "if err != nil {\nreturn err\n}\n}\n}\n")
+}
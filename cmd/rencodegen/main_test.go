@@ -0,0 +1,71 @@
+package main
+
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExampleUpToDate(t *testing.T) {
+	src, err := generate(filepath.Join("internal", "example", "example.go"), []string{"Peer", "Status"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := ioutil.ReadFile(filepath.Join("internal", "example", "example_rencode.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(src, current) {
+		t.Fatal("internal/example/example_rencode.go is out of date, run go generate")
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rencodegen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, tc := range []struct {
+		src   string
+		types []string
+		err   string
+	}{
+		{"package p\ntype T struct{}\n", []string{"U"}, "not found"},
+		{"package p\ntype N int\ntype T struct{ N N `rencode:\",omitempty\"` }\n", nil, "omitempty"},
+		{"package p\ntype T struct{\n", nil, "expected"},
+	} {
+		filename := filepath.Join(dir, "p.go")
+		err = ioutil.WriteFile(filename, []byte(tc.src), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = generate(filename, tc.types)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("expected error containing %q, got %v", tc.err, err)
+		}
+	}
+}
@@ -21,47 +21,418 @@ package rencode
 
 import (
 	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
 )
 
-// this hack allows fetching keys by either string or byte slice type
-// no other special matching is performed with other slice types
-func deepEqual(a, b interface{}) bool {
-	switch a.(type) {
-	case string:
-		switch b.(type) {
-		case []byte:
-			return bytes.Compare([]byte(a.(string)), b.([]byte)) == 0
-		case string:
-			return a == b
-		default:
+// Equal reports whether two rencode values, as returned by DecodeNext or accepted by Encode, are equal:
+// * integers and floats of any width are equal when they have the same numeric value
+// * NaN is equal to itself, so that it can be found in sets
+// * string and []byte values are equal when they have the same content
+// * lists are equal when their elements are equal in the same order
+// * dictionaries are equal when they have equal keys with equal values, regardless of their order
+// * pointers to List, Dictionary and big.Int are compared by the value they point to
+// Values of any other type are compared with reflect.DeepEqual.
+func Equal(a, b interface{}) bool {
+	a, b = normalize(a), normalize(b)
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	// fast path for integers, which depends on their encoding
+	if x, ok := intValue(a); ok {
+		if y, ok := intValue(b); ok {
+			return x == y
+		}
+	}
+
+	ka, kb := rankOf(a), rankOf(b)
+	if ka != kb {
+		return false
+	}
+	switch ka {
+	case rankBool:
+		return a.(bool) == b.(bool)
+	case rankNumber:
+		return compareNumbers(a, b) == 0
+	case rankString:
+		return bytes.Equal(stringBytes(a), stringBytes(b))
+	case rankList:
+		l1, l2 := a.(List), b.(List)
+		if l1.Length() != l2.Length() {
 			return false
 		}
-	case []byte:
-		switch b.(type) {
-		case []byte:
-			return bytes.Compare(a.([]byte), b.([]byte)) == 0
-		case string:
-			return bytes.Compare([]byte(b.(string)), a.([]byte)) == 0
-		default:
+		for i, v := range l1.values {
+			if !Equal(v, l2.values[i]) {
+				return false
+			}
+		}
+		return true
+	case rankDict:
+		d1, d2 := a.(Dictionary), b.(Dictionary)
+		if d1.Length() != d2.Length() {
 			return false
 		}
-	case Dictionary:
-		switch b.(type) {
-		case Dictionary:
-			d1 := a.(Dictionary)
-			d2 := b.(Dictionary)
-			return d1.Equals(&d2)
+		// each pair of d1 must match a distinct pair of d2, as a dictionary can hold
+		// keys of different types that are equal, such as int8(1) and int64(1)
+		used := make([]bool, len(d2.keys))
+		for i, k := range d1.keys {
+			j := 0
+			for j < len(d2.keys) && (used[j] || !Equal(k, d2.keys[j]) || !Equal(d1.values[i], d2.values[j])) {
+				j++
+			}
+			if j == len(d2.keys) {
+				return false
+			}
+			used[j] = true
 		}
-		return false
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// Compare returns an integer comparing two rencode values: 0 if Equal(a, b), -1 if a < b and +1 if a > b.
+// Values of different kinds are ordered as None < bool < numbers < strings < lists < dictionaries;
+// numbers are ordered by value with NaN first, strings byte-wise and lists lexicographically.
+// Dictionaries are compared as lists of (key, value) pairs sorted by key. Values of any other type
+// are ordered after all of the above, in an arbitrary but consistent way.
+func Compare(a, b interface{}) int {
+	a, b = normalize(a), normalize(b)
+	ka, kb := rankOf(a), rankOf(b)
+	if ka != kb {
+		if ka < kb {
+			return -1
+		}
+		return 1
+	}
+
+	switch ka {
+	case rankNone:
+		return 0
+	case rankBool:
+		x, y := a.(bool), b.(bool)
+		switch {
+		case x == y:
+			return 0
+		case !x:
+			return -1
+		}
+		return 1
+	case rankNumber:
+		return compareNumbers(a, b)
+	case rankString:
+		return bytes.Compare(stringBytes(a), stringBytes(b))
+	case rankList:
+		return compareLists(a.(List).values, b.(List).values)
+	case rankDict:
+		return compareLists(sortedPairs(a.(Dictionary)), sortedPairs(b.(Dictionary)))
+	}
+
+	if reflect.DeepEqual(a, b) {
+		return 0
+	}
+	if c := compareStrings(fmt.Sprintf("%T", a), fmt.Sprintf("%T", b)); c != 0 {
+		return c
+	}
+	return compareStrings(fmt.Sprintf("%#v", a), fmt.Sprintf("%#v", b))
+}
+
+// Hash returns a hash of a rencode value that is consistent with Equal: equal values have the same hash.
+// The hash does not depend on the process and can be persisted. Values of types other than those
+// described by Equal are hashed by their type only, as their content can hold pointers.
+func Hash(v interface{}) uint64 {
+	h := fnv.New64a()
+	writeHash(h, v)
+	return h.Sum64()
+}
+
+// ranks of the kinds of values, in the order used by Compare
+const (
+	rankNone = iota
+	rankBool
+	rankNumber
+	rankString
+	rankList
+	rankDict
+	rankOther
+)
+
+// normalize replaces pointers to containers and big integers with the values compared by Equal
+func normalize(v interface{}) interface{} {
+	switch x := v.(type) {
+	case *List:
+		if x == nil {
+			return nil
+		}
+		return *x
+	case *Dictionary:
+		if x == nil {
+			return nil
+		}
+		return *x
+	case *big.Int:
+		if x == nil {
+			return nil
+		}
+	case big.Int:
+		return &x
+	}
+	return v
+}
+
+func rankOf(v interface{}) int {
+	switch v.(type) {
+	case nil:
+		return rankNone
+	case bool:
+		return rankBool
+	case int8, int16, int32, int64, int, uint8, uint16, uint32, uint64, uint, float32, float64, *big.Int:
+		return rankNumber
+	case string, []byte:
+		return rankString
 	case List:
-		switch b.(type) {
-		case List:
-			l1 := a.(List)
-			l2 := b.(List)
-			return l1.Equals(&l2)
+		return rankList
+	case Dictionary:
+		return rankDict
+	}
+	return rankOther
+}
+
+func stringBytes(v interface{}) []byte {
+	if s, ok := v.(string); ok {
+		return []byte(s)
+	}
+	return v.([]byte)
+}
+
+func compareStrings(a, b string) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareLists(a, b []interface{}) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := Compare(a[i], b[i]); c != 0 {
+			return c
 		}
-		return false
 	}
+	switch {
+	case len(a) < len(b):
+		return -1
+	case len(a) > len(b):
+		return 1
+	}
+	return 0
+}
 
-	return a == b
+// sortedPairs returns the (key, value) pairs of a dictionary as lists, sorted by key
+func sortedPairs(d Dictionary) []interface{} {
+	pairs := make([]interface{}, d.Length())
+	for i, k := range d.keys {
+		pairs[i] = List{values: []interface{}{k, d.values[i]}}
+	}
+	// pairs with equal keys, such as int8(1) and int64(1), are ordered by value
+	sort.Slice(pairs, func(i, j int) bool {
+		return Compare(pairs[i], pairs[j]) < 0
+	})
+	return pairs
+}
+
+// floatValue returns the value of a float32 or float64
+func floatValue(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case float32:
+		return float64(x), true
+	case float64:
+		return x, true
+	}
+	return 0, false
+}
+
+// exactValue returns the exact value of any of the numeric types, NaN excluded
+func exactValue(v interface{}) *big.Float {
+	if f, ok := floatValue(v); ok {
+		return new(big.Float).SetFloat64(f)
+	}
+	if x, ok := v.(*big.Int); ok {
+		return new(big.Float).SetInt(x)
+	}
+	if u, ok := toUint64(v); ok {
+		return new(big.Float).SetUint64(u)
+	}
+	i, _ := intValue(v)
+	return new(big.Float).SetInt64(i)
+}
+
+// compareNumbers compares the values of two numbers of any type, NaN being the smallest
+func compareNumbers(a, b interface{}) int {
+	if x, ok := intValue(a); ok {
+		if y, ok := intValue(b); ok {
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			}
+			return 0
+		}
+	}
+
+	x, aFloat := floatValue(a)
+	y, bFloat := floatValue(b)
+	aNaN, bNaN := aFloat && math.IsNaN(x), bFloat && math.IsNaN(y)
+	switch {
+	case aNaN && bNaN:
+		return 0
+	case aNaN:
+		return -1
+	case bNaN:
+		return 1
+	}
+	if aFloat && bFloat {
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+
+	return exactValue(a).Cmp(exactValue(b))
+}
+
+// hash type tags, written before the hashed content
+const (
+	hashNone byte = iota
+	hashBool
+	hashInt
+	hashBigInt
+	hashFloat
+	hashNaN
+	hashString
+	hashList
+	hashDict
+	hashOther
+)
+
+func writeHash(h hash.Hash64, v interface{}) {
+	v = normalize(v)
+	switch rankOf(v) {
+	case rankNone:
+		h.Write([]byte{hashNone})
+	case rankBool:
+		if v.(bool) {
+			h.Write([]byte{hashBool, 1})
+		} else {
+			h.Write([]byte{hashBool, 0})
+		}
+	case rankNumber:
+		// integral values are hashed as integers regardless of their type
+		if i, ok := intValue(v); ok {
+			writeTagged(h, hashInt, uint64(i))
+			return
+		}
+		if f, ok := floatValue(v); ok {
+			switch {
+			case math.IsNaN(f):
+				h.Write([]byte{hashNaN})
+				return
+			case math.IsInf(f, 0) || f != math.Trunc(f):
+				writeTagged(h, hashFloat, math.Float64bits(f))
+				return
+			case math.MinInt64 <= f && f < math.MaxInt64:
+				writeTagged(h, hashInt, uint64(int64(f)))
+				return
+			}
+		}
+		x, _ := exactValue(v).Int(nil)
+		if x.IsInt64() {
+			writeTagged(h, hashInt, uint64(x.Int64()))
+			return
+		}
+		h.Write([]byte{hashBigInt, byte(x.Sign() + 1)})
+		h.Write(x.Bytes())
+	case rankString:
+		b := stringBytes(v)
+		writeTagged(h, hashString, uint64(len(b)))
+		h.Write(b)
+	case rankList:
+		l := v.(List)
+		writeTagged(h, hashList, uint64(l.Length()))
+		for _, e := range l.values {
+			writeHash(h, e)
+		}
+	case rankDict:
+		// pairs are combined with a commutative operation, as their order does not matter
+		d := v.(Dictionary)
+		var sum uint64
+		for i, k := range d.keys {
+			pair := fnv.New64a()
+			writeHash(pair, k)
+			writeHash(pair, d.values[i])
+			sum += pair.Sum64()
+		}
+		writeTagged(h, hashDict, uint64(d.Length()))
+		writeTagged(h, hashDict, sum)
+	default:
+		// reflect.DeepEqual follows pointers, whose addresses cannot be hashed
+		fmt.Fprintf(h, "%c%T", hashOther, v)
+	}
+}
+
+// writeTagged writes a type tag followed by a 64-bit value
+func writeTagged(h hash.Hash64, tag byte, u uint64) {
+	var b [9]byte
+	b[0] = tag
+	binary.BigEndian.PutUint64(b[1:], u)
+	h.Write(b[:])
+}
+
+// intValue returns the value of any of the integer types as an int64
+func intValue(v interface{}) (int64, bool) {
+	switch x := v.(type) {
+	case int8:
+		return int64(x), true
+	case int16:
+		return int64(x), true
+	case int32:
+		return int64(x), true
+	case int64:
+		return x, true
+	case int:
+		return int64(x), true
+	case uint8:
+		return int64(x), true
+	case uint16:
+		return int64(x), true
+	case uint32:
+		return int64(x), true
+	case uint64:
+		if x > math.MaxInt64 {
+			return 0, false
+		}
+		return int64(x), true
+	case uint:
+		if uint64(x) > math.MaxInt64 {
+			return 0, false
+		}
+		return int64(x), true
+	case *big.Int:
+		if x.IsInt64() {
+			return x.Int64(), true
+		}
+	}
+	return 0, false
 }
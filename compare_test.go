@@ -0,0 +1,180 @@
+package rencode
+
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+import (
+	"math"
+	"math/big"
+	"sort"
+	"testing"
+)
+
+func TestEqual(t *testing.T) {
+	var l1, l2, l3 List
+	l1.Add(int8(1))
+	l1.Add("two")
+	l2.Add(int64(1))
+	l2.Add([]byte("two"))
+	l3.Add("two")
+	l3.Add(int8(1))
+
+	var d1, d2 Dictionary
+	d1.Add("a", int8(1))
+	d1.Add(int16(2), l1)
+	d2.Add(int32(2), l2)
+	d2.Add([]byte("a"), 1.0)
+
+	big1 := new(big.Int).Lsh(big.NewInt(1), 70)
+	big2 := new(big.Int).Lsh(big.NewInt(1), 70)
+
+	equal := [][2]interface{}{
+		{nil, nil},
+		{true, true},
+		{int8(5), int64(5)},
+		{uint64(5), int16(5)},
+		{int8(5), float32(5)},
+		{0.5, float32(0.5)},
+		{math.Copysign(0, -1), int8(0)},
+		{math.NaN(), float32(math.NaN())},
+		{big1, big2},
+		{*big1, big2},
+		{big.NewInt(-3), int8(-3)},
+		{uint64(math.MaxUint64), new(big.Int).SetUint64(math.MaxUint64)},
+		{math.Ldexp(1, 70), big1},
+		{"abc", []byte("abc")},
+		{l1, l2},
+		{l1, &l2},
+		{d1, d2},
+		{&d1, d2},
+		{[]int{1}, []int{1}},
+		{&struct{ A int }{1}, &struct{ A int }{1}},
+		{NewDictionary(int8(1), "x", int64(1), "y"), NewDictionary(int64(1), "y", int8(1), "x")},
+	}
+	for _, test := range equal {
+		if !Equal(test[0], test[1]) || !Equal(test[1], test[0]) {
+			t.Errorf("expected %v and %v to be equal", test[0], test[1])
+		}
+		if Compare(test[0], test[1]) != 0 || Compare(test[1], test[0]) != 0 {
+			t.Errorf("expected %v and %v to compare as equal", test[0], test[1])
+		}
+		if Hash(test[0]) != Hash(test[1]) {
+			t.Errorf("expected %v and %v to have the same hash", test[0], test[1])
+		}
+	}
+
+	different := [][2]interface{}{
+		{nil, false},
+		{true, int8(1)},
+		{int8(5), 5.5},
+		{float32(0.1), 0.1},
+		{math.NaN(), 0.0},
+		{big1, new(big.Int).Add(big1, big.NewInt(1))},
+		{"abc", "abd"},
+		{l1, l3},
+		{d1, l1},
+		{(*List)(nil), l1},
+		{[]int{1}, []int{2}},
+		// keys are matched one-to-one
+		{NewDictionary(int8(1), "x", int64(1), "x"), NewDictionary(int8(1), "x", int8(2), "y")},
+		{NewDictionary(int8(1), "x", int64(1), "y"), NewDictionary(int8(1), "x", int64(1), "x")},
+	}
+	for _, test := range different {
+		if Equal(test[0], test[1]) || Equal(test[1], test[0]) {
+			t.Errorf("expected %v and %v to be different", test[0], test[1])
+		}
+		if Compare(test[0], test[1]) == 0 || Compare(test[0], test[1]) != -Compare(test[1], test[0]) {
+			t.Errorf("unexpected comparison of %v and %v", test[0], test[1])
+		}
+	}
+
+	if Hash(d1) == Hash(l1) || Hash("1") == Hash(int8(1)) {
+		t.Error("unexpected hash collision")
+	}
+}
+
+func TestCompareOrder(t *testing.T) {
+	var short, long List
+	short.Add(int8(1))
+	long.Add(int8(1))
+	long.Add(int8(0))
+
+	var d1, d2 Dictionary
+	d1.Add("b", int8(1))
+	d1.Add("a", int8(2))
+	d2.Add("a", int8(2))
+	d2.Add("c", int8(0))
+
+	big1 := new(big.Int).Lsh(big.NewInt(1), 70)
+	expected := []interface{}{
+		nil,
+		false,
+		true,
+		math.NaN(),
+		math.Inf(-1),
+		new(big.Int).Neg(big1),
+		int8(-1),
+		0.5,
+		uint64(math.MaxUint64),
+		big1,
+		math.Inf(1),
+		"",
+		"a",
+		[]byte("b"),
+		short,
+		long,
+		d1,
+		d2,
+	}
+
+	values := make([]interface{}, len(expected))
+	for i := range values {
+		values[i] = expected[len(expected)-1-i]
+	}
+	sort.Slice(values, func(i, j int) bool {
+		return Compare(values[i], values[j]) < 0
+	})
+	for i, v := range values {
+		if !Equal(v, expected[i]) {
+			t.Fatalf("expected %v at position %d but %v found", expected[i], i, v)
+		}
+	}
+}
+
+func TestHashSet(t *testing.T) {
+	// decoded values can be used as set members through their hash
+	set := map[uint64][]interface{}{}
+	add := func(v interface{}) bool {
+		h := Hash(v)
+		for _, e := range set[h] {
+			if Equal(e, v) {
+				return false
+			}
+		}
+		set[h] = append(set[h], v)
+		return true
+	}
+
+	for i, v := range []interface{}{int8(1), "x", int64(1), []byte("x"), 1.0, math.NaN(), math.NaN(), 2.5} {
+		added := add(v)
+		if added != (i == 0 || i == 1 || i == 5 || i == 7) {
+			t.Errorf("unexpected result %v adding %v", added, v)
+		}
+	}
+}
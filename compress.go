@@ -0,0 +1,196 @@
+package rencode
+
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+)
+
+// DefaultMaxMessageSize is the default maximum size of a decompressed message, see CompressedDecoder.SetMaxSize
+const DefaultMaxMessageSize = 64 << 20
+
+var (
+	// ErrMessageTooLarge is the error returned when a decompressed message exceeds the maximum size
+	ErrMessageTooLarge = errors.New("decompressed message too large")
+)
+
+// CompressedEncoder encodes each value as a separate zlib stream, as the messages of the
+// Deluge RPC protocol; streams are complete once Encode returns, so there is nothing to close.
+type CompressedEncoder struct {
+	w   io.Writer
+	enc Encoder
+	zw  *zlib.Writer
+	buf bytes.Buffer
+}
+
+// NewCompressedEncoder returns an encoder writing zlib-compressed values on w with the default compression level
+func NewCompressedEncoder(w io.Writer) *CompressedEncoder {
+	e, _ := NewCompressedEncoderLevel(w, zlib.DefaultCompression)
+	return e
+}
+
+// NewCompressedEncoderLevel returns an encoder writing zlib-compressed values on w with the specified
+// compression level, one of the levels accepted by zlib.NewWriterLevel
+func NewCompressedEncoderLevel(w io.Writer, level int) (*CompressedEncoder, error) {
+	e := &CompressedEncoder{w: w}
+	zw, err := zlib.NewWriterLevel(&e.buf, level)
+	if err != nil {
+		return nil, err
+	}
+	e.zw = zw
+	e.enc = NewEncoder(zw)
+	e.enc.SetAutoFlush(false)
+	return e, nil
+}
+
+// SetTimeFormat selects the representation of time.Time values, see Encoder.SetTimeFormat
+func (e *CompressedEncoder) SetTimeFormat(format TimeFormat) {
+	e.enc.SetTimeFormat(format)
+}
+
+// Encode encodes data as with Encoder.Encode and writes it as a single zlib stream with one Write call;
+// nothing is written if encoding fails
+func (e *CompressedEncoder) Encode(data interface{}) error {
+	err := e.enc.Encode(data)
+	if err != nil {
+		return err
+	}
+
+	e.buf.Reset()
+	e.zw.Reset(&e.buf)
+	err = e.enc.Flush()
+	if err == nil {
+		err = e.zw.Close()
+	}
+	if err != nil {
+		return err
+	}
+	_, err = e.w.Write(e.buf.Bytes())
+	return err
+}
+
+// CompressedDecoder decodes values written by CompressedEncoder, or any sequence of concatenated
+// zlib streams each holding one rencode value, such as the messages of the Deluge RPC protocol
+type CompressedDecoder struct {
+	r       *bufio.Reader
+	zr      io.ReadCloser
+	dec     *Decoder
+	msg     bytes.Reader
+	buf     bytes.Buffer
+	maxSize int64
+}
+
+// NewCompressedDecoder returns a decoder of zlib-compressed values read from r.
+// When r is a *bufio.Reader it is used directly, so that any data following the
+// decoded streams can then be read from it.
+func NewCompressedDecoder(r io.Reader) *CompressedDecoder {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	d := &CompressedDecoder{r: br, maxSize: DefaultMaxMessageSize}
+	d.dec = NewDecoder(&d.msg)
+	return d
+}
+
+// SetMaxSize sets the maximum size of a decompressed message, to protect against decompression bombs;
+// larger messages cause an ErrMessageTooLarge error
+func (d *CompressedDecoder) SetMaxSize(n int64) {
+	d.maxSize = n
+}
+
+// SetUTF8Mode selects how strings are decoded, see Decoder.SetUTF8Mode
+func (d *CompressedDecoder) SetUTF8Mode(mode UTF8Mode) {
+	d.dec.SetUTF8Mode(mode)
+}
+
+// SetMaxDepth sets the maximum nesting depth of decoded values, see Decoder.SetMaxDepth
+func (d *CompressedDecoder) SetMaxDepth(n int) {
+	d.dec.SetMaxDepth(n)
+}
+
+// next decompresses the next message and makes it available to the decoder
+func (d *CompressedDecoder) next() error {
+	if _, err := d.r.Peek(1); err != nil {
+		// clean end of stream between messages
+		return err
+	}
+
+	var err error
+	if d.zr == nil {
+		d.zr, err = zlib.NewReader(d.r)
+	} else {
+		err = d.zr.(zlib.Resetter).Reset(d.r, nil)
+	}
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+
+	d.buf.Reset()
+	n, err := d.buf.ReadFrom(io.LimitReader(d.zr, d.maxSize+1))
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+	if n > d.maxSize {
+		return ErrMessageTooLarge
+	}
+	d.msg.Reset(d.buf.Bytes())
+	d.dec.Reset(&d.msg)
+	return nil
+}
+
+// decode decodes the single value of the next message with f
+func (d *CompressedDecoder) decode(f func() error) error {
+	err := d.next()
+	if err != nil {
+		return err
+	}
+	err = f()
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+	if _, err = d.dec.PeekKind(); err != io.EOF {
+		return errors.New("trailing data after value in compressed message")
+	}
+	return nil
+}
+
+// DecodeNext decompresses the next message and returns its value, as Decoder.DecodeNext.
+// If no more messages are available, an io.EOF error will be returned.
+func (d *CompressedDecoder) DecodeNext() (interface{}, error) {
+	var v interface{}
+	err := d.decode(func() (err error) {
+		v, err = d.dec.DecodeNext()
+		return
+	})
+	return v, err
+}
+
+// Decode decompresses the next message and stores its value in the value pointed to by v, as Decoder.Decode.
+// If no more messages are available, an io.EOF error will be returned.
+func (d *CompressedDecoder) Decode(v interface{}) error {
+	return d.decode(func() error {
+		return d.dec.Decode(v)
+	})
+}
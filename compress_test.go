@@ -0,0 +1,150 @@
+package rencode
+
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"io"
+	"strings"
+	"testing"
+)
+
+// zlibMessage compresses the encoding of v as Deluge does with zlib.compress(rencode.dumps(v))
+func zlibMessage(t *testing.T, v interface{}) []byte {
+	data, err := Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	zw := zlib.NewWriter(&b)
+	zw.Write(data)
+	zw.Close()
+	return b.Bytes()
+}
+
+func TestCompressedRoundTrip(t *testing.T) {
+	var w chunkedWriter
+	e := NewCompressedEncoder(&w)
+	values := []interface{}{"first", int16(1000), strings.Repeat("x", 10000), nil}
+	for _, v := range values {
+		err := e.Encode(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := e.Encode(make(chan int)); err == nil {
+		t.Fatal("expected an error encoding an unsupported type")
+	}
+	if len(w.chunks) != len(values) {
+		t.Fatalf("expected one write per value but %d found", len(w.chunks))
+	}
+	if len(w.chunks[2]) > 100 {
+		t.Fatalf("value not compressed in %d bytes", len(w.chunks[2]))
+	}
+
+	d := NewCompressedDecoder(bytes.NewReader(bytes.Join(w.chunks, nil)))
+	for _, expected := range values {
+		v, err := d.DecodeNext()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !Equal(v, expected) {
+			t.Fatalf("expected %v but %v found", expected, v)
+		}
+	}
+	if _, err := d.DecodeNext(); err != io.EOF {
+		t.Fatalf("expected EOF but %v found", err)
+	}
+
+	_, err := NewCompressedEncoderLevel(&w, 42)
+	if err == nil {
+		t.Fatal("expected an error for an invalid compression level")
+	}
+}
+
+func TestCompressedConcatenatedStreams(t *testing.T) {
+	var request List
+	request.Add(int8(1))
+	request.Add("daemon.login")
+	var stream []byte
+	stream = append(stream, zlibMessage(t, request)...)
+	stream = append(stream, zlibMessage(t, map[string]int{"a": 1})...)
+	// data that is not part of the messages is left unread
+	stream = append(stream, "rest"...)
+
+	r := bufio.NewReader(bytes.NewReader(stream))
+	d := NewCompressedDecoder(r)
+	var l []interface{}
+	err := d.Decode(&l)
+	if err != nil || len(l) != 2 || string(l[1].([]byte)) != "daemon.login" {
+		t.Fatalf("unexpected value %v (%v)", l, err)
+	}
+	var m map[string]int
+	err = d.Decode(&m)
+	if err != nil || m["a"] != 1 {
+		t.Fatalf("unexpected value %v (%v)", m, err)
+	}
+	rest, err := io.ReadAll(r)
+	if err != nil || string(rest) != "rest" {
+		t.Fatalf("unexpected data %q after messages (%v)", rest, err)
+	}
+}
+
+func TestCompressedErrors(t *testing.T) {
+	message := zlibMessage(t, strings.Repeat("x", 1<<20))
+
+	d := NewCompressedDecoder(bytes.NewReader(message))
+	d.SetMaxSize(1 << 10)
+	if _, err := d.DecodeNext(); err != ErrMessageTooLarge {
+		t.Fatalf("expected ErrMessageTooLarge but %v found", err)
+	}
+
+	d = NewCompressedDecoder(bytes.NewReader(message[:len(message)/2]))
+	if _, err := d.DecodeNext(); err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected io.ErrUnexpectedEOF but %v found", err)
+	}
+
+	d = NewCompressedDecoder(strings.NewReader("not zlib"))
+	if _, err := d.DecodeNext(); err == nil {
+		t.Fatal("expected an error for invalid data")
+	}
+
+	// each message holds exactly one value
+	var b bytes.Buffer
+	zw := zlib.NewWriter(&b)
+	zw.Write([]byte{CHR_NONE, CHR_NONE})
+	zw.Close()
+	d = NewCompressedDecoder(&b)
+	if _, err := d.DecodeNext(); err == nil {
+		t.Fatal("expected an error for trailing data")
+	}
+
+	// a truncated value inside a complete stream
+	b.Reset()
+	zw.Reset(&b)
+	zw.Write([]byte{LIST_FIXED_START + 2, 1})
+	zw.Close()
+	d = NewCompressedDecoder(&b)
+	if _, err := d.DecodeNext(); err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected io.ErrUnexpectedEOF but %v found", err)
+	}
+}
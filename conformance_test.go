@@ -0,0 +1,274 @@
+package rencode
+
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// conformanceVector is a value and its encoding by the reference implementation,
+// see testdata/conformance/gen_vectors.py
+type conformanceVector struct {
+	Name  string
+	Value map[string]interface{}
+	Hex   string
+}
+
+// conformanceReject is an encoding refused by the reference implementation, along with
+// the value it describes when the reference also refuses to encode it
+type conformanceReject struct {
+	Name  string
+	Value map[string]interface{}
+	Hex   string
+}
+
+func loadConformanceVectors(t *testing.T) ([]conformanceVector, []conformanceReject) {
+	f, err := os.Open(filepath.Join("testdata", "conformance", "vectors.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var file struct {
+		Generator string
+		Vectors   []conformanceVector
+		Rejects   []conformanceReject
+	}
+	d := json.NewDecoder(f)
+	d.UseNumber()
+	err = d.Decode(&file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the vectors are only meaningful when produced by the reference implementation,
+	// which gen_vectors.py records as "rencode <version>"
+	if !strings.HasPrefix(file.Generator, "rencode ") {
+		t.Fatalf("vectors.json was generated by %q rather than the reference rencode module; "+
+			"regenerate it with testdata/conformance/gen_vectors.py after \"pip install rencode\"", file.Generator)
+	}
+	t.Logf("%d vectors and %d rejections generated by %s", len(file.Vectors), len(file.Rejects), file.Generator)
+	return file.Vectors, file.Rejects
+}
+
+// conformanceValue returns the Go value described by a vector value specification
+func conformanceValue(spec map[string]interface{}) (interface{}, error) {
+	if len(spec) != 1 {
+		return nil, fmt.Errorf("invalid value specification %v", spec)
+	}
+	for kind, v := range spec {
+		switch kind {
+		case "none":
+			return nil, nil
+		case "bool":
+			return v.(bool), nil
+		case "int":
+			i, ok := new(big.Int).SetString(v.(string), 10)
+			if !ok {
+				return nil, fmt.Errorf("invalid integer %v", v)
+			}
+			if i.IsInt64() {
+				return i.Int64(), nil
+			}
+			return i, nil
+		case "float32":
+			f, err := strconv.ParseFloat(fmt.Sprint(v), 32)
+			return float32(f), err
+		case "float64":
+			return strconv.ParseFloat(fmt.Sprint(v), 64)
+		case "str":
+			return v.(string), nil
+		case "bytes":
+			return hex.DecodeString(v.(string))
+		case "list":
+			var l List
+			for _, e := range v.([]interface{}) {
+				ev, err := conformanceValue(e.(map[string]interface{}))
+				if err != nil {
+					return nil, err
+				}
+				l.Add(ev)
+			}
+			return l, nil
+		case "dict":
+			var d Dictionary
+			for _, e := range v.([]interface{}) {
+				pair := e.([]interface{})
+				k, err := conformanceValue(pair[0].(map[string]interface{}))
+				if err != nil {
+					return nil, err
+				}
+				ev, err := conformanceValue(pair[1].(map[string]interface{}))
+				if err != nil {
+					return nil, err
+				}
+				err = d.Add(k, ev)
+				if err != nil {
+					return nil, err
+				}
+			}
+			return d, nil
+		}
+		return nil, fmt.Errorf("unknown value kind %q", kind)
+	}
+	panic("unreachable")
+}
+
+// conformanceEqual compares an expected value with a decoded one; integers are compared by value
+// and floats by their bits
+func conformanceEqual(expected, found interface{}) bool {
+	switch x := expected.(type) {
+	case int64:
+		i, ok := toInt64(found)
+		return ok && i == x
+	case *big.Int:
+		if u, ok := found.(uint64); ok {
+			return x.IsUint64() && x.Uint64() == u
+		}
+		y, ok := found.(*big.Int)
+		return ok && x.Cmp(y) == 0
+	case float32:
+		y, ok := found.(float32)
+		return ok && math.Float32bits(x) == math.Float32bits(y)
+	case float64:
+		y, ok := found.(float64)
+		return ok && math.Float64bits(x) == math.Float64bits(y)
+	case string:
+		y, ok := found.([]byte)
+		return ok && string(y) == x
+	case []byte:
+		y, ok := found.([]byte)
+		return ok && bytes.Equal(x, y)
+	case List:
+		y, ok := found.(List)
+		if !ok || x.Length() != y.Length() {
+			return false
+		}
+		for i, e := range x.Values() {
+			if !conformanceEqual(e, y.Values()[i]) {
+				return false
+			}
+		}
+		return true
+	case Dictionary:
+		y, ok := found.(Dictionary)
+		if !ok || x.Length() != y.Length() {
+			return false
+		}
+		for i, k := range x.Keys() {
+			if !conformanceEqual(k, y.Keys()[i]) || !conformanceEqual(x.Values()[i], y.Values()[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return expected == found
+}
+
+func TestConformanceVectors(t *testing.T) {
+	vectors, _ := loadConformanceVectors(t)
+	for _, vector := range vectors {
+		value, err := conformanceValue(vector.Value)
+		if err != nil {
+			t.Fatalf("%s: %v", vector.Name, err)
+		}
+		expected, err := hex.DecodeString(vector.Hex)
+		if err != nil {
+			t.Fatalf("%s: %v", vector.Name, err)
+		}
+
+		var b bytes.Buffer
+		e := NewEncoder(&b)
+		err = e.Encode(value)
+		if err != nil {
+			t.Errorf("%s: %v", vector.Name, err)
+			continue
+		}
+		if !bytes.Equal(b.Bytes(), expected) {
+			t.Errorf("%s: expected encoding %x but %x found", vector.Name, expected, b.Bytes())
+		}
+
+		d := NewDecoder(bytes.NewReader(expected))
+		found, err := d.DecodeNext()
+		if err != nil {
+			t.Errorf("%s: %v", vector.Name, err)
+			continue
+		}
+		if !conformanceEqual(value, found) {
+			t.Errorf("%s: expected %v but %v decoded", vector.Name, value, found)
+		}
+		if _, err = d.DecodeNext(); err != io.EOF {
+			t.Errorf("%s: trailing data after value (%v)", vector.Name, err)
+		}
+
+		d = NewDecoder(bytes.NewReader(expected))
+		err = d.Skip()
+		if err != nil {
+			t.Errorf("%s: %v", vector.Name, err)
+		}
+		if _, err = d.DecodeNext(); err != io.EOF {
+			t.Errorf("%s: value not entirely skipped (%v)", vector.Name, err)
+		}
+	}
+}
+
+func TestConformanceRejects(t *testing.T) {
+	_, rejects := loadConformanceVectors(t)
+	if len(rejects) == 0 {
+		t.Fatal("no rejection vectors found")
+	}
+	for _, reject := range rejects {
+		if reject.Value != nil {
+			value, err := conformanceValue(reject.Value)
+			if err != nil {
+				t.Fatalf("%s: %v", reject.Name, err)
+			}
+			e := NewEncoder(ioutil.Discard)
+			err = e.Encode(value)
+			if err == nil {
+				t.Errorf("%s: value accepted by the encoder", reject.Name)
+			}
+		}
+
+		data, err := hex.DecodeString(reject.Hex)
+		if err != nil {
+			t.Fatalf("%s: %v", reject.Name, err)
+		}
+		_, err = NewDecoder(bytes.NewReader(data)).DecodeNext()
+		if err == nil {
+			t.Errorf("%s: encoding accepted by the decoder", reject.Name)
+		}
+		err = NewDecoder(bytes.NewReader(data)).Skip()
+		if err == nil {
+			t.Errorf("%s: encoding skipped without error", reject.Name)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package rencode
+
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// deadlineReader is implemented by readers whose blocked reads can be interrupted, such as net.Conn
+type deadlineReader interface {
+	SetReadDeadline(t time.Time) error
+}
+
+// DecodeNextContext is like DecodeNext but gives up when ctx is cancelled or its deadline expires,
+// returning an error wrapping ctx.Err() that tells how far decoding got.
+//
+// When the underlying reader has a SetReadDeadline method, as net.Conn does, the deadline of ctx
+// is set as its read deadline, unless the one set with the SetReadDeadline method of the decoder is
+// earlier, and a read blocked on it is interrupted by setting a read deadline in the past when ctx is
+// cancelled. The read deadline set with the decoder, or none, is restored afterwards; a deadline set
+// directly on the reader is not known to the decoder and is cleared. With any other reader, the context
+// is only checked before decoding starts, that is between values.
+// A decoder interrupted in the middle of a value cannot be used further on the same stream.
+func (r *Decoder) DecodeNextContext(ctx context.Context) (interface{}, error) {
+	var v interface{}
+	err := r.withContext(ctx, func() (err error) {
+		v, err = r.DecodeNext()
+		return
+	})
+	return v, err
+}
+
+// DecodeContext is like Decode but gives up when ctx is cancelled or its deadline expires,
+// see DecodeNextContext
+func (r *Decoder) DecodeContext(ctx context.Context, v interface{}) error {
+	return r.withContext(ctx, func() error {
+		return r.Decode(v)
+	})
+}
+
+// SetReadDeadline sets the read deadline of the underlying reader, which must have a SetReadDeadline
+// method as net.Conn does; the decoder restores it after DecodeNextContext and DecodeContext.
+// A zero value for t means reads will not time out.
+func (r *Decoder) SetReadDeadline(t time.Time) error {
+	dr, ok := r.src.r.(deadlineReader)
+	if !ok {
+		return fmt.Errorf("rencode: %T does not support read deadlines", r.src.r)
+	}
+	err := dr.SetReadDeadline(t)
+	if err != nil {
+		return err
+	}
+	r.readDeadline = t
+	return nil
+}
+
+// withContext runs decode, interrupting the reads of the underlying reader when ctx is done
+func (r *Decoder) withContext(ctx context.Context, decode func() error) error {
+	start := r.InputOffset()
+	if err := ctx.Err(); err != nil {
+		return r.interrupted(err, start)
+	}
+	dr, ok := r.src.r.(deadlineReader)
+	if !ok || ctx.Done() == nil {
+		return decode()
+	}
+
+	// the deadline of ctx is enforced by the reader itself, without waiting for ctx to be done
+	ctxDeadline, hasDeadline := ctx.Deadline()
+	hasDeadline = hasDeadline && (r.readDeadline.IsZero() || ctxDeadline.Before(r.readDeadline))
+	changed := hasDeadline
+	if hasDeadline {
+		err := dr.SetReadDeadline(ctxDeadline)
+		if err != nil {
+			return err
+		}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			// a deadline in the past makes blocked and future reads fail immediately
+			dr.SetReadDeadline(time.Unix(1, 0))
+			changed = true
+		case <-done:
+		}
+	}()
+	err := decode()
+	close(done)
+	<-stopped
+
+	if changed {
+		restoreErr := dr.SetReadDeadline(r.readDeadline)
+		if err == nil {
+			err = restoreErr
+		}
+	}
+	ctxErr := ctx.Err()
+	if ctxErr == nil && hasDeadline && errors.Is(err, os.ErrDeadlineExceeded) && !time.Now().Before(ctxDeadline) {
+		// the reader can time out just before ctx is done
+		ctxErr = context.DeadlineExceeded
+	}
+	if ctxErr != nil && err != nil {
+		return r.interrupted(ctxErr, start)
+	}
+	return err
+}
+
+// interrupted wraps the error of a done context with the position reached in the stream
+func (r *Decoder) interrupted(err error, start int64) error {
+	offset := r.InputOffset()
+	return fmt.Errorf("decoding interrupted at offset %d, %d bytes into the value: %w", offset, offset-start, err)
+}
@@ -0,0 +1,221 @@
+package rencode
+
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDecodeNextContextConn(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+	d := NewDecoder(client)
+
+	// a list of which only the first element is sent
+	go server.Write([]byte{CHR_LIST, 1})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := d.DecodeNextContext(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded but %v found", err)
+	}
+	if !strings.Contains(err.Error(), "at offset 2, 2 bytes into the value") {
+		t.Errorf("expected the offset reached in %q", err)
+	}
+
+	// the connection can still be read after cancellation
+	d.Reset(client)
+	go server.Write([]byte{CHR_TRUE})
+	v, err := d.DecodeNextContext(context.Background())
+	if err != nil || v != true {
+		t.Errorf("expected true but %v (%v) found", v, err)
+	}
+}
+
+func TestDecodeContextCancel(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+	d := NewDecoder(client)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	var s string
+	err := d.DecodeContext(ctx, &s)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled but %v found", err)
+	}
+	if !strings.Contains(err.Error(), "at offset 0, 0 bytes into the value") {
+		t.Errorf("expected the offset reached in %q", err)
+	}
+
+	go server.Write([]byte{STR_FIXED_START + 2, 'o', 'k'})
+	err = d.DecodeContext(context.Background(), &s)
+	if err != nil || s != "ok" {
+		t.Errorf("expected \"ok\" but %q (%v) found", s, err)
+	}
+}
+
+func TestDecodeNextContextReader(t *testing.T) {
+	var b bytes.Buffer
+	e := NewEncoder(&b)
+	for _, v := range []interface{}{1, "two", NewList(3)} {
+		err := e.Encode(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	d := NewDecoder(&b)
+
+	v, err := d.DecodeNextContext(context.Background())
+	if err != nil || !Equal(v, 1) {
+		t.Fatalf("expected 1 but %v (%v) found", v, err)
+	}
+	if d.InputOffset() != 1 {
+		t.Errorf("expected input offset 1 but %d found", d.InputOffset())
+	}
+
+	// other readers are checked between values
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = d.DecodeNextContext(ctx)
+	if !errors.Is(err, context.Canceled) || !strings.Contains(err.Error(), "at offset 1, 0 bytes") {
+		t.Errorf("expected context.Canceled at offset 1 but %v found", err)
+	}
+
+	var s string
+	err = d.DecodeContext(context.Background(), &s)
+	if err != nil || s != "two" {
+		t.Fatalf("expected \"two\" but %q (%v) found", s, err)
+	}
+	v, err = d.DecodeNext()
+	if err != nil || !Equal(v, NewList(3)) {
+		t.Fatalf("expected [3] but %v (%v) found", v, err)
+	}
+	if d.InputOffset() != 7 {
+		t.Errorf("expected input offset 7 but %d found", d.InputOffset())
+	}
+
+	d.Reset(bytes.NewReader([]byte{CHR_NONE}))
+	if d.InputOffset() != 0 {
+		t.Errorf("expected input offset 0 after Reset but %d found", d.InputOffset())
+	}
+}
+
+// deadlineConn records the read deadlines set on a connection
+type deadlineConn struct {
+	net.Conn
+	mu        sync.Mutex
+	deadlines []time.Time
+}
+
+func (c *deadlineConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.deadlines = append(c.deadlines, t)
+	c.mu.Unlock()
+	return c.Conn.SetReadDeadline(t)
+}
+
+func (c *deadlineConn) last() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.deadlines[len(c.deadlines)-1]
+}
+
+func TestDecodeContextReadDeadline(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+	conn := &deadlineConn{Conn: client}
+	d := NewDecoder(conn)
+	deadline := time.Now().Add(time.Hour)
+	err := d.SetReadDeadline(deadline)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the deadline of the context is set upfront and the one of the decoder is restored
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	ctxDeadline, _ := ctx.Deadline()
+	_, err = d.DecodeNextContext(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded but %v found", err)
+	}
+	if !conn.deadlines[1].Equal(ctxDeadline) {
+		t.Errorf("expected the deadline of the context to be set but %v found", conn.deadlines[1])
+	}
+	if !conn.last().Equal(deadline) {
+		t.Errorf("expected the read deadline to be restored to %v but %v found", deadline, conn.last())
+	}
+
+	// a cancelled read also restores it
+	d.Reset(conn)
+	err = d.SetReadDeadline(deadline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	_, err = d.DecodeNextContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled but %v found", err)
+	}
+	if !conn.last().Equal(deadline) {
+		t.Errorf("expected the read deadline to be restored to %v but %v found", deadline, conn.last())
+	}
+
+	// an earlier read deadline of the decoder is kept and is not reported as the context's
+	d.Reset(conn)
+	err = d.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := len(conn.deadlines)
+	ctx, cancel = context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	_, err = d.DecodeNextContext(ctx)
+	if !errors.Is(err, os.ErrDeadlineExceeded) || errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected os.ErrDeadlineExceeded but %v found", err)
+	}
+	if len(conn.deadlines) != n {
+		t.Errorf("expected the read deadline to be left alone but %v found", conn.deadlines[n:])
+	}
+
+	d.Reset(bytes.NewReader(nil))
+	if err := d.SetReadDeadline(deadline); err == nil {
+		t.Error("expected an error for a reader without read deadlines")
+	}
+}
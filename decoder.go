@@ -20,70 +20,285 @@ package rencode
 // Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"math"
 	"math/big"
 	"strconv"
+	"time"
+	"unicode/utf8"
 )
 
 // Decoder implements a rencode decoder
 type Decoder struct {
-	r io.Reader
+	r *bufio.Reader
+	// src is the underlying reader, which counts the bytes read from it
+	src offsetReader
+	// containers holds the count of remaining elements for each container opened by Token, -1 when unknown
+	containers []int
+	utf8Mode   UTF8Mode
+	// depth is the nesting level of the lists and dictionaries being decoded recursively
+	depth    int
+	maxDepth int
+	// scratch holds fixed-size values being read
+	scratch [8]byte
+	// hook is notified of the values read; hookFrames holds the lists and dictionaries opened by Token
+	hook       Hook
+	hookFrames []ValueInfo
+	// readDeadline is the read deadline of the underlying reader set with SetReadDeadline
+	readDeadline time.Time
 }
 
-var (
-	maxUint64 big.Int
+// UTF8Mode selects how the decoder returns strings
+type UTF8Mode int
+
+const (
+	// UTF8Bytes returns all strings as []byte; this is the default
+	UTF8Bytes UTF8Mode = iota
+	// UTF8Strings returns strings that are valid UTF-8 as string and any other string as []byte
+	UTF8Strings
+	// UTF8Strict returns all strings as string and fails on invalid UTF-8, like Python rencode with decode_utf8
+	UTF8Strict
 )
 
-func init() {
-	maxUint64.SetUint64(^uint64(0))
+// NewDecoder returns a rencode decoder that sources all bytes from the specified reader.
+// The decoder buffers its input and may read data from r beyond the values requested, see Buffered.
+func NewDecoder(r io.Reader) *Decoder {
+	d := &Decoder{}
+	d.Reset(r)
+	return d
 }
 
-// NewDecoder returns a rencode decoder that sources all bytes from the specified reader
-func NewDecoder(r io.Reader) *Decoder {
-	return &Decoder{r}
+// Reset makes the decoder read from r, discarding any state left from the previous stream
+// such as buffered data and partially decoded containers; options like the UTF-8 mode are kept
+func (r *Decoder) Reset(rd io.Reader) {
+	r.src = offsetReader{r: rd}
+	if r.r == nil {
+		r.r = bufio.NewReader(&r.src)
+	} else {
+		r.r.Reset(&r.src)
+	}
+	r.containers = r.containers[:0]
+	r.depth = 0
+	r.hookFrames = r.hookFrames[:0]
+	r.readDeadline = time.Time{}
+}
+
+// Buffered returns a reader of the data remaining in the decoder's buffer, which has been read
+// from the underlying reader but not decoded yet. The reader is valid until the next call to the decoder.
+func (r *Decoder) Buffered() io.Reader {
+	data, _ := r.r.Peek(r.r.Buffered())
+	return bytes.NewReader(data)
+}
+
+// InputOffset returns the number of bytes consumed from the stream since the decoder was created or reset
+func (r *Decoder) InputOffset() int64 {
+	return r.src.n - int64(r.r.Buffered())
+}
+
+// offsetReader counts the bytes read from r
+type offsetReader struct {
+	r io.Reader
+	n int64
 }
 
-func (r *Decoder) readByte() (b byte, err error) {
-	data := []byte{0}
-	_, err = r.r.Read(data)
+func (c *offsetReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// PeekKind returns the kind of the next value in the stream without consuming it; KindEnd
+// is returned for the terminator of a list or dictionary of unknown length.
+// If no more objects are available, an io.EOF error will be returned.
+func (r *Decoder) PeekKind() (Kind, error) {
+	typeCode, err := r.peekByte()
 	if err != nil {
-		return
+		return KindInvalid, err
 	}
-	b = data[0]
-	return
+	kind := kindOf(typeCode)
+	if kind == KindInvalid {
+		return kind, fmt.Errorf("invalid typecode %d", typeCode)
+	}
+	return kind, nil
+}
+
+// SetUTF8Mode changes how strings are returned by DecodeNext and Token.
+// Decoding into typed string and []byte values works with any mode, although
+// UTF8Strict rejects invalid UTF-8 even for []byte destinations.
+func (r *Decoder) SetUTF8Mode(mode UTF8Mode) {
+	r.utf8Mode = mode
 }
 
-func (r *Decoder) readSlice(delim byte) (data []byte, err error) {
-	var b byte
+// stringValue returns the decoded string data according to the UTF-8 mode
+func (r *Decoder) stringValue(data []byte) (interface{}, error) {
+	switch r.utf8Mode {
+	case UTF8Strings:
+		if utf8.Valid(data) {
+			return string(data), nil
+		}
+	case UTF8Strict:
+		if !utf8.Valid(data) {
+			return nil, fmt.Errorf("invalid UTF-8 string %q", data)
+		}
+		return string(data), nil
+	}
+	return data, nil
+}
+
+func (r *Decoder) readByte() (byte, error) {
+	return r.r.ReadByte()
+}
+
+// readFixed reads n bytes into scratch
+func (r *Decoder) readFixed(n int) ([]byte, error) {
+	_, err := io.ReadFull(r.r, r.scratch[:n])
+	return r.scratch[:n], unexpectedEOF(err)
+}
+
+// peekByte returns the next byte without consuming it
+func (r *Decoder) peekByte() (byte, error) {
+	b, err := r.r.Peek(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+// maxLengthDigits is the maximum number of digits of a string length prefix, enough for any int64
+const maxLengthDigits = 19
+
+// readLength reads the length prefix of a string whose first digit is first, up to the ':' delimiter
+func (r *Decoder) readLength(first byte) (int64, error) {
+	digits := []byte{first}
 	for {
-		b, err = r.readByte()
+		b, err := r.readByte()
 		if err != nil {
-			return
+			return 0, unexpectedEOF(err)
 		}
-
-		if b == delim {
+		if b == ':' {
 			break
 		}
+		if len(digits) == maxLengthDigits {
+			return 0, fmt.Errorf("string length is longer than %d digits", maxLengthDigits)
+		}
+		digits = append(digits, b)
+	}
+	return strconv.ParseInt(string(digits), 10, 64)
+}
+
+// readChunkSize is the size of the chunks in which long strings are read, so that
+// memory is only allocated as the announced data actually arrives
+const readChunkSize = 64 << 10
+
+// readString reads a string of n bytes
+func (r *Decoder) readString(n int64) ([]byte, error) {
+	if n <= readChunkSize {
+		data := make([]byte, n)
+		_, err := io.ReadFull(r.r, data)
+		return data, unexpectedEOF(err)
+	}
+
+	var data []byte
+	for int64(len(data)) < n {
+		chunk := n - int64(len(data))
+		if chunk > readChunkSize {
+			chunk = readChunkSize
+		}
+		start := len(data)
+		data = append(data, make([]byte, chunk)...)
+		_, err := io.ReadFull(r.r, data[start:])
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+	}
+	return data, nil
+}
 
-		data = append(data, b)
+// unexpectedEOF converts io.EOF, reported when a value is truncated, to io.ErrUnexpectedEOF
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
 	}
-	return
+	return err
+}
+
+// DefaultMaxDepth is the default maximum nesting depth of lists and dictionaries accepted by a decoder
+const DefaultMaxDepth = 10000
+
+// SetMaxDepth changes the maximum nesting depth of lists and dictionaries that the decoder accepts
+// before failing, which protects against stack exhaustion; zero restores DefaultMaxDepth
+func (r *Decoder) SetMaxDepth(n int) {
+	r.maxDepth = n
+}
+
+// checkDepth returns an error if one more level of nesting would exceed the maximum depth
+func (r *Decoder) checkDepth() error {
+	max := r.maxDepth
+	if max <= 0 {
+		max = DefaultMaxDepth
+	}
+	if r.depth+len(r.containers) >= max {
+		return fmt.Errorf("maximum nesting depth of %d exceeded", max)
+	}
+	return nil
+}
+
+// enter accounts for a list or dictionary being decoded recursively, see leave
+func (r *Decoder) enter() error {
+	err := r.checkDepth()
+	if err != nil {
+		return err
+	}
+	r.depth++
+	return nil
+}
+
+func (r *Decoder) leave() {
+	r.depth--
 }
 
 // DecodeNext returns the next available object stored in the rencode stream.
+// Big numbers are returned as int64 or uint64 when they fit and as *big.Int otherwise.
 // If no more objects are available, an io.EOF error will be returned.
 func (r *Decoder) DecodeNext() (interface{}, error) {
+	v, err := r.decodeNext()
+	if err != nil {
+		if len(r.containers) > 0 {
+			// within a container opened by Token
+			return nil, unexpectedEOF(err)
+		}
+		return nil, err
+	}
+
+	r.tokenDone()
+	return v, nil
+}
+
+func (r *Decoder) decodeNext() (interface{}, error) {
 	typeCode, err := r.readByte()
 	if err != nil {
 		return nil, err
 	}
 
-	return r.decode(typeCode)
+	// the value is truncated if the stream ends after its typecode
+	v, err := r.decode(typeCode)
+	return v, unexpectedEOF(err)
 }
 
-func (r *Decoder) decode(typeCode byte) (v interface{}, err error) {
+// decode decodes the value identified by typeCode, reporting it to the hook if any
+func (r *Decoder) decode(typeCode byte) (interface{}, error) {
+	if r.hook != nil {
+		return r.decodeHooked(typeCode)
+	}
+	return r.decodeTypeCode(typeCode)
+}
+
+func (r *Decoder) decodeTypeCode(typeCode byte) (v interface{}, err error) {
 	switch typeCode {
 	case CHR_TRUE:
 		v = true
@@ -92,56 +307,46 @@ func (r *Decoder) decode(typeCode byte) (v interface{}, err error) {
 	case CHR_NONE:
 		// leave v as nil
 	case CHR_INT1:
-		data := []byte{0}
-		_, err = r.r.Read(data)
-		if err != nil {
-			return
-		}
+		var data []byte
+		data, err = r.readFixed(1)
 		v = int8(data[0])
 	case CHR_INT2:
-		var data int16
-		err = binary.Read(r.r, binary.BigEndian, &data)
-		v = data
+		var data []byte
+		data, err = r.readFixed(2)
+		v = int16(binary.BigEndian.Uint16(data))
 	case CHR_INT4:
-		var data int32
-		err = binary.Read(r.r, binary.BigEndian, &data)
-		v = data
+		var data []byte
+		data, err = r.readFixed(4)
+		v = int32(binary.BigEndian.Uint32(data))
 	case CHR_INT8:
-		var data int64
-		err = binary.Read(r.r, binary.BigEndian, &data)
-		v = data
+		var data []byte
+		data, err = r.readFixed(8)
+		v = int64(binary.BigEndian.Uint64(data))
 	case CHR_INT:
-		var collected []byte
-		collected, err = r.readSlice(CHR_TERM)
-		if err != nil {
-			return
-		}
-
-		var i big.Int
-		_, err = fmt.Sscan(string(collected), &i)
-		if err != nil {
-			return
-		}
-
-		// if this is simply an uint64, return it as such
-		if i.Cmp(&maxUint64) == 0 {
-			v = maxUint64
-		} else {
-			v = i
-		}
+		v, err = r.decodeBigNumber()
 	case CHR_FLOAT32:
-		var data float32
-		err = binary.Read(r.r, binary.BigEndian, &data)
-		v = data
+		var data []byte
+		data, err = r.readFixed(4)
+		v = math.Float32frombits(binary.BigEndian.Uint32(data))
 	case CHR_FLOAT64:
-		var data float64
-		err = binary.Read(r.r, binary.BigEndian, &data)
-		v = data
+		var data []byte
+		data, err = r.readFixed(8)
+		v = math.Float64frombits(binary.BigEndian.Uint64(data))
 	case CHR_LIST:
+		err = r.enter()
+		if err != nil {
+			return
+		}
 		v, err = r.decodeList()
+		r.leave()
 		return
 	case CHR_DICT:
+		err = r.enter()
+		if err != nil {
+			return
+		}
 		v, err = r.decodeDict()
+		r.leave()
 		return
 	default:
 		if INT_POS_FIXED_START <= typeCode && typeCode < INT_POS_FIXED_START+INT_POS_FIXED_COUNT {
@@ -154,43 +359,38 @@ func (r *Decoder) decode(typeCode byte) (v interface{}, err error) {
 			return
 		}
 		if STR_FIXED_START <= typeCode && typeCode < STR_FIXED_START+STR_FIXED_COUNT {
-			b := typeCode - STR_FIXED_START
-			data := make([]byte, b)
-
-			_, err = r.r.Read(data)
+			var data []byte
+			data, err = r.readString(int64(typeCode - STR_FIXED_START))
 			if err != nil {
 				return
 			}
-			v = data
+			v, err = r.stringValue(data)
 			return
 		}
 		if '1' <= typeCode && typeCode <= '9' {
-			var collected []byte
-			collected, err = r.readSlice(':')
+			var stringSz int64
+			stringSz, err = r.readLength(typeCode)
 			if err != nil {
 				return
 			}
 
-			// use the typeCode as first digit
-			n := []byte{typeCode}
-			n = append(n, collected...)
-
-			var stringSz int
-			stringSz, err = strconv.Atoi(string(n))
+			var data []byte
+			data, err = r.readString(stringSz)
 			if err != nil {
 				return
 			}
 
-			data := make([]byte, stringSz)
-			_, err = r.r.Read(data)
+			v, err = r.stringValue(data)
+			return
+		}
+
+		if LIST_FIXED_START <= typeCode && typeCode <= (LIST_FIXED_START+LIST_FIXED_COUNT-1) {
+			err = r.enter()
 			if err != nil {
 				return
 			}
+			defer r.leave()
 
-			v = data
-		}
-
-		if LIST_FIXED_START <= typeCode && typeCode <= (LIST_FIXED_START+LIST_FIXED_COUNT-1) {
 			var l List
 			var value interface{}
 			var i byte
@@ -198,7 +398,7 @@ func (r *Decoder) decode(typeCode byte) (v interface{}, err error) {
 
 			for i = 0; i < size; i++ {
 				// get next value
-				value, err = r.DecodeNext()
+				value, err = r.decodeNext()
 				if err != nil {
 					return
 				}
@@ -207,8 +407,15 @@ func (r *Decoder) decode(typeCode byte) (v interface{}, err error) {
 				l.Add(value)
 			}
 			v = l
+			return
 		}
 		if DICT_FIXED_START <= typeCode && typeCode < DICT_FIXED_START+DICT_FIXED_COUNT {
+			err = r.enter()
+			if err != nil {
+				return
+			}
+			defer r.leave()
+
 			var d Dictionary
 			var key, value interface{}
 			var i byte
@@ -216,31 +423,162 @@ func (r *Decoder) decode(typeCode byte) (v interface{}, err error) {
 
 			for i = 0; i < size; i++ {
 				// get next key
-				key, err = r.DecodeNext()
+				key, err = r.decodeNext()
 				if err != nil {
 					return
 				}
 
 				// get next value
-				value, err = r.DecodeNext()
+				value, err = r.decodeNext()
 				if err != nil {
 					return
 				}
 
 				// add, never update existing key
-				err = d.Add(key, value)
+				err = d.addDecoded(key, value)
 				if err != nil {
 					return
 				}
 			}
 			v = d
+			return
 		}
+
+		err = fmt.Errorf("invalid typecode %d", typeCode)
 	} // end of switch
 
 	// AOK
 	return
 }
 
+// decodeBigNumber reads the base 10 digits of a big number up to its terminator; the value is
+// returned as an int64 or uint64 when it fits and as a *big.Int otherwise
+func (r *Decoder) decodeBigNumber() (interface{}, error) {
+	var collected []byte
+	for {
+		b, err := r.readByte()
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		if b == CHR_TERM {
+			break
+		}
+		if len(collected) == MAX_INT_LENGTH-1 {
+			return nil, fmt.Errorf("Number is longer than %d characters", MAX_INT_LENGTH-1)
+		}
+		collected = append(collected, b)
+	}
+
+	i, ok := new(big.Int).SetString(string(collected), 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid big number %q", collected)
+	}
+	if i.IsInt64() {
+		return i.Int64(), nil
+	}
+	if i.IsUint64() {
+		return i.Uint64(), nil
+	}
+	return i, nil
+}
+
+// discard consumes n bytes from the stream
+func (r *Decoder) discard(n int64) error {
+	_, err := io.CopyN(ioutil.Discard, r.r, n)
+	return unexpectedEOF(err)
+}
+
+// skip consumes the value identified by typeCode without decoding it
+func (r *Decoder) skip(typeCode byte) error {
+	switch typeCode {
+	case CHR_TRUE, CHR_FALSE, CHR_NONE:
+		return nil
+	case CHR_INT1:
+		return r.discard(1)
+	case CHR_INT2:
+		return r.discard(2)
+	case CHR_INT4, CHR_FLOAT32:
+		return r.discard(4)
+	case CHR_INT8, CHR_FLOAT64:
+		return r.discard(8)
+	case CHR_INT:
+		_, err := r.decodeBigNumber()
+		return err
+	case CHR_LIST:
+		return r.eachElement(typeCode, r.skip)
+	case CHR_DICT:
+		return r.eachElement(typeCode, r.skipPair)
+	}
+
+	switch {
+	case INT_POS_FIXED_START <= typeCode && typeCode < INT_POS_FIXED_START+INT_POS_FIXED_COUNT,
+		INT_NEG_FIXED_START <= typeCode && typeCode < INT_NEG_FIXED_START+INT_NEG_FIXED_COUNT:
+		return nil
+	case STR_FIXED_START <= typeCode && typeCode < STR_FIXED_START+STR_FIXED_COUNT:
+		return r.discard(int64(typeCode - STR_FIXED_START))
+	case '1' <= typeCode && typeCode <= '9':
+		stringSz, err := r.readLength(typeCode)
+		if err != nil {
+			return err
+		}
+		return r.discard(stringSz)
+	case LIST_FIXED_START <= typeCode && typeCode <= LIST_FIXED_START+LIST_FIXED_COUNT-1:
+		return r.eachElement(typeCode, r.skip)
+	case DICT_FIXED_START <= typeCode && typeCode < DICT_FIXED_START+DICT_FIXED_COUNT:
+		return r.eachElement(typeCode, r.skipPair)
+	}
+
+	return fmt.Errorf("invalid typecode %d", typeCode)
+}
+
+func (r *Decoder) skipPair(keyCode byte) error {
+	err := r.skip(keyCode)
+	if err != nil {
+		return err
+	}
+	valueCode, err := r.readByte()
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+	if valueCode == CHR_TERM {
+		return fmt.Errorf("incomplete key-value pair in dictionary data")
+	}
+	return r.skip(valueCode)
+}
+
+// eachElement calls fn with the typecode of each element of the list or dictionary identified by typeCode;
+// for dictionaries, fn is called once per (key, value) pair with the typecode of the key and must consume the value too
+func (r *Decoder) eachElement(typeCode byte, fn func(byte) error) error {
+	size := -1
+	if LIST_FIXED_START <= typeCode && typeCode <= LIST_FIXED_START+LIST_FIXED_COUNT-1 {
+		size = int(typeCode - LIST_FIXED_START)
+	} else if DICT_FIXED_START <= typeCode && typeCode < DICT_FIXED_START+DICT_FIXED_COUNT {
+		size = int(typeCode - DICT_FIXED_START)
+	}
+
+	err := r.enter()
+	if err != nil {
+		return err
+	}
+	defer r.leave()
+
+	for i := 0; size < 0 || i < size; i++ {
+		elementCode, err := r.readByte()
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		if size < 0 && elementCode == CHR_TERM {
+			// no more elements
+			break
+		}
+		err = fn(elementCode)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (r *Decoder) decodeDict() (d Dictionary, err error) {
 	var key, value interface{}
 	var typeCode byte
@@ -277,7 +615,7 @@ func (r *Decoder) decodeDict() (d Dictionary, err error) {
 		}
 
 		// add, never update existing key
-		err = d.Add(key, value)
+		err = d.addDecoded(key, value)
 		if err != nil {
 			return
 		}
@@ -20,7 +20,10 @@ package rencode
 // Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
 
 import (
+	"bytes"
 	"errors"
+	"math/big"
+	"reflect"
 )
 
 var (
@@ -40,10 +43,10 @@ func (d *Dictionary) Keys() []interface{} {
 }
 
 // Get returns the value stored for the matching key.
-// Note that special equality rules apply.
+// Note that special equality rules apply, see keyEqual.
 func (d *Dictionary) Get(key interface{}) (interface{}, error) {
 	for i, k := range d.keys {
-		if deepEqual(k, key) {
+		if keyEqual(k, key) {
 			return d.values[i], nil
 		}
 	}
@@ -53,7 +56,7 @@ func (d *Dictionary) Get(key interface{}) (interface{}, error) {
 // Set updates or add the specified key with the specified value and returns true if a previous value was overwritten
 func (d *Dictionary) Set(key, value interface{}) bool {
 	for i, k := range d.keys {
-		if deepEqual(k, key) {
+		if keyEqual(k, key) {
 			d.values[i] = value
 			return true
 		}
@@ -64,10 +67,22 @@ func (d *Dictionary) Set(key, value interface{}) bool {
 	return false
 }
 
+// Delete removes the specified key and its value and returns true if the key was found
+func (d *Dictionary) Delete(key interface{}) bool {
+	for i, k := range d.keys {
+		if keyEqual(k, key) {
+			d.keys = append(d.keys[:i], d.keys[i+1:]...)
+			d.values = append(d.values[:i], d.values[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
 // Add appends a new (key, value) pair or returns an error if key already exists
 func (d *Dictionary) Add(key, value interface{}) error {
 	for _, k := range d.keys {
-		if deepEqual(k, key) {
+		if keyEqual(k, key) {
 			return ErrKeyAlreadyExists
 		}
 	}
@@ -77,8 +92,75 @@ func (d *Dictionary) Add(key, value interface{}) error {
 	return nil
 }
 
-// Equals will compare keys and values to be a perfect match.
-// Note that special equality rules apply.
+// addDecoded is like Add but for keys read from a stream, which also match keys of a different
+// integer width with the same value: the width depends on the encoding, so such keys would be
+// duplicates once encoded again
+func (d *Dictionary) addDecoded(key, value interface{}) error {
+	x, isInt := intValue(key)
+	for _, k := range d.keys {
+		if isInt {
+			if y, ok := intValue(k); ok && x == y {
+				return ErrKeyAlreadyExists
+			}
+		}
+		if keyEqual(k, key) {
+			return ErrKeyAlreadyExists
+		}
+	}
+
+	d.keys = append(d.keys, key)
+	d.values = append(d.values, value)
+	return nil
+}
+
+// keyEqual compares dictionary keys; string and []byte keys with the same bytes match, while
+// all other keys must have the same type, so that for example int8(1) and int64(1) are distinct keys.
+// Lists and dictionaries are compared with their Equals method.
+func keyEqual(a, b interface{}) bool {
+	switch x := a.(type) {
+	case string:
+		switch y := b.(type) {
+		case string:
+			return x == y
+		case []byte:
+			return x == string(y)
+		}
+		return false
+	case []byte:
+		switch y := b.(type) {
+		case []byte:
+			return bytes.Equal(x, y)
+		case string:
+			return string(x) == y
+		}
+		return false
+	case Dictionary:
+		y, ok := b.(Dictionary)
+		return ok && x.Equals(&y)
+	case List:
+		y, ok := b.(List)
+		return ok && x.Equals(&y)
+	case *big.Int:
+		y, ok := b.(*big.Int)
+		return ok && x.Cmp(y) == 0
+	case big.Int:
+		y, ok := b.(big.Int)
+		return ok && x.Cmp(&y) == 0
+	}
+	switch b.(type) {
+	case []byte, Dictionary, List, big.Int:
+		// not comparable with ==
+		return false
+	}
+	if t := reflect.TypeOf(a); t != nil && t == reflect.TypeOf(b) && !t.Comparable() {
+		// == would panic, as for a slice or map stored in an interface{} key
+		return false
+	}
+	return a == b
+}
+
+// Equals will compare keys and values to be a perfect match, in the same order;
+// they are compared with Equal, which also compares dictionaries regardless of the order of their keys.
 func (d *Dictionary) Equals(b *Dictionary) bool {
 	if d.Length() != b.Length() {
 		return false
@@ -86,12 +168,12 @@ func (d *Dictionary) Equals(b *Dictionary) bool {
 
 	keys2 := b.Keys()
 	for i, k1 := range d.keys {
-		if !deepEqual(k1, keys2[i]) {
+		if !Equal(k1, keys2[i]) {
 			return false
 		}
 
 		// compare values as well
-		if !deepEqual(d.values[i], b.values[i]) {
+		if !Equal(d.values[i], b.values[i]) {
 			return false
 		}
 	}
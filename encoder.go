@@ -25,6 +25,10 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
 )
 
 // Constants as defined in https://github.com/aresch/rencode/blob/master/rencode/rencode.pyx
@@ -62,31 +66,132 @@ const (
 	LIST_FIXED_COUNT = 64
 )
 
-// Encoder implements a rencode encoder
+// Marshaler is the interface implemented by types that can encode themselves as rencode values
+type Marshaler interface {
+	EncodeRencode(e *Encoder) error
+}
+
+// Encoder implements a rencode encoder.
+//
+// Values are accumulated in an internal buffer; by default the buffer is written on the
+// underlying Writer with a single Write call each time a top-level value is complete,
+// see SetAutoFlush and Flush. A value that fails to encode is discarded from the buffer,
+// so that a partial value is never written.
 type Encoder struct {
-	w io.Writer
+	w   io.Writer
+	buf []byte
+	// level is the nesting level of the value being encoded, 0 at value boundaries
+	level int
+	// mark is the position in buf of the top-level value being encoded
+	mark        int
+	manualFlush bool
+	timeFormat  TimeFormat
+	// written is the number of bytes flushed since the encoder was created or reset
+	written int64
+	// hook is notified of the values written; hookFrames holds the lists and dictionaries being written
+	hook       Hook
+	hookFrames []ValueInfo
+	// scratch holds a typecode followed by the fixed-size value being written
+	scratch [9]byte
 }
 
 // NewEncoder returns a rencode encoder that writes on specified Writer
 func NewEncoder(w io.Writer) Encoder {
-	return Encoder{w}
+	return Encoder{w: w}
+}
+
+// Reset makes the encoder write on w, so that it can be reused; buffered data is discarded
+func (r *Encoder) Reset(w io.Writer) {
+	r.w = w
+	r.buf = r.buf[:0]
+	r.level = 0
+	r.mark = 0
+	r.written = 0
+	r.hookFrames = r.hookFrames[:0]
+}
+
+// SetAutoFlush controls whether buffered data is written on the underlying Writer
+// each time a top-level value is complete, which is the default. When disabled,
+// data is written only by calls to Flush.
+func (r *Encoder) SetAutoFlush(enabled bool) {
+	r.manualFlush = !enabled
+}
+
+// Buffered returns the number of bytes that have not yet been written on the underlying Writer
+func (r *Encoder) Buffered() int {
+	return len(r.buf)
+}
+
+// Flush writes buffered data on the underlying Writer. Flushing while a list or dictionary
+// started with EncodeListStart or EncodeDictStart is still open writes a partial value.
+func (r *Encoder) Flush() error {
+	if len(r.buf) == 0 {
+		return nil
+	}
+	n, err := r.w.Write(r.buf)
+	if err == nil && n < len(r.buf) {
+		err = io.ErrShortWrite
+	}
+	r.buf = r.buf[:copy(r.buf, r.buf[n:])]
+	r.mark = 0
+	r.written += int64(n)
+	return err
+}
+
+// begin marks the start of a value that is complete only once end is called
+func (r *Encoder) begin() {
+	if r.level == 0 {
+		r.mark = len(r.buf)
+	}
+	r.level++
+}
+
+// end marks the end of a value started with begin; if err is not nil the whole top-level value
+// being encoded, including any list or dictionary still open, is discarded
+func (r *Encoder) end(err error) error {
+	if r.level > 0 {
+		r.level--
+	}
+	if err != nil {
+		if r.hook != nil {
+			r.hookEnd(err)
+		}
+		r.buf = r.buf[:r.mark]
+		r.level = 0
+		return err
+	}
+	return r.boundary()
+}
+
+// boundary flushes the buffer when automatic flushing is enabled and no value is being encoded
+func (r *Encoder) boundary() error {
+	if r.level == 0 && !r.manualFlush {
+		return r.Flush()
+	}
+	return nil
+}
+
+// writeScalar writes the typecode followed by the first n bytes of the value stored in scratch
+func (r *Encoder) writeScalar(typeCode byte, n int) error {
+	r.scratch[0] = typeCode
+	start := len(r.buf)
+	r.buf = append(r.buf, r.scratch[:1+n]...)
+	if r.hook != nil {
+		r.hookScalar(start)
+	}
+	return r.boundary()
 }
 
 // EncodeInt8 encodes an int8 value
 func (r *Encoder) EncodeInt8(x int8) error {
 	if 0 <= x && x < INT_POS_FIXED_COUNT {
-		_, err := r.w.Write([]byte{byte(INT_POS_FIXED_START + x)})
-		return err
+		return r.writeScalar(byte(INT_POS_FIXED_START+x), 0)
 	}
 	if -INT_NEG_FIXED_COUNT <= x && x < 0 {
-		_, err := r.w.Write([]byte{byte(INT_NEG_FIXED_START - 1 - x)})
-		return err
+		return r.writeScalar(byte(INT_NEG_FIXED_START-1-x), 0)
 	}
-	if -128 < x && x <= 127 {
-		_, err := r.w.Write([]byte{CHR_INT1, byte(x)})
-		return err
-	}
-	panic("impossible just happened")
+	r.scratch[1] = byte(x)
+	return r.writeScalar(CHR_INT1, 1)
 }
 
 // EncodeBool encodes a bool value
@@ -98,93 +203,184 @@ func (r *Encoder) EncodeBool(b bool) error {
 		data = CHR_FALSE
 	}
 
-	_, err := r.w.Write([]byte{data})
-	return err
+	return r.writeScalar(data, 0)
 }
 
 // EncodeInt16 encodes an int16 value
 func (r *Encoder) EncodeInt16(x int16) error {
-	_, err := r.w.Write([]byte{CHR_INT2})
-	if err != nil {
-		return err
-	}
-	return binary.Write(r.w, binary.BigEndian, x)
+	binary.BigEndian.PutUint16(r.scratch[1:], uint16(x))
+	return r.writeScalar(CHR_INT2, 2)
 }
 
 // EncodeInt32 encodes an int32 value
 func (r *Encoder) EncodeInt32(x int32) error {
-	_, err := r.w.Write([]byte{CHR_INT4})
-	if err != nil {
-		return err
-	}
-	return binary.Write(r.w, binary.BigEndian, x)
+	binary.BigEndian.PutUint32(r.scratch[1:], uint32(x))
+	return r.writeScalar(CHR_INT4, 4)
 }
 
 // EncodeInt64 encodes an int64 value
 func (r *Encoder) EncodeInt64(x int64) error {
-	_, err := r.w.Write([]byte{CHR_INT8})
-	if err != nil {
-		return err
+	binary.BigEndian.PutUint64(r.scratch[1:], uint64(x))
+	return r.writeScalar(CHR_INT8, 8)
+}
+
+// EncodeInt encodes an integer value using the smallest of the available representations
+func (r *Encoder) EncodeInt(x int64) error {
+	if math.MinInt8 <= x && x <= math.MaxInt8 {
+		return r.EncodeInt8(int8(x))
+	}
+	if math.MinInt16 <= x && x <= math.MaxInt16 {
+		return r.EncodeInt16(int16(x))
 	}
-	return binary.Write(r.w, binary.BigEndian, x)
+	if math.MinInt32 <= x && x <= math.MaxInt32 {
+		return r.EncodeInt32(int32(x))
+	}
+	return r.EncodeInt64(x)
+}
+
+// EncodeBigInt encodes an integer of arbitrary size; values fitting in an int64 use
+// the smallest of the available representations, as with EncodeInt
+func (r *Encoder) EncodeBigInt(x *big.Int) error {
+	if x.IsInt64() {
+		return r.EncodeInt(x.Int64())
+	}
+	return r.EncodeBigNumber(x.String())
 }
 
-// EncodeBigNumber encodes a big number (> 2^64)
+// EncodeBigNumber encodes a big number (> 2^64) written as base 10 digits with an optional minus sign;
+// as with the reference implementation, numbers of MAX_INT_LENGTH characters or more are rejected
 func (r *Encoder) EncodeBigNumber(s string) error {
-	_, err := r.w.Write([]byte{CHR_INT})
+	err := checkBigNumber(s)
 	if err != nil {
 		return err
 	}
-	_, err = r.w.Write([]byte(s))
-	if err != nil {
-		return err
+	start := len(r.buf)
+	r.buf = append(r.buf, CHR_INT)
+	r.buf = append(r.buf, s...)
+	r.buf = append(r.buf, CHR_TERM)
+	if r.hook != nil {
+		r.hookScalar(start)
 	}
-	_, err = r.w.Write([]byte{CHR_TERM})
-	return err
+	return r.boundary()
+}
+
+// checkBigNumber returns an error if s is not a base 10 integer shorter than MAX_INT_LENGTH characters
+func checkBigNumber(s string) error {
+	if len(s) >= MAX_INT_LENGTH {
+		return fmt.Errorf("Number is longer than %d characters", MAX_INT_LENGTH-1)
+	}
+	digits := strings.TrimPrefix(s, "-")
+	if digits == "" {
+		return fmt.Errorf("invalid big number %q", s)
+	}
+	for i := 0; i < len(digits); i++ {
+		if digits[i] < '0' || digits[i] > '9' {
+			return fmt.Errorf("invalid big number %q", s)
+		}
+	}
+	return nil
 }
 
 // EncodeNone encodes a nil value without any type information
 func (r *Encoder) EncodeNone() error {
-	_, err := r.w.Write([]byte{CHR_NONE})
-	return err
+	return r.writeScalar(CHR_NONE, 0)
+}
+
+// appendStringLength appends the typecode or length prefix of a string of n bytes
+func (r *Encoder) appendStringLength(n int) {
+	if n < STR_FIXED_COUNT {
+		r.buf = append(r.buf, byte(STR_FIXED_START+n))
+		return
+	}
+	r.buf = strconv.AppendInt(r.buf, int64(n), 10)
+	r.buf = append(r.buf, ':')
 }
 
 // EncodeBytes encodes a byte slice; all strings should be encoded as byte slices
 func (r *Encoder) EncodeBytes(b []byte) error {
-	if len(b) < STR_FIXED_COUNT {
-		_, err := r.w.Write([]byte{byte(STR_FIXED_START + len(b))})
-		if err != nil {
-			return err
-		}
-		_, err = r.w.Write(b)
-		return err
+	start := len(r.buf)
+	r.appendStringLength(len(b))
+	r.buf = append(r.buf, b...)
+	if r.hook != nil {
+		r.hookScalar(start)
 	}
+	return r.boundary()
+}
 
-	prefix := []byte(fmt.Sprintf("%d:", len(b)))
-
-	_, err := r.w.Write(prefix)
-	if err != nil {
-		return err
+// EncodeString encodes a string as a byte slice
+func (r *Encoder) EncodeString(s string) error {
+	start := len(r.buf)
+	r.appendStringLength(len(s))
+	r.buf = append(r.buf, s...)
+	if r.hook != nil {
+		r.hookScalar(start)
 	}
-
-	_, err = r.w.Write(b)
-	return err
+	return r.boundary()
 }
 
 // EncodeFloat32 encodes a float32 value
 func (r *Encoder) EncodeFloat32(f float32) error {
-	_, err := r.w.Write([]byte{CHR_FLOAT32})
-	if err != nil {
-		return err
-	}
-	return binary.Write(r.w, binary.BigEndian, f)
+	binary.BigEndian.PutUint32(r.scratch[1:], math.Float32bits(f))
+	return r.writeScalar(CHR_FLOAT32, 4)
 }
 
 // EncodeFloat64 encodes an float64 value
 func (r *Encoder) EncodeFloat64(f float64) error {
-	_, err := r.w.Write([]byte{CHR_FLOAT64})
-	if err != nil {
-		return err
+	binary.BigEndian.PutUint64(r.scratch[1:], math.Float64bits(f))
+	return r.writeScalar(CHR_FLOAT64, 8)
+}
+
+// EncodeListStart begins a list of n elements, or of unknown length if n is negative;
+// the elements must then be encoded followed by a call to EncodeListEnd with the same n
+func (r *Encoder) EncodeListStart(n int) error {
+	r.begin()
+	typeCode := byte(CHR_LIST)
+	if 0 <= n && n < LIST_FIXED_COUNT {
+		typeCode = byte(LIST_FIXED_START + n)
+	}
+	return r.writeContainerStart(typeCode)
+}
+
+// EncodeListEnd terminates a list started with EncodeListStart(n)
+func (r *Encoder) EncodeListEnd(n int) error {
+	if n < 0 || n >= LIST_FIXED_COUNT {
+		r.buf = append(r.buf, CHR_TERM)
+	}
+	// otherwise the length is embedded in typecode
+	if r.hook != nil {
+		r.hookEnd(nil)
+	}
+	return r.end(nil)
+}
+
+// EncodeDictStart begins a dictionary of n (key, value) pairs, or of unknown length if n is negative;
+// the keys and values must then be encoded followed by a call to EncodeDictEnd with the same n
+func (r *Encoder) EncodeDictStart(n int) error {
+	r.begin()
+	typeCode := byte(CHR_DICT)
+	if 0 <= n && n < DICT_FIXED_COUNT {
+		typeCode = byte(DICT_FIXED_START + n)
+	}
+	return r.writeContainerStart(typeCode)
+}
+
+// writeContainerStart writes the typecode of a list or dictionary started with begin
+func (r *Encoder) writeContainerStart(typeCode byte) error {
+	if r.hook != nil {
+		r.hookStart(typeCode)
+	}
+	r.buf = append(r.buf, typeCode)
+	return r.boundary()
+}
+
+// EncodeDictEnd terminates a dictionary started with EncodeDictStart(n)
+func (r *Encoder) EncodeDictEnd(n int) error {
+	if n < 0 || n >= DICT_FIXED_COUNT {
+		r.buf = append(r.buf, CHR_TERM)
+	}
+	// otherwise the length is embedded in typecode
+	if r.hook != nil {
+		r.hookEnd(nil)
 	}
-	return binary.Write(r.w, binary.BigEndian, f)
+	return r.end(nil)
 }
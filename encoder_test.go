@@ -0,0 +1,158 @@
+package rencode
+
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// failingValue starts a list of unknown length and fails after its first element
+type failingValue struct{}
+
+func (failingValue) EncodeRencode(e *Encoder) error {
+	err := e.EncodeListStart(-1)
+	if err != nil {
+		return err
+	}
+	err = e.EncodeInt(1000)
+	if err != nil {
+		return err
+	}
+	return errors.New("failing value")
+}
+
+func TestEncoderAutoFlush(t *testing.T) {
+	var w chunkedWriter
+	e := NewEncoder(&w)
+
+	var l List
+	for i := 0; i < 100; i++ {
+		l.Add(int32(i))
+	}
+	err := e.Encode(l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = e.EncodeInt(300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(w.chunks) != 2 {
+		t.Fatalf("expected one write per value but %d found", len(w.chunks))
+	}
+
+	// a list started manually is written once terminated
+	err = e.EncodeListStart(2)
+	if err == nil {
+		err = e.EncodeInt8(1)
+	}
+	if err == nil {
+		err = e.EncodeString("two")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(w.chunks) != 2 || e.Buffered() != 6 {
+		t.Fatalf("unexpected writes %v with %d bytes buffered", w.chunks, e.Buffered())
+	}
+	err = e.EncodeListEnd(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(w.chunks) != 3 || !bytes.Equal(w.chunks[2], []byte{LIST_FIXED_START + 2, 1, STR_FIXED_START + 3, 't', 'w', 'o'}) {
+		t.Fatalf("unexpected writes %v", w.chunks)
+	}
+}
+
+func TestEncoderFlush(t *testing.T) {
+	var b bytes.Buffer
+	e := NewEncoder(&b)
+	e.SetAutoFlush(false)
+
+	for _, v := range []interface{}{int8(1), "two", []int{3}} {
+		err := e.Encode(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if b.Len() != 0 {
+		t.Fatalf("%d bytes written before Flush", b.Len())
+	}
+	expected := e.Buffered()
+
+	// failed values leave the buffer as it was
+	for _, v := range []interface{}{[]interface{}{1, make(chan int)}, failingValue{}, []interface{}{"x", failingValue{}}} {
+		err := e.Encode(v)
+		if err == nil {
+			t.Fatalf("expected an error encoding %v", v)
+		}
+		if e.Buffered() != expected {
+			t.Fatalf("%d bytes buffered after a failed value, expected %d", e.Buffered(), expected)
+		}
+	}
+
+	err := e.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Buffered() != 0 || b.Len() != expected {
+		t.Fatalf("unexpected %d bytes written, %d buffered", b.Len(), e.Buffered())
+	}
+	d := NewDecoder(&b)
+	for i := 0; i < 3; i++ {
+		_, err = d.DecodeNext()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err = d.DecodeNext(); err != io.EOF {
+		t.Fatalf("trailing data after flushed values (%v)", err)
+	}
+}
+
+// errorWriter fails after accepting n bytes
+type errorWriter struct {
+	n int
+}
+
+func (w *errorWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		n := w.n
+		w.n = 0
+		return n, errors.New("write failed")
+	}
+	w.n -= len(p)
+	return len(p), nil
+}
+
+func TestEncoderWriteError(t *testing.T) {
+	e := NewEncoder(&errorWriter{n: 2})
+	err := e.Encode("abc")
+	if err == nil {
+		t.Fatal("expected a write error")
+	}
+	// the part that was not written is kept
+	if e.Buffered() != 2 {
+		t.Fatalf("expected 2 bytes buffered but %d found", e.Buffered())
+	}
+}
@@ -0,0 +1,343 @@
+package rencode
+
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// reprMode selects how values are rendered by Format
+type reprMode int
+
+const (
+	// reprPython renders values as Python's repr(), as decoded by the reference implementation
+	reprPython reprMode = iota
+	// reprWidths is reprPython with integers and floats annotated with the name of their typecode
+	reprWidths
+	// reprGo renders values as Go expressions
+	reprGo
+)
+
+// NewList returns a list of the specified values
+func NewList(values ...interface{}) List {
+	return List{values: values}
+}
+
+// NewDictionary returns a dictionary of the specified keys, each followed by its value;
+// it is meant for literal values and panics if a key is repeated or has no value
+func NewDictionary(keysAndValues ...interface{}) Dictionary {
+	if len(keysAndValues)%2 != 0 {
+		panic("rencode: NewDictionary requires pairs of keys and values")
+	}
+	var d Dictionary
+	for i := 0; i < len(keysAndValues); i += 2 {
+		if err := d.Add(keysAndValues[i], keysAndValues[i+1]); err != nil {
+			panic(fmt.Sprintf("rencode: NewDictionary key %v: %v", keysAndValues[i], err))
+		}
+	}
+	return d
+}
+
+// String returns the list as rendered by Python's repr(), e.g. [1, b'two']
+func (l List) String() string {
+	var b strings.Builder
+	writeRepr(&b, l, reprPython)
+	return b.String()
+}
+
+// Format implements fmt.Formatter: %v and %s render the list as String does,
+// %+v also shows the typecode of integers and floats, e.g. CHR_INT2(300), and %#v renders
+// a Go expression that evaluates to the list. The Go expression is valid for the values returned
+// by the decoder and for the types supported by Encode; values of other types are rendered with
+// their own %#v format, which is not always valid Go syntax.
+func (l List) Format(f fmt.State, verb rune) {
+	format(f, verb, l)
+}
+
+// String returns the dictionary as rendered by Python's repr(), e.g. {b'name': 5}
+func (d Dictionary) String() string {
+	var b strings.Builder
+	writeRepr(&b, d, reprPython)
+	return b.String()
+}
+
+// Format implements fmt.Formatter, see List.Format
+func (d Dictionary) Format(f fmt.State, verb rune) {
+	format(f, verb, d)
+}
+
+func format(f fmt.State, verb rune, v interface{}) {
+	mode := reprPython
+	switch {
+	case verb == 'v' && f.Flag('#'):
+		mode = reprGo
+	case verb == 'v' && f.Flag('+'):
+		mode = reprWidths
+	case verb != 'v' && verb != 's':
+		fmt.Fprintf(f, "%%!%c(%T=", verb, v)
+		defer f.Write([]byte(")"))
+	}
+	var b strings.Builder
+	writeRepr(&b, v, mode)
+	f.Write([]byte(b.String()))
+}
+
+func writeRepr(b *strings.Builder, v interface{}, mode reprMode) {
+	switch x := normalize(v).(type) {
+	case nil:
+		if mode == reprGo {
+			b.WriteString("nil")
+		} else {
+			b.WriteString("None")
+		}
+	case bool:
+		switch {
+		case mode == reprGo:
+			b.WriteString(strconv.FormatBool(x))
+		case x:
+			b.WriteString("True")
+		default:
+			b.WriteString("False")
+		}
+	case List:
+		if mode == reprGo {
+			b.WriteString("rencode.NewList(")
+		} else {
+			b.WriteByte('[')
+		}
+		for i, e := range x.values {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			writeRepr(b, e, mode)
+		}
+		if mode == reprGo {
+			b.WriteByte(')')
+		} else {
+			b.WriteByte(']')
+		}
+	case Dictionary:
+		if mode == reprGo {
+			b.WriteString("rencode.NewDictionary(")
+		} else {
+			b.WriteByte('{')
+		}
+		for i, k := range x.keys {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			writeRepr(b, k, mode)
+			if mode == reprGo {
+				b.WriteString(", ")
+			} else {
+				b.WriteString(": ")
+			}
+			writeRepr(b, x.values[i], mode)
+		}
+		if mode == reprGo {
+			b.WriteByte(')')
+		} else {
+			b.WriteByte('}')
+		}
+	case []byte:
+		if mode == reprGo {
+			b.WriteString("[]byte(" + strconv.Quote(string(x)) + ")")
+		} else {
+			writePythonString(b, string(x), true)
+		}
+	case string:
+		if mode == reprGo {
+			b.WriteString(strconv.Quote(x))
+		} else {
+			writePythonString(b, x, false)
+		}
+	case float32:
+		writeFloat(b, float64(x), 32, mode)
+	case float64:
+		writeFloat(b, x, 64, mode)
+	case *big.Int:
+		switch {
+		case mode != reprGo:
+			writeNumber(b, numberTypeCode(x), x.String(), mode)
+		case x.IsInt64():
+			b.WriteString("big.NewInt(" + x.String() + ")")
+		default:
+			b.WriteString(`func() *big.Int { x, _ := new(big.Int).SetString("` + x.String() + `", 10); return x }()`)
+		}
+	case int8, int16, int32, int64, int, uint8, uint16, uint32, uint64, uint:
+		typ := fmt.Sprintf("%T", x)
+		if typ == "int" && mode == reprGo {
+			// untyped integer constants are of type int
+			b.WriteString(fmt.Sprint(x))
+			return
+		}
+		if mode == reprWidths {
+			typ = numberTypeCode(x)
+		}
+		writeNumber(b, typ, fmt.Sprint(x), mode)
+	default:
+		if mode == reprGo {
+			fmt.Fprintf(b, "%#v", x)
+		} else {
+			fmt.Fprintf(b, "%v", x)
+		}
+	}
+}
+
+// numberTypeCode returns the name of the typecode of an integer: the typecode it was decoded from
+// for the types returned by the decoder, or the one written by Encode for the other types.
+// Small integers are reported as fixed even when decoded from a non-canonical encoding.
+func numberTypeCode(v interface{}) string {
+	switch x := v.(type) {
+	case int16:
+		return "CHR_INT2"
+	case int32:
+		return "CHR_INT4"
+	case int64:
+		return "CHR_INT8"
+	case uint64, *big.Int:
+		return "CHR_INT"
+	case uint:
+		if strconv.IntSize == 64 {
+			return "CHR_INT"
+		}
+		return intTypeCode(int64(x))
+	}
+	i, _ := intValue(v)
+	return intTypeCode(i)
+}
+
+// intTypeCode returns the name of the typecode written by EncodeInt for x
+func intTypeCode(x int64) string {
+	switch {
+	case 0 <= x && x < INT_POS_FIXED_COUNT:
+		return "INT_POS_FIXED"
+	case -INT_NEG_FIXED_COUNT <= x && x < 0:
+		return "INT_NEG_FIXED"
+	case math.MinInt8 <= x && x <= math.MaxInt8:
+		return "CHR_INT1"
+	case math.MinInt16 <= x && x <= math.MaxInt16:
+		return "CHR_INT2"
+	case math.MinInt32 <= x && x <= math.MaxInt32:
+		return "CHR_INT4"
+	}
+	return "CHR_INT8"
+}
+
+// writeNumber writes a number, annotated with its type unless rendered as in Python
+func writeNumber(b *strings.Builder, typ, s string, mode reprMode) {
+	if mode == reprPython {
+		b.WriteString(s)
+		return
+	}
+	b.WriteString(typ + "(" + s + ")")
+}
+
+func writeFloat(b *strings.Builder, f float64, bitSize int, mode reprMode) {
+	typ := "float" + strconv.Itoa(bitSize)
+	if mode != reprGo {
+		typ = "CHR_FLOAT" + strconv.Itoa(bitSize)
+		writeNumber(b, typ, pythonFloat(f, bitSize), mode)
+		return
+	}
+	switch {
+	case math.IsNaN(f):
+		writeNumber(b, typ, "math.NaN()", mode)
+	case math.IsInf(f, 0):
+		writeNumber(b, typ, "math.Inf("+strconv.Itoa(int(math.Copysign(1, f)))+")", mode)
+	default:
+		writeNumber(b, typ, strconv.FormatFloat(f, 'g', -1, bitSize), mode)
+	}
+}
+
+// pythonFloat formats a float as Python's repr(), using the shortest representation
+// of the value as a float of the specified size
+func pythonFloat(f float64, bitSize int) string {
+	switch {
+	case math.IsNaN(f):
+		return "nan"
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	}
+	s := strconv.FormatFloat(f, 'e', -1, bitSize)
+	exp, _ := strconv.Atoi(s[strings.IndexByte(s, 'e')+1:])
+	if exp < -4 || exp >= 16 {
+		return s
+	}
+	s = strconv.FormatFloat(f, 'f', -1, bitSize)
+	if !strings.Contains(s, ".") {
+		s += ".0"
+	}
+	return s
+}
+
+// writePythonString writes a string literal as Python's repr() of a bytes or str object
+func writePythonString(b *strings.Builder, s string, isBytes bool) {
+	quote := byte('\'')
+	if strings.IndexByte(s, '\'') >= 0 && strings.IndexByte(s, '"') < 0 {
+		quote = '"'
+	}
+	if isBytes {
+		b.WriteByte('b')
+	}
+	b.WriteByte(quote)
+	for i := 0; i < len(s); {
+		r, size := rune(s[i]), 1
+		if !isBytes {
+			r, size = utf8.DecodeRuneInString(s[i:])
+			if r == utf8.RuneError && size == 1 {
+				r = rune(s[i])
+			}
+		}
+		c := s[i]
+		i += size
+
+		switch {
+		case r == '\\' || r == rune(quote):
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case size == 1 && (r < 0x20 || r >= 0x7f):
+			fmt.Fprintf(b, `\x%02x`, c)
+		case size == 1 || unicode.IsPrint(r):
+			b.WriteRune(r)
+		case r < 0x100:
+			fmt.Fprintf(b, `\x%02x`, r)
+		case r < 0x10000:
+			fmt.Fprintf(b, `\u%04x`, r)
+		default:
+			fmt.Fprintf(b, `\U%08x`, r)
+		}
+	}
+	b.WriteByte(quote)
+}
@@ -0,0 +1,123 @@
+package rencode
+
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	big1, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	d := NewDictionary(
+		[]byte("name"), []byte("debian.iso"),
+		[]byte("peers"), NewList(int16(300), 1.5, float32(0.1), nil, true, "it's"),
+		int8(-3), NewDictionary(),
+		[]byte("size"), big1,
+	)
+
+	for _, test := range []struct {
+		format, expected string
+	}{
+		{"%v", `{b'name': b'debian.iso', b'peers': [300, 1.5, 0.1, None, True, "it's"], -3: {}, b'size': 123456789012345678901234567890}`},
+		{"%s", `{b'name': b'debian.iso', b'peers': [300, 1.5, 0.1, None, True, "it's"], -3: {}, b'size': 123456789012345678901234567890}`},
+		{"%+v", `{b'name': b'debian.iso', b'peers': [CHR_INT2(300), CHR_FLOAT64(1.5), CHR_FLOAT32(0.1), None, True, "it's"], INT_NEG_FIXED(-3): {}, b'size': CHR_INT(123456789012345678901234567890)}`},
+		{"%#v", `rencode.NewDictionary([]byte("name"), []byte("debian.iso"), []byte("peers"), rencode.NewList(int16(300), float64(1.5), float32(0.1), nil, true, "it's"), int8(-3), rencode.NewDictionary(), []byte("size"), func() *big.Int { x, _ := new(big.Int).SetString("123456789012345678901234567890", 10); return x }())`},
+		{"%d", `%!d(rencode.Dictionary={b'name': b'debian.iso', b'peers': [300, 1.5, 0.1, None, True, "it's"], -3: {}, b'size': 123456789012345678901234567890})`},
+	} {
+		if found := fmt.Sprintf(test.format, d); found != test.expected {
+			t.Errorf("%s: expected\n%s\nbut found\n%s", test.format, test.expected, found)
+		}
+		if found := fmt.Sprintf(test.format, &d); found != test.expected {
+			t.Errorf("%s: unexpected formatting of a pointer\n%s", test.format, found)
+		}
+	}
+	if d.String() != fmt.Sprint(d) {
+		t.Errorf("String and %%v differ: %s", d.String())
+	}
+
+	l := NewList(int8(1), uint64(math.MaxUint64), 7, big.NewInt(-2), math.Inf(-1), float32(math.NaN()))
+	if found := fmt.Sprintf("%#v", l); found != `rencode.NewList(int8(1), uint64(18446744073709551615), 7, big.NewInt(-2), float64(math.Inf(-1)), float32(math.NaN()))` {
+		t.Errorf("unexpected Go syntax %s", found)
+	}
+	if found := l.String(); found != `[1, 18446744073709551615, 7, -2, -inf, nan]` {
+		t.Errorf("unexpected repr %s", found)
+	}
+
+	// integers show the typecode they are decoded from, or the one written by Encode for other types
+	l = NewList(int8(100), int32(-5), int64(1<<40), 70000, uint8(200), uint64(1))
+	if found := fmt.Sprintf("%+v", l); found != `[CHR_INT1(100), CHR_INT4(-5), CHR_INT8(1099511627776), CHR_INT4(70000), CHR_INT2(200), CHR_INT(1)]` {
+		t.Errorf("unexpected typecodes %s", found)
+	}
+}
+
+func TestFormatStrings(t *testing.T) {
+	for _, test := range []struct {
+		value    interface{}
+		expected string
+	}{
+		{[]byte{0, 'a', 0xff, '\n', '\\', '\t'}, `b'\x00a\xff\n\\\t'`},
+		{[]byte(`'"`), `b'\'"'`},
+		{[]byte(`it's`), `b"it's"`},
+		{"héllo", `'héllo'`},
+		{"\u0080\u200b\U0001f600", `'\x80\u200b😀'`},
+		{"\U000e0001", `'\U000e0001'`},
+	} {
+		if found := NewList(test.value).String(); found != "["+test.expected+"]" {
+			t.Errorf("expected [%s] but %s found", test.expected, found)
+		}
+	}
+}
+
+func TestPythonFloat(t *testing.T) {
+	for _, test := range []struct {
+		f        float64
+		expected string
+	}{
+		{1, "1.0"},
+		{-0.5, "-0.5"},
+		{123456789, "123456789.0"},
+		{1e15, "1000000000000000.0"},
+		{1e16, "1e+16"},
+		{1.5e300, "1.5e+300"},
+		{0.0001, "0.0001"},
+		{0.00001, "1e-05"},
+		{math.Inf(1), "inf"},
+	} {
+		if found := pythonFloat(test.f, 64); found != test.expected {
+			t.Errorf("expected %s but %s found", test.expected, found)
+		}
+	}
+}
+
+func TestNewDictionaryPanics(t *testing.T) {
+	for _, args := range [][]interface{}{{"a"}, {"a", 1, []byte("a"), 2}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected a panic for %v", args)
+				}
+			}()
+			NewDictionary(args...)
+		}()
+	}
+}
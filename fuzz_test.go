@@ -0,0 +1,319 @@
+package rencode
+
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// fuzzSeeds returns the encoding of the values used by the tests of rencode_test.go,
+// followed by malformed inputs exercising the error paths of the decoder
+func fuzzSeeds(t testing.TB) [][]byte {
+	var big1 big.Int
+	big1.SetUint64(^uint64(0))
+	big1.Mul(&big1, big.NewInt(32))
+
+	var fixedList, list List
+	for _, v := range []interface{}{int8(1), "two", 3.5} {
+		fixedList.Add(v)
+	}
+	for i := 0; i < 70; i++ {
+		list.Add(int16(i * 100))
+	}
+	var fixedDict, dict Dictionary
+	fixedDict.Add("a", int8(1))
+	fixedDict.Add("b", list)
+	for i := 0; i < 30; i++ {
+		dict.Add(int32(i), fixedList)
+	}
+
+	values := []interface{}{
+		int8(10), int8(-10), int8(100), int8(-100), int8(-128), []byte{1},
+		int16(27123), int16(-27123), int32(7483648), int32(-7483648),
+		int64(8223372036854775807), int64(-8223372036854775808), big1, new(big.Int).Neg(&big1),
+		float32(1234.56), float64(1234.56), "foobarbaz", strings.Repeat("f", 255), "fööbar",
+		nil, true, false, []byte{0, 1, 2, 255}, "", fixedList, list, fixedDict, dict,
+	}
+
+	var seeds [][]byte
+	for _, v := range values {
+		data, err := Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		seeds = append(seeds, data)
+	}
+
+	return append(seeds,
+		[]byte(strings.Repeat("9", 100)),
+		[]byte("99999999999:abc"),
+		[]byte{CHR_INT, 'x', 'y', CHR_TERM},
+		[]byte{CHR_INT, '1', '2'},
+		[]byte{CHR_LIST, CHR_LIST, CHR_DICT, 1},
+		[]byte{DICT_FIXED_START + 2, STR_FIXED_START, 1, STR_FIXED_START, 2},
+		[]byte{LIST_FIXED_START + 3, CHR_TERM},
+		[]byte{CHR_INT2, 1},
+		[]byte{58, 45, 255},
+	)
+}
+
+// checkDecodeError verifies that a failed decoding did not report a truncated value as a clean end of stream
+func checkDecodeError(t *testing.T, data []byte, err error) {
+	if err == io.EOF && len(data) > 0 {
+		t.Fatalf("io.EOF returned for non-empty input %v", data)
+	}
+}
+
+func FuzzDecodeNext(f *testing.F) {
+	for _, seed := range fuzzSeeds(f) {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		d := NewDecoder(bytes.NewReader(data))
+		d.SetMaxDepth(100)
+		_, err := d.DecodeNext()
+		if err != nil {
+			checkDecodeError(t, data, err)
+			return
+		}
+
+		// the same input can be skipped and tokenized
+		d = NewDecoder(bytes.NewReader(data))
+		err = d.Skip()
+		if err != nil {
+			t.Fatalf("value decoded but not skipped: %v", err)
+		}
+		d = NewDecoder(bytes.NewReader(data))
+		for depth := 0; ; {
+			tok, err := d.Token()
+			if err != nil {
+				t.Fatalf("value decoded but not tokenized: %v", err)
+			}
+			switch tok.(type) {
+			case ListStart, DictStart:
+				depth++
+			case End:
+				depth--
+			}
+			if depth == 0 {
+				break
+			}
+		}
+	})
+}
+
+func FuzzDecode(f *testing.F) {
+	for _, seed := range fuzzSeeds(f) {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, target := range []interface{}{
+			new(torrentStatus),
+			new(interface{}),
+			new(map[interface{}]interface{}),
+			new([]int),
+			new([3]string),
+			new(TypedDict[string, []float32]),
+			new(selfEncoded),
+		} {
+			d := NewDecoder(bytes.NewReader(data))
+			d.SetMaxDepth(100)
+			err := d.Decode(target)
+			if err != nil {
+				checkDecodeError(t, data, err)
+			}
+		}
+	})
+}
+
+func FuzzRoundTrip(f *testing.F) {
+	for _, seed := range fuzzSeeds(f) {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		d := NewDecoder(bytes.NewReader(data))
+		d.SetMaxDepth(100)
+		v, err := d.DecodeNext()
+		if err != nil {
+			return
+		}
+
+		// decoded values are encoded in a canonical form that is stable across round trips
+		encoded, err := Marshal(v)
+		if err != nil {
+			t.Fatalf("cannot encode decoded value %v: %v", v, err)
+		}
+		d = NewDecoder(bytes.NewReader(encoded))
+		d.SetMaxDepth(100)
+		v2, err := d.DecodeNext()
+		if err != nil {
+			t.Fatalf("cannot decode %v: %v", encoded, err)
+		}
+		reencoded, err := Marshal(v2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(encoded, reencoded) {
+			t.Fatalf("encoding %v changed to %v after a round trip", encoded, reencoded)
+		}
+	})
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	for _, data := range [][]byte{
+		[]byte(strings.Repeat("9", 100)),
+		[]byte("99999999999:abc"),
+		[]byte("1x:a"),
+		[]byte{CHR_INT, 'x', 'y', CHR_TERM},
+		[]byte{CHR_INT, '1', '2'},
+		[]byte{CHR_TERM},
+		[]byte{58},
+		[]byte{LIST_FIXED_START + 3, CHR_TERM},
+		[]byte{LIST_FIXED_START + 2, 1},
+		[]byte{CHR_DICT, 1},
+		[]byte{CHR_INT8, 1, 2},
+		[]byte{STR_FIXED_START + 5, 'a'},
+	} {
+		_, err := NewDecoder(bytes.NewReader(data)).DecodeNext()
+		if err == nil || err == io.EOF {
+			t.Errorf("expected an error decoding %q, got %v", data, err)
+		}
+		err = NewDecoder(bytes.NewReader(data)).Skip()
+		if err == nil || err == io.EOF {
+			t.Errorf("expected an error skipping %q, got %v", data, err)
+		}
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	deep := bytes.Repeat([]byte{LIST_FIXED_START + 1}, 1000)
+	deep = append(deep, 0)
+
+	d := NewDecoder(bytes.NewReader(deep))
+	_, err := d.DecodeNext()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d = NewDecoder(bytes.NewReader(deep))
+	d.SetMaxDepth(999)
+	_, err = d.DecodeNext()
+	if err == nil || !strings.Contains(err.Error(), "depth") {
+		t.Fatalf("expected a depth error, got %v", err)
+	}
+	d = NewDecoder(bytes.NewReader(deep))
+	d.SetMaxDepth(999)
+	err = d.Skip()
+	if err == nil {
+		t.Fatal("expected a depth error skipping")
+	}
+	d = NewDecoder(bytes.NewReader(deep))
+	d.SetMaxDepth(999)
+	var v interface{}
+	err = d.Decode(&v)
+	if err == nil {
+		t.Fatal("expected a depth error decoding")
+	}
+
+	// far deeper than the default limit
+	open := bytes.Repeat([]byte{CHR_LIST}, DefaultMaxDepth+1)
+	_, err = NewDecoder(bytes.NewReader(open)).DecodeNext()
+	if err == nil || !strings.Contains(err.Error(), "depth") {
+		t.Fatalf("expected a depth error, got %v", err)
+	}
+}
+
+func TestDecodeLongStringChunked(t *testing.T) {
+	// a huge announced length with little data must fail without allocating the announced size
+	data := append([]byte("900000000000:"), make([]byte, 100)...)
+	allocs := testing.AllocsPerRun(1, func() {
+		_, err := NewDecoder(bytes.NewReader(data)).DecodeNext()
+		if err != io.ErrUnexpectedEOF {
+			t.Fatalf("unexpected error %v", err)
+		}
+	})
+	if allocs > 20 {
+		t.Fatalf("too many allocations: %v", allocs)
+	}
+
+	value := strings.Repeat("x", 3*readChunkSize+5)
+	encoded, err := Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found string
+	err = Unmarshal(encoded, &found)
+	if err != nil || found != value {
+		t.Fatalf("long string not decoded: %v", err)
+	}
+}
+
+func TestFuzzRegressions(t *testing.T) {
+	// the smallest int8 has no fixed representation
+	data, err := Marshal(int8(-128))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var i int8
+	err = Unmarshal(data, &i)
+	if err != nil || i != -128 {
+		t.Fatalf("unexpected value %v (%v)", i, err)
+	}
+
+	// keys differing only by integer width are duplicates
+	_, err = NewDecoder(bytes.NewReader([]byte{DICT_FIXED_START + 2, 1, 0, CHR_INT2, 0, 1, 0})).DecodeNext()
+	if err != ErrKeyAlreadyExists {
+		t.Fatalf("expected ErrKeyAlreadyExists but %v found", err)
+	}
+
+	// a truncated container is not a clean end of stream
+	var v interface{}
+	err = NewDecoder(bytes.NewReader([]byte{DICT_FIXED_START + 1})).Decode(&v)
+	if err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected io.ErrUnexpectedEOF but %v found", err)
+	}
+}
+
+func FuzzEncodeUnsigned(f *testing.F) {
+	for _, seed := range []uint64{0, math.MaxInt8, math.MaxUint8, math.MaxInt16, math.MaxUint16, math.MaxInt32, math.MaxUint32, math.MaxUint64} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, x uint64) {
+		for _, v := range []interface{}{uint8(x), uint16(x), uint32(x), x, uint(x)} {
+			data, err := Marshal(v)
+			if err != nil {
+				t.Fatalf("cannot encode %T(%v): %v", v, v, err)
+			}
+			found, err := NewDecoder(bytes.NewReader(data)).DecodeNext()
+			if err != nil {
+				t.Fatalf("cannot decode %T(%v): %v", v, v, err)
+			}
+			if fmt.Sprint(found) != fmt.Sprint(v) {
+				t.Fatalf("expected %v but %v found", v, found)
+			}
+		}
+	})
+}
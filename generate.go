@@ -47,13 +47,20 @@ const top = `package rencode
 // Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
 
 import (
+	"encoding/json"
 	"fmt"
 	"math"
 	"math/big"
+	"net"
+	"net/url"
+	"reflect"
+	"time"
 )
 
 // Encode is the generic encoder method that will encode any of the following supported types:
-// * big.Int
+// * Marshaler
+// * big.Int, *big.Int
+// * time.Time, time.Duration, net.IP, *url.URL, json.Number (see EncodeTime, EncodeDuration and EncodeNumber)
 // * List
 // * Dictionary
 // * bool
@@ -61,68 +68,66 @@ import (
 // * []byte, string (all strings are stored as byte slices anyway)
 // * int8, int16, int32, int64, int
 // * uint8, uint16, uint32, uint64, uint
+// Values of any other type are encoded through reflection, see Marshal.
+// Nothing is written if encoding fails.
 func (r *Encoder) Encode(data interface{}) error {
+	r.begin()
+	return r.end(r.encode(data))
+}
+
+func (r *Encoder) encode(data interface{}) error {
 	if data == nil {
 		return r.EncodeNone()
 	}
 	switch data.(type) {
+	case Marshaler:
+		if v := reflect.ValueOf(data); v.Kind() == reflect.Ptr && v.IsNil() {
+			return r.EncodeNone()
+		}
+		return data.(Marshaler).EncodeRencode(r)
+	case *big.Int:
+		x := data.(*big.Int)
+		if x == nil {
+			return r.EncodeNone()
+		}
+		return r.EncodeBigInt(x)
 	case big.Int:
 		x := data.(big.Int)
-		s := x.String()
-		if len(s) > MAX_INT_LENGTH {
-			return fmt.Errorf("Number is longer than %d characters", MAX_INT_LENGTH)
+		return r.EncodeBigInt(&x)
+	case time.Time:
+		return r.EncodeTime(data.(time.Time))
+	case time.Duration:
+		return r.EncodeDuration(data.(time.Duration))
+	case net.IP:
+		x := data.(net.IP)
+		if x == nil {
+			return r.EncodeNone()
 		}
-		return r.EncodeBigNumber(s)
+		return r.EncodeString(x.String())
+	case *url.URL:
+		x := data.(*url.URL)
+		if x == nil {
+			return r.EncodeNone()
+		}
+		return r.EncodeString(x.String())
+	case json.Number:
+		return r.EncodeNumber(data.(json.Number))
 	case List:
 		x := data.(List)
-		if x.Length() < LIST_FIXED_COUNT {
-			_, err := r.w.Write([]byte{byte(LIST_FIXED_START + x.Length())})
-			if err != nil {
-				return err
-			}
-			for _, v := range x.Values() {
-				err = r.Encode(v)
-				if err != nil {
-					return err
-				}
-			}
-			return nil
-		}
-		_, err := r.w.Write([]byte{byte(CHR_LIST)})
+		err := r.EncodeListStart(x.Length())
 		if err != nil {
 			return err
 		}
-
 		for _, v := range x.Values() {
 			err = r.Encode(v)
 			if err != nil {
 				return err
 			}
 		}
-
-		_, err = r.w.Write([]byte{byte(CHR_TERM)})
-		return err
+		return r.EncodeListEnd(x.Length())
 	case Dictionary:
 		x := data.(Dictionary)
-		if x.Length() < DICT_FIXED_COUNT {
-			_, err := r.w.Write([]byte{byte(DICT_FIXED_START + x.Length())})
-			if err != nil {
-				return err
-			}
-			keys := x.Keys()
-			for i, v := range x.Values() {
-				err = r.Encode(keys[i])
-				if err != nil {
-					return err
-				}
-				err = r.Encode(v)
-				if err != nil {
-					return err
-				}
-			}
-			return nil
-		}
-		_, err := r.w.Write([]byte{byte(CHR_DICT)})
+		err := r.EncodeDictStart(x.Length())
 		if err != nil {
 			return err
 		}
@@ -137,9 +142,7 @@ func (r *Encoder) Encode(data interface{}) error {
 				return err
 			}
 		}
-
-		_, err = r.w.Write([]byte{byte(CHR_TERM)})
-		return err
+		return r.EncodeDictEnd(x.Length())
 	case bool:
 		return r.EncodeBool(data.(bool))
 	case float32:
@@ -216,23 +219,31 @@ func unsignedGenerate(t string, bitsize int) {
 			return r.EncodeInt8(int8(x))
 		}`)
 
-	if bitsize >= 16 {
-		fmt.Println(`		if x <= math.MaxInt16 {
-		return r.EncodeInt16(int16(x))
-		}`)
+	// values above the largest signed range checked use the next wider integer
+	if bitsize == 8 {
+		fmt.Println(`		return r.EncodeInt16(int16(x))`)
+		return
 	}
 
-	if bitsize >= 32 {
-		fmt.Println(`		if x <= math.MaxInt32 {
-		return r.EncodeInt32(int32(x))
+	fmt.Println(`		if x <= math.MaxInt16 {
+			return r.EncodeInt16(int16(x))
 		}`)
+
+	if bitsize == 16 {
+		fmt.Println(`		return r.EncodeInt32(int32(x))`)
 		return
 	}
 
-	if bitsize == 63 {
+	fmt.Println(`		if x <= math.MaxInt32 {
+			return r.EncodeInt32(int32(x))
+		}`)
+
+	if bitsize == 32 {
 		fmt.Println(`		return r.EncodeInt64(int64(x))`)
 		return
 	}
+
+	panic("unsigned: using bitsize larger than 32")
 }
 
 func main() {
@@ -257,16 +268,11 @@ func main() {
 		caseStr += ", uint"
 	}
 	fmt.Printf("\tcase %s:\n", caseStr)
-	fmt.Println(`		s := fmt.Sprintf("%d", data)
-		if len(s) > MAX_INT_LENGTH {
-			return fmt.Errorf("Number is longer than %d characters", MAX_INT_LENGTH)
-		}
-		return r.EncodeBigNumber(s)`)
+	fmt.Println(`		return r.EncodeBigNumber(fmt.Sprintf("%d", data))`)
 
 	// tail default case
 	fmt.Println(`	default:
-		return fmt.Errorf("could not encode data of type %T", data)
+		return r.encodeReflect(reflect.ValueOf(data))
 	}
-	panic("unexpected fallthrough")
 }`)
 }
@@ -0,0 +1,3 @@
+module github.com/gdm85/go-rencode
+
+go 1.20
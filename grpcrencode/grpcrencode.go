@@ -0,0 +1,61 @@
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+/*
+Package grpcrencode provides a gRPC codec exchanging rencode payloads.
+
+Importing the package registers the codec with the name "rencode"; clients select it with
+the grpc.CallContentSubtype("rencode") call option, and servers use it for the requests
+with a matching content subtype. Messages are encoded with rencode.Marshal and decoded
+with rencode.Unmarshal, so they can be plain Go structs.
+
+The package is a separate module pinning its gRPC dependency, so that the rencode package
+itself has no dependencies.
+*/
+package grpcrencode
+
+import (
+	"github.com/gdm85/go-rencode"
+	"google.golang.org/grpc/encoding"
+)
+
+// Name is the name under which the codec is registered, also used as gRPC content subtype
+const Name = "rencode"
+
+func init() {
+	encoding.RegisterCodec(Codec{})
+}
+
+// Codec is a gRPC codec marshaling messages as rencode values
+type Codec struct{}
+
+// Marshal returns the rencode encoding of v
+func (Codec) Marshal(v interface{}) ([]byte, error) {
+	return rencode.Marshal(v)
+}
+
+// Unmarshal decodes a rencode value into the value pointed to by v
+func (Codec) Unmarshal(data []byte, v interface{}) error {
+	return rencode.Unmarshal(data, v)
+}
+
+// Name returns the name of the codec
+func (Codec) Name() string {
+	return Name
+}
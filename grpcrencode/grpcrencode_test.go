@@ -0,0 +1,139 @@
+package grpcrencode
+
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type peerRequest struct {
+	Torrent string `rencode:"torrent"`
+	Limit   int    `rencode:"limit"`
+}
+
+type peerReply struct {
+	Peers    []string `rencode:"peers"`
+	Progress float64  `rencode:"progress"`
+}
+
+type peerServer interface {
+	Peers(ctx context.Context, req *peerRequest) (*peerReply, error)
+}
+
+type peers struct{}
+
+func (peers) Peers(ctx context.Context, req *peerRequest) (*peerReply, error) {
+	if req.Limit < 0 {
+		return nil, status.Error(codes.InvalidArgument, "negative limit")
+	}
+	reply := &peerReply{Progress: 0.5}
+	for i := 0; i < req.Limit; i++ {
+		reply.Peers = append(reply.Peers, req.Torrent)
+	}
+	return reply, nil
+}
+
+// serviceDesc is written by hand as there are no protocol buffers definitions
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: "rencode.test.Torrents",
+	HandlerType: (*peerServer)(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "Peers",
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+			req := new(peerRequest)
+			if err := dec(req); err != nil {
+				return nil, err
+			}
+			return srv.(peerServer).Peers(ctx, req)
+		},
+	}},
+}
+
+func dialBufconn(t *testing.T) *grpc.ClientConn {
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	server.RegisterService(&serviceDesc, peers{})
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.CallContentSubtype(Name)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestCodec(t *testing.T) {
+	conn := dialBufconn(t)
+	ctx := context.Background()
+
+	var reply peerReply
+	err := conn.Invoke(ctx, "/rencode.test.Torrents/Peers", &peerRequest{Torrent: "debian.iso", Limit: 3}, &reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reply.Peers) != 3 || reply.Peers[0] != "debian.iso" || reply.Progress != 0.5 {
+		t.Fatalf("unexpected reply %+v", reply)
+	}
+
+	err = conn.Invoke(ctx, "/rencode.test.Torrents/Peers", &peerRequest{Limit: -1}, &reply)
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// encoding errors are reported to the caller
+	err = conn.Invoke(ctx, "/rencode.test.Torrents/Peers", make(chan int), &reply)
+	if err == nil {
+		t.Fatal("expected an error encoding an unsupported type")
+	}
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	var c Codec
+	if c.Name() != "rencode" {
+		t.Fatalf("unexpected name %q", c.Name())
+	}
+	data, err := c.Marshal(&peerRequest{Torrent: "x", Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var req peerRequest
+	err = c.Unmarshal(data, &req)
+	if err != nil || req.Torrent != "x" || req.Limit != 1 {
+		t.Fatalf("unexpected request %+v (%v)", req, err)
+	}
+	if err = c.Unmarshal(data[:len(data)-1], &req); err == nil {
+		t.Fatalf("expected a decoding error, got %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package rencode
+
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+// ValueInfo describes a value encoded or decoded, as reported to a Hook
+type ValueInfo struct {
+	Kind     Kind
+	TypeCode byte
+	// Depth is the number of lists and dictionaries containing the value, 0 for top-level values
+	Depth int
+	// Offset is the position of the value in the stream, counted from the creation or last reset
+	// of the encoder or decoder
+	Offset int64
+	// Size is the number of bytes of the value, elements of lists and dictionaries included;
+	// it is only known when the value ends
+	Size int64
+}
+
+// Hook is notified of the values processed by an Encoder or Decoder, see SetHook.
+// ValueStart and ValueEnd calls are nested like the values themselves; for scalars, ValueEnd
+// immediately follows ValueStart. err is the error that interrupted the value, if any.
+type Hook interface {
+	ValueStart(info ValueInfo)
+	ValueEnd(info ValueInfo, err error)
+}
+
+// SetHook makes the encoder report the values it writes to h, or stops reporting when h is nil.
+// It should be called between top-level values.
+func (r *Encoder) SetHook(h Hook) {
+	r.hook = h
+	r.hookFrames = r.hookFrames[:0]
+}
+
+// SetHook makes the decoder report the values it reads to h, or stops reporting when h is nil.
+// It should be called between top-level values. Values consumed by Skip, and lists and
+// dictionaries traversed by Select and Validate, are not reported.
+func (r *Decoder) SetHook(h Hook) {
+	r.hook = h
+	r.hookFrames = r.hookFrames[:0]
+}
+
+// offset returns the position in the stream of the next byte written
+func (r *Encoder) offset() int64 {
+	return r.written + int64(len(r.buf))
+}
+
+// hookScalar reports a scalar value written at position start of the buffer
+func (r *Encoder) hookScalar(start int) {
+	info := ValueInfo{
+		Kind:     kindOf(r.buf[start]),
+		TypeCode: r.buf[start],
+		Depth:    len(r.hookFrames),
+		Offset:   r.written + int64(start),
+		Size:     int64(len(r.buf) - start),
+	}
+	r.hook.ValueStart(info)
+	r.hook.ValueEnd(info, nil)
+}
+
+// hookStart reports the start of a list or dictionary about to be written
+func (r *Encoder) hookStart(typeCode byte) {
+	info := ValueInfo{Kind: kindOf(typeCode), TypeCode: typeCode, Depth: len(r.hookFrames), Offset: r.offset()}
+	r.hookFrames = append(r.hookFrames, info)
+	r.hook.ValueStart(info)
+}
+
+// hookEnd reports the end of the innermost list or dictionary, or of all the open ones if err is not nil
+func (r *Encoder) hookEnd(err error) {
+	for n := len(r.hookFrames); n > 0; n-- {
+		info := r.hookFrames[n-1]
+		r.hookFrames = r.hookFrames[:n-1]
+		info.Size = r.offset() - info.Offset
+		r.hook.ValueEnd(info, err)
+		if err == nil {
+			return
+		}
+	}
+}
+
+// decodeHooked decodes the value identified by typeCode, reporting it
+func (r *Decoder) decodeHooked(typeCode byte) (interface{}, error) {
+	info := ValueInfo{Kind: kindOf(typeCode), TypeCode: typeCode, Depth: r.depth + len(r.containers), Offset: r.InputOffset() - 1}
+	r.hook.ValueStart(info)
+	v, err := r.decodeTypeCode(typeCode)
+	info.Size = r.InputOffset() - info.Offset
+	r.hook.ValueEnd(info, err)
+	return v, err
+}
+
+// hookStart reports the start of a list or dictionary returned by Token
+func (r *Decoder) hookStart(typeCode byte) {
+	info := ValueInfo{Kind: kindOf(typeCode), TypeCode: typeCode, Depth: r.depth + len(r.containers), Offset: r.InputOffset() - 1}
+	r.hookFrames = append(r.hookFrames, info)
+	r.hook.ValueStart(info)
+}
+
+// hookEnd reports the end of a list or dictionary returned by Token
+func (r *Decoder) hookEnd() {
+	n := len(r.hookFrames)
+	if n == 0 {
+		return
+	}
+	info := r.hookFrames[n-1]
+	r.hookFrames = r.hookFrames[:n-1]
+	info.Size = r.InputOffset() - info.Offset
+	r.hook.ValueEnd(info, nil)
+}
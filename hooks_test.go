@@ -0,0 +1,224 @@
+package rencode
+
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// recordingHook records the values reported to it
+type recordingHook struct {
+	events []string
+}
+
+func (h *recordingHook) ValueStart(info ValueInfo) {
+	h.events = append(h.events, fmt.Sprintf("start %s %d depth %d at %d", info.Kind, info.TypeCode, info.Depth, info.Offset))
+}
+
+func (h *recordingHook) ValueEnd(info ValueInfo, err error) {
+	event := fmt.Sprintf("end %s %d depth %d at %d size %d", info.Kind, info.TypeCode, info.Depth, info.Offset, info.Size)
+	if err != nil {
+		event += " error"
+	}
+	h.events = append(h.events, event)
+}
+
+func checkEvents(t *testing.T, how string, found []string, expected []string) {
+	t.Helper()
+	if strings.Join(found, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("%s: expected events\n%s\nbut found\n%s", how, strings.Join(expected, "\n"), strings.Join(found, "\n"))
+	}
+}
+
+func TestHooks(t *testing.T) {
+	value := NewDictionary("a", NewList(1, "xy"))
+	expected := []string{
+		"start dict 103 depth 0 at 0",
+		"start string 129 depth 1 at 1",
+		"end string 129 depth 1 at 1 size 2",
+		"start list 194 depth 1 at 3",
+		"start int 1 depth 2 at 4",
+		"end int 1 depth 2 at 4 size 1",
+		"start string 130 depth 2 at 5",
+		"end string 130 depth 2 at 5 size 3",
+		"end list 194 depth 1 at 3 size 5",
+		"end dict 103 depth 0 at 0 size 8",
+		"start bool 67 depth 0 at 8",
+		"end bool 67 depth 0 at 8 size 1",
+	}
+
+	var b bytes.Buffer
+	e := NewEncoder(&b)
+	var h recordingHook
+	e.SetHook(&h)
+	for _, v := range []interface{}{value, true} {
+		err := e.Encode(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkEvents(t, "Encode", h.events, expected)
+	data := b.Bytes()
+
+	h.events = nil
+	d := NewDecoder(bytes.NewReader(data))
+	d.SetHook(&h)
+	for i := 0; i < 2; i++ {
+		_, err := d.DecodeNext()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkEvents(t, "DecodeNext", h.events, expected)
+
+	// Decode reads lists and dictionaries as tokens
+	h.events = nil
+	d = NewDecoder(bytes.NewReader(data))
+	d.SetHook(&h)
+	var m map[string][]interface{}
+	var ok bool
+	err := d.Decode(&m)
+	if err == nil {
+		err = d.Decode(&ok)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkEvents(t, "Decode", h.events, expected)
+
+	// nothing is reported once the hook is removed
+	h.events = nil
+	d = NewDecoder(bytes.NewReader(data))
+	d.SetHook(&h)
+	d.SetHook(nil)
+	_, err = d.DecodeNext()
+	if err != nil || len(h.events) != 0 {
+		t.Errorf("expected no events but %q (%v) found", h.events, err)
+	}
+}
+
+func TestHooksErrors(t *testing.T) {
+	var b bytes.Buffer
+	e := NewEncoder(&b)
+	var h recordingHook
+	e.SetHook(&h)
+	err := e.Encode(NewList(int8(1), make(chan int)))
+	if err == nil {
+		t.Fatal("expected an error encoding a channel")
+	}
+	checkEvents(t, "Encode", h.events, []string{
+		"start list 194 depth 0 at 0",
+		"start int 1 depth 1 at 1",
+		"end int 1 depth 1 at 1 size 1",
+		"end list 194 depth 0 at 0 size 2 error",
+	})
+
+	// offsets of the following values are not affected by the discarded one
+	h.events = nil
+	err = e.Encode(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkEvents(t, "Encode", h.events, []string{
+		"start none 69 depth 0 at 0",
+		"end none 69 depth 0 at 0 size 1",
+	})
+
+	h.events = nil
+	d := NewDecoder(bytes.NewReader([]byte{CHR_LIST, 1, STR_FIXED_START + 3, 'a'}))
+	d.SetHook(&h)
+	_, err = d.DecodeNext()
+	if err == nil {
+		t.Fatal("expected an error decoding a truncated list")
+	}
+	checkEvents(t, "DecodeNext", h.events, []string{
+		"start list 59 depth 0 at 0",
+		"start int 1 depth 1 at 1",
+		"end int 1 depth 1 at 1 size 1",
+		"start string 131 depth 1 at 2",
+		"end string 131 depth 1 at 2 size 2 error",
+		"end list 59 depth 0 at 0 size 4 error",
+	})
+}
+
+func TestMetrics(t *testing.T) {
+	var m Metrics
+	var b bytes.Buffer
+	e := NewEncoder(&b)
+	e.SetHook(m.Hook())
+	values := []interface{}{NewList("abc", "def", 1), NewList("ghi"), "jkl"}
+	for _, v := range values {
+		err := e.Encode(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d := NewDecoder(&b)
+	d.SetHook(m.Hook())
+	for range values {
+		_, err := d.DecodeNext()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats := m.Stats()
+	var summary []string
+	for _, s := range stats {
+		summary = append(summary, fmt.Sprintf("%d %s count %d bytes %d timed %d", s.TypeCode, s.Kind, s.Count, s.Bytes, s.Durations.Count()))
+	}
+	// each value is counted when encoded and when decoded
+	checkEvents(t, "Stats", summary, []string{
+		"131 string count 8 bytes 32 timed 2",
+		"195 list count 2 bytes 20 timed 2",
+		"193 list count 2 bytes 10 timed 2",
+		"1 int count 2 bytes 2 timed 0",
+	})
+	if q := stats[0].Sizes.Quantile(0.5); q != 7 {
+		t.Errorf("expected a median size bucket bounded by 7 but %d found", q)
+	}
+
+	m.Reset()
+	if len(m.Stats()) != 0 {
+		t.Errorf("expected no statistics after Reset but %v found", m.Stats())
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	var h Histogram
+	for _, v := range []int64{0, 1, 2, 3, 4, 1000, -1} {
+		h.add(v)
+	}
+	if h[0] != 1 || h[1] != 1 || h[2] != 2 || h[3] != 1 || h[10] != 1 || h[HistogramBuckets-1] != 1 {
+		t.Errorf("unexpected buckets %v", h)
+	}
+	if h.Count() != 7 {
+		t.Errorf("expected 7 values but %d found", h.Count())
+	}
+	for q, expected := range map[float64]int64{0: 0, 0.5: 3, 0.8: 1023} {
+		if found := h.Quantile(q); found != expected {
+			t.Errorf("expected quantile %v to be %d but %d found", q, expected, found)
+		}
+	}
+}
@@ -0,0 +1,487 @@
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+/*
+Package httprencode provides helpers for HTTP handlers and clients exchanging rencode values,
+with the application/x-rencode content type.
+
+ReadRequest decodes request bodies encoded as rencode or JSON, optionally compressed with gzip or
+zlib, enforcing limits on their size and nesting depth. WriteResponse writes a rencode response,
+while Respond negotiates the format and compression of the response with the Accept and
+Accept-Encoding headers of the request. JSON bodies are handled with package encoding/json.
+*/
+package httprencode
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gdm85/go-rencode"
+)
+
+// Content types of the supported formats
+const (
+	ContentType     = "application/x-rencode"
+	JSONContentType = "application/json"
+)
+
+// Default limits of request and response bodies
+const (
+	DefaultMaxBodySize = 10 << 20
+	DefaultMaxDepth    = 100
+)
+
+// ErrBodyTooLarge is returned when a body, once decompressed, exceeds the maximum size
+var ErrBodyTooLarge = errors.New("body too large")
+
+// Error is returned by ReadRequest and ReadResponse; Status is the HTTP status code
+// that a server should respond with
+type Error struct {
+	Status int
+	Err    error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Config holds the options of the helpers; the zero value uses the defaults
+type Config struct {
+	// MaxBodySize is the maximum size of decoded bodies after decompression, DefaultMaxBodySize if 0
+	MaxBodySize int64
+	// MaxDepth is the maximum nesting depth of the lists and dictionaries of decoded bodies,
+	// or of the arrays and objects of JSON bodies, DefaultMaxDepth if 0
+	MaxDepth int
+	// NoCompression disables the compression of the responses written by Respond
+	NoCompression bool
+}
+
+var defaultConfig Config
+
+// ReadRequest decodes the body of r into the value pointed to by v with the default configuration
+func ReadRequest(r *http.Request, v interface{}) error {
+	return defaultConfig.ReadRequest(r, v)
+}
+
+// ReadResponse decodes the body of resp into the value pointed to by v with the default configuration
+func ReadResponse(resp *http.Response, v interface{}) error {
+	return defaultConfig.ReadResponse(resp, v)
+}
+
+// WriteResponse writes v as a rencode response with the specified status code
+func WriteResponse(w http.ResponseWriter, status int, v interface{}) error {
+	return defaultConfig.WriteResponse(w, status, v)
+}
+
+// Respond writes v as a response to r with the default configuration, see Config.Respond
+func Respond(w http.ResponseWriter, r *http.Request, status int, v interface{}) error {
+	return defaultConfig.Respond(w, r, status, v)
+}
+
+// NewRequest returns a request with v encoded as rencode body, accepting rencode responses
+func NewRequest(method, url string, v interface{}) (*http.Request, error) {
+	data, err := rencode.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", ContentType)
+	req.Header.Set("Accept", ContentType)
+	return req, nil
+}
+
+// ReadRequest decodes the body of r into the value pointed to by v, according to its
+// Content-Type and Content-Encoding headers; bodies without a content type are decoded as rencode.
+// On failure the error is an *Error holding the status code to respond with.
+func (c *Config) ReadRequest(r *http.Request, v interface{}) error {
+	return c.readBody(r.Header, r.Body, v)
+}
+
+// ReadResponse decodes the body of resp into the value pointed to by v, as with ReadRequest
+func (c *Config) ReadResponse(resp *http.Response, v interface{}) error {
+	return c.readBody(resp.Header, resp.Body, v)
+}
+
+func (c *Config) readBody(header http.Header, body io.Reader, v interface{}) error {
+	maxSize := c.MaxBodySize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxBodySize
+	}
+
+	isJSON := false
+	if ct := header.Get("Content-Type"); ct != "" {
+		mediaType, _, err := mime.ParseMediaType(ct)
+		if err != nil {
+			return &Error{http.StatusUnsupportedMediaType, err}
+		}
+		switch mediaType {
+		case ContentType:
+		case JSONContentType:
+			isJSON = true
+		default:
+			return &Error{http.StatusUnsupportedMediaType, fmt.Errorf("unsupported content type %q", mediaType)}
+		}
+	}
+
+	// the size is limited both before and after decompression
+	body = &limitedReader{r: body, n: maxSize}
+	switch encoding := strings.ToLower(strings.TrimSpace(header.Get("Content-Encoding"))); encoding {
+	case "", "identity":
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			return &Error{http.StatusBadRequest, err}
+		}
+		defer zr.Close()
+		body = &limitedReader{r: zr, n: maxSize}
+	case "deflate":
+		zr, err := zlib.NewReader(body)
+		if err != nil {
+			return &Error{http.StatusBadRequest, err}
+		}
+		defer zr.Close()
+		body = &limitedReader{r: zr, n: maxSize}
+	default:
+		return &Error{http.StatusUnsupportedMediaType, fmt.Errorf("unsupported content encoding %q", encoding)}
+	}
+
+	maxDepth := c.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxDepth
+	}
+	var err error
+	if isJSON {
+		// the body is checked before decoding, as encoding/json has no depth limit of its own
+		var data []byte
+		data, err = io.ReadAll(body)
+		if err == nil {
+			err = checkJSONDepth(data, maxDepth)
+		}
+		if err == nil {
+			d := json.NewDecoder(bytes.NewReader(data))
+			err = d.Decode(v)
+			if err == nil && d.More() {
+				err = errors.New("trailing data after value")
+			}
+		}
+	} else {
+		d := rencode.NewDecoder(body)
+		d.SetMaxDepth(maxDepth)
+		err = d.Decode(v)
+		if err == nil {
+			if _, err = d.PeekKind(); err == io.EOF {
+				err = nil
+			} else if err == nil {
+				err = errors.New("trailing data after value")
+			}
+		}
+	}
+	if errors.Is(err, ErrBodyTooLarge) {
+		return &Error{http.StatusRequestEntityTooLarge, err}
+	}
+	if err != nil {
+		return &Error{http.StatusBadRequest, err}
+	}
+	return nil
+}
+
+// checkJSONDepth returns an error if the arrays and objects of data are nested deeper than max
+func checkJSONDepth(data []byte, max int) error {
+	depth := 0
+	inString := false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case inString:
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '[' || c == '{':
+			depth++
+			if depth > max {
+				return fmt.Errorf("maximum nesting depth of %d exceeded", max)
+			}
+		case c == ']' || c == '}':
+			depth--
+		}
+	}
+	return nil
+}
+
+// WriteResponse writes v as a rencode response with the specified status code.
+// The value is encoded before writing anything, so that encoding errors result in a
+// 500 Internal Server Error response.
+func (c *Config) WriteResponse(w http.ResponseWriter, status int, v interface{}) error {
+	data, err := rencode.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return err
+	}
+	return writeBody(w, status, ContentType, "", data)
+}
+
+// Respond writes v as a response to r with the specified status code, encoded as rencode or JSON
+// as preferred by the Accept header of r, rencode being used if both are accepted equally.
+// For JSON, the rencode.List and rencode.Dictionary values held by v, its slices and its maps are
+// written as arrays and objects, with their strings written as JSON strings when valid UTF-8;
+// struct fields are written by encoding/json as usual.
+// The response is compressed with gzip or zlib when accepted by the Accept-Encoding header of r,
+// unless compression is disabled. A 406 Not Acceptable response is written if neither format is
+// accepted.
+func (c *Config) Respond(w http.ResponseWriter, r *http.Request, status int, v interface{}) error {
+	w.Header().Add("Vary", "Accept")
+	contentType := negotiate(r.Header.Get("Accept"), ContentType, JSONContentType)
+	var data []byte
+	var err error
+	switch contentType {
+	case ContentType:
+		data, err = rencode.Marshal(v)
+	case JSONContentType:
+		var jv interface{}
+		jv, err = jsonValue(v, false)
+		if err == nil {
+			data, err = json.Marshal(jv)
+		}
+	default:
+		err = fmt.Errorf("none of %s and %s is acceptable", ContentType, JSONContentType)
+		http.Error(w, err.Error(), http.StatusNotAcceptable)
+		return err
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return err
+	}
+
+	encoding := ""
+	if !c.NoCompression {
+		w.Header().Add("Vary", "Accept-Encoding")
+		// responses are not compressed for clients without an Accept-Encoding header
+		if accept := r.Header.Get("Accept-Encoding"); accept != "" {
+			encoding = negotiate(accept, "gzip", "deflate", "identity")
+		}
+		if encoding == "identity" {
+			encoding = ""
+		}
+	}
+	if encoding != "" {
+		var b bytes.Buffer
+		var zw io.WriteCloser
+		if encoding == "gzip" {
+			zw = gzip.NewWriter(&b)
+		} else {
+			zw = zlib.NewWriter(&b)
+		}
+		_, err = zw.Write(data)
+		if err == nil {
+			err = zw.Close()
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		data = b.Bytes()
+	}
+	return writeBody(w, status, contentType, encoding, data)
+}
+
+// jsonValue returns v with its lists and dictionaries converted to values that encoding/json
+// writes as arrays and objects; inRencode is true for the values held by a list or dictionary,
+// whose byte slices are strings
+func jsonValue(v interface{}, inRencode bool) (interface{}, error) {
+	switch x := v.(type) {
+	case *rencode.List:
+		if x == nil {
+			return nil, nil
+		}
+		return jsonValue(*x, inRencode)
+	case *rencode.Dictionary:
+		if x == nil {
+			return nil, nil
+		}
+		return jsonValue(*x, inRencode)
+	case rencode.List:
+		a := make([]interface{}, x.Length())
+		for i, e := range x.Values() {
+			var err error
+			a[i], err = jsonValue(e, true)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return a, nil
+	case rencode.Dictionary:
+		m := make(map[string]interface{}, x.Length())
+		keys := x.Keys()
+		for i, e := range x.Values() {
+			k, err := jsonKey(keys[i])
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := m[k]; ok {
+				return nil, fmt.Errorf("duplicate JSON object key %q", k)
+			}
+			m[k], err = jsonValue(e, true)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case []byte:
+		if inRencode && utf8.Valid(x) {
+			return string(x), nil
+		}
+	case []interface{}:
+		a := make([]interface{}, len(x))
+		for i, e := range x {
+			var err error
+			a[i], err = jsonValue(e, inRencode)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return a, nil
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(x))
+		for k, e := range x {
+			var err error
+			m[k], err = jsonValue(e, inRencode)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	}
+	return v, nil
+}
+
+// jsonKey returns the JSON object key of a dictionary key, which must be a string or an integer
+func jsonKey(key interface{}) (string, error) {
+	switch x := key.(type) {
+	case string:
+		return x, nil
+	case []byte:
+		return string(x), nil
+	case int8, int16, int32, int64, int, uint8, uint16, uint32, uint64, uint, *big.Int:
+		return fmt.Sprint(x), nil
+	}
+	return "", fmt.Errorf("dictionary key of type %T cannot be represented in JSON", key)
+}
+
+func writeBody(w http.ResponseWriter, status int, contentType, encoding string, data []byte) error {
+	h := w.Header()
+	h.Set("Content-Type", contentType)
+	if encoding != "" {
+		h.Set("Content-Encoding", encoding)
+	}
+	h.Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(status)
+	_, err := w.Write(data)
+	return err
+}
+
+// negotiate returns the first of the offers with the highest quality in header, a list
+// of comma-separated values with optional "q" parameters as used by Accept and Accept-Encoding;
+// an empty header accepts the first offer. It returns an empty string if no offer is acceptable.
+func negotiate(header string, offers ...string) string {
+	if strings.TrimSpace(header) == "" {
+		return offers[0]
+	}
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		// the most specific matching range determines the quality
+		q, specificity := 0.0, -1
+		for _, part := range strings.Split(header, ",") {
+			params := strings.Split(part, ";")
+			s := matchRange(strings.ToLower(strings.TrimSpace(params[0])), offer)
+			if s <= specificity {
+				continue
+			}
+			specificity = s
+			q = 1
+			for _, param := range params[1:] {
+				kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+				if len(kv) == 2 && strings.TrimSpace(kv[0]) == "q" {
+					if f, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64); err == nil {
+						q = f
+					}
+				}
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// matchRange returns the specificity of the match of value by the range r, or -1
+func matchRange(r, value string) int {
+	switch {
+	case r == value:
+		return 2
+	case r == "*" || r == "*/*":
+		return 0
+	case strings.HasSuffix(r, "/*") && strings.HasPrefix(value, r[:len(r)-1]):
+		return 1
+	}
+	return -1
+}
+
+// limitedReader fails with ErrBodyTooLarge once more than n bytes are read
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		return 0, ErrBodyTooLarge
+	}
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n, ErrBodyTooLarge
+	}
+	return n, err
+}
@@ -0,0 +1,285 @@
+package httprencode
+
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gdm85/go-rencode"
+)
+
+type announce struct {
+	Torrent string   `rencode:"torrent" json:"torrent"`
+	Peers   []string `rencode:"peers" json:"peers"`
+	Left    uint64   `rencode:"left" json:"left"`
+}
+
+// newServer returns a server echoing the decoded announce with an additional peer
+func newServer(t *testing.T, c *Config) *httptest.Server {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var a announce
+		err := c.ReadRequest(r, &a)
+		if err != nil {
+			http.Error(w, err.Error(), err.(*Error).Status)
+			return
+		}
+		a.Peers = append(a.Peers, "10.0.0.1")
+		c.Respond(w, r, http.StatusOK, a)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func do(t *testing.T, req *http.Request) *http.Response {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestRoundTrip(t *testing.T) {
+	s := newServer(t, &Config{})
+
+	req, err := NewRequest(http.MethodPost, s.URL, announce{Torrent: "debian.iso", Left: 1 << 40})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := do(t, req)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != ContentType {
+		t.Fatalf("unexpected response %v %v", resp.Status, resp.Header)
+	}
+	var a announce
+	err = ReadResponse(resp, &a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Torrent != "debian.iso" || a.Left != 1<<40 || len(a.Peers) != 1 {
+		t.Fatalf("unexpected response %+v", a)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	s := newServer(t, &Config{})
+
+	req, _ := http.NewRequest(http.MethodPost, s.URL, strings.NewReader(`{"torrent":"x","left":3}`))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Accept", "application/x-rencode;q=0.5, application/json")
+	resp := do(t, req)
+	if resp.Header.Get("Content-Type") != JSONContentType {
+		t.Fatalf("unexpected content type %q", resp.Header.Get("Content-Type"))
+	}
+	var a announce
+	err := json.NewDecoder(resp.Body).Decode(&a)
+	if err != nil || a.Torrent != "x" || a.Left != 3 || len(a.Peers) != 1 {
+		t.Fatalf("unexpected response %+v (%v)", a, err)
+	}
+}
+
+func TestCompression(t *testing.T) {
+	s := newServer(t, &Config{})
+
+	data, _ := rencode.Marshal(announce{Torrent: strings.Repeat("x", 1000)})
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	zw.Write(data)
+	zw.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, s.URL, &b)
+	req.Header.Set("Content-Type", ContentType)
+	req.Header.Set("Content-Encoding", "gzip")
+	// setting Accept-Encoding disables the transparent decompression of the client
+	req.Header.Set("Accept-Encoding", "gzip;q=0.1, deflate")
+	resp := do(t, req)
+	if resp.Header.Get("Content-Encoding") != "deflate" {
+		t.Fatalf("unexpected response %v %v", resp.Status, resp.Header)
+	}
+	if resp.ContentLength >= int64(len(data)) {
+		t.Fatalf("response of %d bytes not compressed", resp.ContentLength)
+	}
+	var a announce
+	err := ReadResponse(resp, &a)
+	if err != nil || len(a.Torrent) != 1000 {
+		t.Fatalf("unexpected response %+v (%v)", a, err)
+	}
+
+	// compression can be disabled
+	s = newServer(t, &Config{NoCompression: true})
+	req, _ = NewRequest(http.MethodPost, s.URL, announce{})
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp = do(t, req)
+	if resp.Header.Get("Content-Encoding") != "" {
+		t.Fatalf("unexpected content encoding %q", resp.Header.Get("Content-Encoding"))
+	}
+}
+
+func TestRequestErrors(t *testing.T) {
+	s := newServer(t, &Config{MaxBodySize: 1024, MaxDepth: 5})
+
+	var bomb bytes.Buffer
+	zw := zlib.NewWriter(&bomb)
+	data, _ := rencode.Marshal(announce{Torrent: strings.Repeat("x", 1<<20)})
+	zw.Write(data)
+	zw.Close()
+	large, _ := rencode.Marshal(announce{Torrent: strings.Repeat("x", 2000)})
+	deep := append(bytes.Repeat([]byte{rencode.CHR_LIST}, 10), bytes.Repeat([]byte{rencode.CHR_TERM}, 10)...)
+	// unknown fields are ignored by encoding/json, but not their depth
+	deepJSON := []byte(`{"extra":` + strings.Repeat("[", 10) + strings.Repeat("]", 10) + "}")
+	// brackets within strings do not count
+	shallowJSON := []byte(`{"torrent":"` + strings.Repeat("[{", 10) + `\""}`)
+
+	for _, test := range []struct {
+		contentType, encoding string
+		body                  []byte
+		status                int
+	}{
+		{ContentType, "", large, http.StatusRequestEntityTooLarge},
+		{ContentType, "deflate", bomb.Bytes(), http.StatusRequestEntityTooLarge},
+		{ContentType, "", deep, http.StatusBadRequest},
+		{JSONContentType, "", deepJSON, http.StatusBadRequest},
+		{JSONContentType, "", shallowJSON, http.StatusOK},
+		{JSONContentType, "", []byte(`{"torrent":"x"} {}`), http.StatusBadRequest},
+		{ContentType, "", []byte{rencode.CHR_DICT}, http.StatusBadRequest},
+		{ContentType, "", []byte{rencode.CHR_NONE, rencode.CHR_NONE}, http.StatusBadRequest},
+		{"text/plain", "", nil, http.StatusUnsupportedMediaType},
+		{ContentType, "br", nil, http.StatusUnsupportedMediaType},
+		{ContentType, "gzip", []byte("not gzip"), http.StatusBadRequest},
+	} {
+		req, _ := http.NewRequest(http.MethodPost, s.URL, bytes.NewReader(test.body))
+		req.Header.Set("Content-Type", test.contentType)
+		if test.encoding != "" {
+			req.Header.Set("Content-Encoding", test.encoding)
+		}
+		resp := do(t, req)
+		if resp.StatusCode != test.status {
+			body, _ := io.ReadAll(resp.Body)
+			t.Errorf("%s %s: expected status %d but %s found (%s)", test.contentType, test.encoding, test.status, resp.Status, body)
+		}
+	}
+
+	req, _ := NewRequest(http.MethodPost, s.URL, announce{})
+	req.Header.Set("Accept", "text/html")
+	if resp := do(t, req); resp.StatusCode != http.StatusNotAcceptable {
+		t.Errorf("expected status 406 but %s found", resp.Status)
+	}
+}
+
+func TestRespondContentTypes(t *testing.T) {
+	var peer rencode.Dictionary
+	peer.Add("ip", []byte("10.0.0.1"))
+	peer.Add("port", 6881)
+	var peers rencode.List
+	peers.Add(peer)
+	peers.Add([]byte{0xff})
+	var v rencode.Dictionary
+	v.Add("peers", peers)
+	v.Add(int8(1), nil)
+
+	for _, test := range []struct {
+		accept, contentType, body string
+	}{
+		{"", ContentType, ""},
+		{"application/x-rencode", ContentType, ""},
+		{"application/json", JSONContentType, `{"1":null,"peers":[{"ip":"10.0.0.1","port":6881},"/w=="]}`},
+		{"application/json;q=0.9, */*;q=0.1", JSONContentType, `{"1":null,"peers":[{"ip":"10.0.0.1","port":6881},"/w=="]}`},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", test.accept)
+		w := httptest.NewRecorder()
+		err := Respond(w, req, http.StatusOK, v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w.Header().Get("Content-Type") != test.contentType {
+			t.Fatalf("%q: unexpected content type %q", test.accept, w.Header().Get("Content-Type"))
+		}
+		if test.contentType == JSONContentType {
+			if w.Body.String() != test.body {
+				t.Fatalf("%q: expected %s but %s found", test.accept, test.body, w.Body.String())
+			}
+			continue
+		}
+		found, err := rencode.NewDecoder(w.Body).DecodeNext()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !rencode.Equal(found, v) {
+			t.Fatalf("%q: expected %v but %v found", test.accept, v, found)
+		}
+	}
+
+	// keys that cannot be JSON object keys
+	var d rencode.Dictionary
+	d.Add(1.5, "x")
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", JSONContentType)
+	w := httptest.NewRecorder()
+	if err := Respond(w, req, http.StatusOK, d); err == nil || w.Code != http.StatusInternalServerError {
+		t.Fatalf("unexpected response %d (%v)", w.Code, err)
+	}
+}
+
+func TestWriteResponse(t *testing.T) {
+	w := httptest.NewRecorder()
+	err := WriteResponse(w, http.StatusCreated, map[string]int{"a": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusCreated || w.Header().Get("Content-Type") != ContentType {
+		t.Fatalf("unexpected response %d %v", w.Code, w.Header())
+	}
+	expected, _ := rencode.Marshal(map[string]int{"a": 1})
+	if !bytes.Equal(w.Body.Bytes(), expected) {
+		t.Fatalf("expected body %v but %v found", expected, w.Body.Bytes())
+	}
+
+	w = httptest.NewRecorder()
+	err = WriteResponse(w, http.StatusOK, make(chan int))
+	if err == nil || w.Code != http.StatusInternalServerError {
+		t.Fatalf("unexpected response %d (%v)", w.Code, err)
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	for _, test := range []struct {
+		header, expected string
+	}{
+		{"", ContentType},
+		{"*/*", ContentType},
+		{"application/*", ContentType},
+		{"application/json", JSONContentType},
+		{"application/*;q=0.5, application/json", JSONContentType},
+		{"application/x-rencode;q=0, */*", JSONContentType},
+		{"text/html, application/json;q=0.2", JSONContentType},
+		{"text/html", ""},
+	} {
+		if found := negotiate(test.header, ContentType, JSONContentType); found != test.expected {
+			t.Errorf("%q: expected %q but %q found", test.header, test.expected, found)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package rencode
+
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+// Kind is the kind of a rencode value, as identified by its typecode
+type Kind int
+
+// Kinds of rencode values
+const (
+	KindInvalid Kind = iota
+	KindInt
+	KindFloat
+	KindString
+	KindList
+	KindDict
+	KindNone
+	KindBool
+	// KindEnd is the terminator of a list or dictionary of unknown length
+	KindEnd
+)
+
+var kindNames = [...]string{
+	KindInvalid: "invalid",
+	KindInt:     "int",
+	KindFloat:   "float",
+	KindString:  "string",
+	KindList:    "list",
+	KindDict:    "dict",
+	KindNone:    "none",
+	KindBool:    "bool",
+	KindEnd:     "end",
+}
+
+func (k Kind) String() string {
+	if k < 0 || int(k) >= len(kindNames) {
+		return "invalid"
+	}
+	return kindNames[k]
+}
+
+// kindOf returns the kind of the value starting with typeCode
+func kindOf(typeCode byte) Kind {
+	switch typeCode {
+	case CHR_INT, CHR_INT1, CHR_INT2, CHR_INT4, CHR_INT8:
+		return KindInt
+	case CHR_FLOAT32, CHR_FLOAT64:
+		return KindFloat
+	case CHR_LIST:
+		return KindList
+	case CHR_DICT:
+		return KindDict
+	case CHR_NONE:
+		return KindNone
+	case CHR_TRUE, CHR_FALSE:
+		return KindBool
+	case CHR_TERM:
+		return KindEnd
+	}
+
+	switch {
+	case INT_POS_FIXED_START <= typeCode && typeCode < INT_POS_FIXED_START+INT_POS_FIXED_COUNT,
+		INT_NEG_FIXED_START <= typeCode && typeCode < INT_NEG_FIXED_START+INT_NEG_FIXED_COUNT:
+		return KindInt
+	case STR_FIXED_START <= typeCode && typeCode < STR_FIXED_START+STR_FIXED_COUNT,
+		'1' <= typeCode && typeCode <= '9':
+		return KindString
+	case LIST_FIXED_START <= typeCode && typeCode <= LIST_FIXED_START+LIST_FIXED_COUNT-1:
+		return KindList
+	case DICT_FIXED_START <= typeCode && typeCode < DICT_FIXED_START+DICT_FIXED_COUNT:
+		return KindDict
+	}
+	return KindInvalid
+}
@@ -0,0 +1,147 @@
+package rencode
+
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestPeekKind(t *testing.T) {
+	b := bytes.Buffer{}
+	e := NewEncoder(&b)
+	values := []interface{}{
+		int8(5), int8(-5), int8(100), int16(1000), int32(100000), int64(1) << 40,
+		new(big.Int).Lsh(big.NewInt(1), 70),
+		float32(1.5), 2.5, "short", strings.Repeat("x", 100),
+		[]int{1}, make([]int, 70), map[string]int{"a": 1}, nil, true, false,
+	}
+	expected := []Kind{
+		KindInt, KindInt, KindInt, KindInt, KindInt, KindInt, KindInt,
+		KindFloat, KindFloat, KindString, KindString,
+		KindList, KindList, KindDict, KindNone, KindBool, KindBool,
+	}
+	for _, v := range values {
+		err := e.Encode(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d := NewDecoder(&b)
+	for i, kind := range expected {
+		found, err := d.PeekKind()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found != kind {
+			t.Fatalf("value %d: expected kind %v but %v found", i, kind, found)
+		}
+		// peeking does not consume anything
+		found, _ = d.PeekKind()
+		if found != kind {
+			t.Fatalf("value %d: kind changed to %v", i, found)
+		}
+		err = d.Skip()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := d.PeekKind()
+	if err != io.EOF {
+		t.Fatalf("expected EOF but %v found", err)
+	}
+
+	d = NewDecoder(bytes.NewReader([]byte{CHR_LIST, CHR_TERM, 58}))
+	d.Token()
+	if kind, _ := d.PeekKind(); kind != KindEnd {
+		t.Fatalf("expected kind %v but %v found", KindEnd, kind)
+	}
+	d.Token()
+	if _, err := d.PeekKind(); err == nil {
+		t.Fatal("expected an error for an invalid typecode")
+	}
+}
+
+func TestBuffered(t *testing.T) {
+	// a rencode handshake followed by data of another protocol
+	b := bytes.Buffer{}
+	e := NewEncoder(&b)
+	err := e.Encode("hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.WriteString("RAW PROTOCOL DATA")
+
+	d := NewDecoder(&b)
+	v, err := d.DecodeNext()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(v.([]byte)) != "hello" {
+		t.Fatalf("unexpected value %v", v)
+	}
+
+	rest, err := ioutil.ReadAll(io.MultiReader(d.Buffered(), &b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(rest) != "RAW PROTOCOL DATA" {
+		t.Fatalf("unexpected remaining data %q", rest)
+	}
+}
+
+// countingReader counts the Read calls made on the underlying reader
+type countingReader struct {
+	r     io.Reader
+	reads int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	c.reads++
+	return c.r.Read(p)
+}
+
+func TestDecoderBuffersReads(t *testing.T) {
+	b := bytes.Buffer{}
+	e := NewEncoder(&b)
+	for i := 0; i < 100; i++ {
+		err := e.Encode(int32(100000 + i))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := &countingReader{r: &b}
+	d := NewDecoder(c)
+	for i := 0; i < 100; i++ {
+		_, err := d.DecodeNext()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if c.reads > 2 {
+		t.Fatalf("expected buffered reads but %d reads were issued", c.reads)
+	}
+}
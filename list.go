@@ -58,15 +58,14 @@ func (l *List) Length() int {
 }
 
 // Equals performs an equality comparison on specified lists;
-// []byte and string are considered the same type, and only List and Dictionary
-// types are recursively compared
+// elements are compared with Equal
 func (l *List) Equals(b *List) bool {
 	if l.Length() != b.Length() {
 		return false
 	}
 
 	for i, v1 := range l.values {
-		if !deepEqual(v1, b.values[i]) {
+		if !Equal(v1, b.values[i]) {
 			return false
 		}
 	}
@@ -0,0 +1,329 @@
+package rencode
+
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type stepKind int
+
+const (
+	stepKey       stepKind = iota // a dictionary key or list index
+	stepWildcard                  // '*', any direct child
+	stepRecursive                 // '**', zero or more levels
+)
+
+type step struct {
+	kind stepKind
+	key  string
+	// index is valid when isIndex is true and allows matching list elements and integer keys
+	index   int64
+	isIndex bool
+}
+
+// Selector is a compiled path expression that can be evaluated against decoded values.
+//
+// A path is a list of segments separated by dots; each segment can be:
+// * a dictionary key, matched against string/[]byte keys and, when numeric, integer keys and list indexes
+// * '*', matching any element of a list or any value of a dictionary
+// * '**', matching zero or more levels of nesting (recursive descent)
+// Dots, asterisks and backslashes that are part of a key must be escaped with a backslash.
+// The empty path selects the root value.
+type Selector struct {
+	steps []step
+}
+
+// Match is a value selected by a Selector along with its concrete path;
+// path elements are dictionary keys (as found in the dictionary) and list indexes (as int).
+type Match struct {
+	Path  []interface{}
+	Value interface{}
+}
+
+// ParseSelector compiles the specified path expression
+func ParseSelector(path string) (*Selector, error) {
+	s := &Selector{}
+	if path == "" {
+		return s, nil
+	}
+
+	var segment []byte
+	escaped := false
+	literal := false
+	flush := func() error {
+		if len(segment) == 0 && !literal {
+			return fmt.Errorf("empty segment in path %q", path)
+		}
+		seg := string(segment)
+		switch {
+		case !literal && seg == "*":
+			s.steps = append(s.steps, step{kind: stepWildcard})
+		case !literal && seg == "**":
+			s.steps = append(s.steps, step{kind: stepRecursive})
+		default:
+			st := step{kind: stepKey, key: seg}
+			if i, err := strconv.ParseInt(seg, 10, 64); err == nil {
+				st.index = i
+				st.isIndex = true
+			}
+			s.steps = append(s.steps, st)
+		}
+		segment = segment[:0]
+		literal = false
+		return nil
+	}
+
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if escaped {
+			segment = append(segment, c)
+			escaped = false
+			continue
+		}
+		switch c {
+		case '\\':
+			escaped = true
+			// an escaped character always makes the segment a literal key
+			literal = true
+		case '.':
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		default:
+			segment = append(segment, c)
+		}
+	}
+	if escaped {
+		return nil, fmt.Errorf("trailing escape character in path %q", path)
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// String returns the path expression of the selector
+func (s *Selector) String() string {
+	parts := make([]string, len(s.steps))
+	for i, st := range s.steps {
+		switch st.kind {
+		case stepWildcard:
+			parts[i] = "*"
+		case stepRecursive:
+			parts[i] = "**"
+		default:
+			parts[i] = escapePathKey(st.key)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// escapePathKey returns key as a path segment matching it literally
+func escapePathKey(key string) string {
+	key = strings.NewReplacer(`\`, `\\`, `.`, `\.`).Replace(key)
+	if key == "*" || key == "**" {
+		key = `\` + key
+	}
+	return key
+}
+
+// Lookup returns all values within v matching the specified path expression, see Selector
+func Lookup(v interface{}, path string) ([]Match, error) {
+	s, err := ParseSelector(path)
+	if err != nil {
+		return nil, err
+	}
+	return s.Select(v), nil
+}
+
+// Select returns all values within v matched by the selector, in depth-first order
+func (s *Selector) Select(v interface{}) []Match {
+	var matches []Match
+	s.eval(v, nil, s.closure([]int{0}), &matches)
+	return matches
+}
+
+// closure expands the set of states with the states reachable by matching '**' against zero levels
+func (s *Selector) closure(states []int) []int {
+	for i := 0; i < len(states); i++ {
+		st := states[i]
+		if st < len(s.steps) && s.steps[st].kind == stepRecursive {
+			states = addState(states, st+1)
+		}
+	}
+	return states
+}
+
+func addState(states []int, st int) []int {
+	for _, existing := range states {
+		if existing == st {
+			return states
+		}
+	}
+	return append(states, st)
+}
+
+// accepts returns true if any of the states has matched all steps
+func (s *Selector) accepts(states []int) bool {
+	for _, st := range states {
+		if st == len(s.steps) {
+			return true
+		}
+	}
+	return false
+}
+
+// advance returns the states obtained by descending into a child with specified key
+func (s *Selector) advance(states []int, key interface{}, isListIndex bool) []int {
+	var next []int
+	for _, st := range states {
+		if st == len(s.steps) {
+			continue
+		}
+		switch s.steps[st].kind {
+		case stepRecursive:
+			next = addState(next, st)
+		case stepWildcard:
+			next = addState(next, st+1)
+		default:
+			if s.steps[st].matches(key, isListIndex) {
+				next = addState(next, st+1)
+			}
+		}
+	}
+	return s.closure(next)
+}
+
+func (st *step) matches(key interface{}, isListIndex bool) bool {
+	if isListIndex {
+		return st.isIndex && int64(key.(int)) == st.index
+	}
+	if st.isIndex {
+		if i, ok := intValue(key); ok && i == st.index {
+			return true
+		}
+	}
+	return Equal(key, st.key)
+}
+
+func (s *Selector) eval(v interface{}, path []interface{}, states []int, matches *[]Match) {
+	if s.accepts(states) {
+		*matches = append(*matches, Match{Path: copyPath(path), Value: v})
+	}
+
+	switch x := v.(type) {
+	case *List:
+		v = *x
+	case *Dictionary:
+		v = *x
+	}
+
+	switch x := v.(type) {
+	case List:
+		for i, child := range x.Values() {
+			next := s.advance(states, i, true)
+			if len(next) != 0 {
+				s.eval(child, append(path, i), next, matches)
+			}
+		}
+	case Dictionary:
+		keys := x.Keys()
+		for i, child := range x.Values() {
+			next := s.advance(states, keys[i], false)
+			if len(next) != 0 {
+				s.eval(child, append(path, keys[i]), next, matches)
+			}
+		}
+	}
+}
+
+func copyPath(path []interface{}) []interface{} {
+	return append([]interface{}(nil), path...)
+}
+
+// Select decodes the next value from the rencode stream and returns all the values matched by the selector;
+// branches that cannot match are skipped without being decoded.
+// If no more objects are available, an io.EOF error will be returned.
+func (r *Decoder) Select(s *Selector) ([]Match, error) {
+	typeCode, err := r.readByte()
+	if err != nil {
+		if len(r.containers) > 0 {
+			return nil, unexpectedEOF(err)
+		}
+		return nil, err
+	}
+
+	var matches []Match
+	err = r.selectValue(s, typeCode, nil, s.closure([]int{0}), &matches)
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+
+	r.tokenDone()
+	return matches, nil
+}
+
+func (r *Decoder) selectValue(s *Selector, typeCode byte, path []interface{}, states []int, matches *[]Match) error {
+	if len(states) == 0 {
+		return r.skip(typeCode)
+	}
+
+	if s.accepts(states) {
+		// the whole value is needed, descendants are matched in memory
+		v, err := r.decode(typeCode)
+		if err != nil {
+			return err
+		}
+		s.eval(v, path, states, matches)
+		return nil
+	}
+
+	switch {
+	case typeCode == CHR_LIST, LIST_FIXED_START <= typeCode && typeCode <= LIST_FIXED_START+LIST_FIXED_COUNT-1:
+		i := 0
+		return r.eachElement(typeCode, func(childCode byte) error {
+			err := r.selectValue(s, childCode, append(path, i), s.advance(states, i, true), matches)
+			i++
+			return err
+		})
+	case typeCode == CHR_DICT, DICT_FIXED_START <= typeCode && typeCode < DICT_FIXED_START+DICT_FIXED_COUNT:
+		return r.eachElement(typeCode, func(keyCode byte) error {
+			key, err := r.decode(keyCode)
+			if err != nil {
+				return err
+			}
+			valueCode, err := r.readByte()
+			if err != nil {
+				return err
+			}
+			if valueCode == CHR_TERM {
+				return fmt.Errorf("incomplete key-value pair in dictionary data")
+			}
+			return r.selectValue(s, valueCode, append(path, key), s.advance(states, key, false), matches)
+		})
+	}
+
+	// scalars cannot have children
+	return r.skip(typeCode)
+}
@@ -0,0 +1,247 @@
+package rencode
+
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+)
+
+// torrentsFixture returns a dictionary similar to a torrent status response
+func torrentsFixture(t *testing.T) Dictionary {
+	var torrents Dictionary
+	for i, name := range []string{"debian.iso", "ubuntu.iso"} {
+		var torrent Dictionary
+		var files List
+		files.Add([]byte(name))
+		files.Add([]byte("README"))
+
+		for _, kv := range [][2]interface{}{
+			{"name", []byte(name)},
+			{"progress", float32(i) / 2},
+			{"files", files},
+		} {
+			err := torrent.Add(kv[0], kv[1])
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		err := torrents.Add([]byte(fmt.Sprintf("hash%d", i)), torrent)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var root Dictionary
+	err := root.Add([]byte("torrents"), torrents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = root.Add(int8(7), []byte("seven"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func matchesString(matches []Match) string {
+	var b bytes.Buffer
+	for _, m := range matches {
+		fmt.Fprint(&b, "[")
+		for i, p := range m.Path {
+			if i > 0 {
+				fmt.Fprint(&b, " ")
+			}
+			if s, ok := p.([]byte); ok {
+				p = string(s)
+			}
+			fmt.Fprint(&b, p)
+		}
+		v := m.Value
+		if s, ok := v.([]byte); ok {
+			v = string(s)
+		}
+		switch v.(type) {
+		case List, Dictionary:
+			v = fmt.Sprintf("%T", v)
+		}
+		fmt.Fprintf(&b, "]=%v;", v)
+	}
+	return b.String()
+}
+
+func TestLookup(t *testing.T) {
+	root := torrentsFixture(t)
+
+	for _, tc := range []struct {
+		path     string
+		expected string
+	}{
+		{"", "[]=rencode.Dictionary;"},
+		{"torrents.hash1.name", "[torrents hash1 name]=ubuntu.iso;"},
+		{"torrents.*.name", "[torrents hash0 name]=debian.iso;[torrents hash1 name]=ubuntu.iso;"},
+		{"torrents.*.files.1", "[torrents hash0 files 1]=README;[torrents hash1 files 1]=README;"},
+		{"torrents.hash0.files.*", "[torrents hash0 files 0]=debian.iso;[torrents hash0 files 1]=README;"},
+		{"**.progress", "[torrents hash0 progress]=0;[torrents hash1 progress]=0.5;"},
+		{"**.**.name", "[torrents hash0 name]=debian.iso;[torrents hash1 name]=ubuntu.iso;"},
+		{"torrents.**.0", "[torrents hash0 files 0]=debian.iso;[torrents hash1 files 0]=ubuntu.iso;"},
+		{"7", "[7]=seven;"},
+		{"torrents.missing.name", ""},
+		{"torrents.hash0.files.2", ""},
+	} {
+		matches, err := Lookup(root, tc.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		found := matchesString(matches)
+		if found != tc.expected {
+			t.Errorf("path %q: expected %s but %s found", tc.path, tc.expected, found)
+		}
+
+		// the same selector evaluated on the byte stream must find the same values
+		b := bytes.Buffer{}
+		e := NewEncoder(&b)
+		err = e.Encode(root)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		s, err := ParseSelector(tc.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d := NewDecoder(&b)
+		matches, err = d.Select(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		found = matchesString(matches)
+		if found != tc.expected {
+			t.Errorf("path %q on stream: expected %s but %s found", tc.path, tc.expected, found)
+		}
+		_, err = d.DecodeNext()
+		if err != io.EOF {
+			t.Errorf("path %q on stream: expected end of stream but %v found", tc.path, err)
+		}
+	}
+}
+
+func TestLookupRecursiveAll(t *testing.T) {
+	var l List
+	l.Add(int8(1))
+	var nested List
+	nested.Add(int8(2))
+	l.Add(nested)
+
+	matches, err := Lookup(l, "**")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "[]=rencode.List;[0]=1;[1]=rencode.List;[1 0]=2;"
+	if found := matchesString(matches); found != expected {
+		t.Fatalf("expected %s but %s found", expected, found)
+	}
+}
+
+func TestParseSelector(t *testing.T) {
+	for _, path := range []string{`a.b`, `a\.b.*`, `\*.**.3`, `a\\b`} {
+		s, err := ParseSelector(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.String() != path {
+			t.Errorf("expected %q but %q found", path, s.String())
+		}
+	}
+
+	for _, path := range []string{`a..b`, `.a`, `a.`, `a\`} {
+		_, err := ParseSelector(path)
+		if err == nil {
+			t.Errorf("expected an error for path %q", path)
+		}
+	}
+
+	var d Dictionary
+	err := d.Add("a.b", int8(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = d.Add("*", int8(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	matches, err := Lookup(d, `a\.b`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found := matchesString(matches); found != "[a.b]=1;" {
+		t.Fatalf("unexpected matches %s", found)
+	}
+
+	matches, err = Lookup(d, `\*`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found := matchesString(matches); found != "[*]=2;" {
+		t.Fatalf("unexpected matches %s", found)
+	}
+}
+
+func TestDecoderSkip(t *testing.T) {
+	root := torrentsFixture(t)
+
+	b := bytes.Buffer{}
+	e := NewEncoder(&b)
+	for _, v := range []interface{}{root, int64(1) << 40, float64(1.5), []byte(string(make([]byte, 300))), uint64(1) << 63, int8(-100)} {
+		err := e.Encode(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := e.Encode("end")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDecoder(&b)
+	for i := 0; i < 6; i++ {
+		typeCode, err := d.readByte()
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = d.skip(typeCode)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	found, err := d.DecodeNext()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(found.([]byte)) != "end" {
+		t.Fatalf("expected %q but %v found", "end", found)
+	}
+}
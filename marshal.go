@@ -0,0 +1,961 @@
+package rencode
+
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Unmarshaler is the interface implemented by types that can decode themselves from a rencode stream
+type Unmarshaler interface {
+	DecodeRencode(d *Decoder) error
+}
+
+// UnmarshalTypeError describes a rencode value that cannot be stored in a Go value of a specific type
+type UnmarshalTypeError struct {
+	Value string // description of the rencode value, e.g. "list" or "int8"
+	Type  reflect.Type
+}
+
+func (e *UnmarshalTypeError) Error() string {
+	return "cannot decode " + e.Value + " into Go value of type " + e.Type.String()
+}
+
+var (
+	unmarshalerType = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+	listType        = reflect.TypeOf(List{})
+	dictionaryType  = reflect.TypeOf(Dictionary{})
+	bigIntType      = reflect.TypeOf(big.Int{})
+)
+
+// Marshal returns the rencode encoding of v.
+//
+// Besides the types supported by Encoder.Encode, Marshal encodes through reflection:
+// * structs as dictionaries of their exported fields, with field names as keys
+// * slices and arrays as lists, except for byte slices and arrays which are encoded as strings
+// * maps as dictionaries, sorted by key
+// * pointers and interfaces as the value they point to
+// * nil pointers, interfaces, maps and slices (other than []byte) as None
+// * named types according to their underlying type
+//
+// Struct fields can be customised with a "rencode" tag holding the key name followed by
+// comma-separated options; the "omitempty" option skips fields having an empty value and
+// a name of "-" skips the field entirely.
+func Marshal(v interface{}) ([]byte, error) {
+	e := GetEncoder(nil)
+	defer PutEncoder(e)
+	e.SetAutoFlush(false)
+
+	err := e.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), e.buf...), nil
+}
+
+// Unmarshal decodes the first rencode value in data and stores it in the value pointed to by v, see Decoder.Decode
+func Unmarshal(data []byte, v interface{}) error {
+	d := GetDecoder(bytes.NewReader(data))
+	defer PutDecoder(d)
+	return d.Decode(v)
+}
+
+type field struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+var fieldCache sync.Map // map[reflect.Type][]field
+
+// structFields returns the encodable fields of the specified struct type
+func structFields(t reflect.Type) []field {
+	if f, ok := fieldCache.Load(t); ok {
+		return f.([]field)
+	}
+
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			// unexported
+			continue
+		}
+		f := field{name: sf.Name, index: sf.Index}
+		tag := sf.Tag.Get("rencode")
+		if tag == "-" {
+			continue
+		}
+		if tag != "" {
+			parts := strings.Split(tag, ",")
+			if parts[0] != "" {
+				f.name = parts[0]
+			}
+			for _, opt := range parts[1:] {
+				if opt == "omitempty" {
+					f.omitEmpty = true
+				}
+			}
+		}
+		fields = append(fields, f)
+	}
+
+	fieldCache.Store(t, fields)
+	return fields
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// encodeReflect encodes the values not directly supported by Encode
+func (r *Encoder) encodeReflect(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return r.EncodeNone()
+		}
+		return r.Encode(v.Elem().Interface())
+	case reflect.Bool:
+		return r.EncodeBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return r.EncodeInt(v.Int())
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return r.EncodeInt(int64(v.Uint()))
+	case reflect.Uint, reflect.Uint64, reflect.Uintptr:
+		return r.Encode(v.Uint())
+	case reflect.Float32:
+		return r.EncodeFloat32(float32(v.Float()))
+	case reflect.Float64:
+		return r.EncodeFloat64(v.Float())
+	case reflect.String:
+		return r.EncodeString(v.String())
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			if v.Kind() == reflect.Slice {
+				return r.EncodeBytes(v.Bytes())
+			}
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			return r.EncodeBytes(b)
+		}
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return r.EncodeNone()
+		}
+		n := v.Len()
+		err := r.EncodeListStart(n)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < n; i++ {
+			err = r.Encode(v.Index(i).Interface())
+			if err != nil {
+				return err
+			}
+		}
+		return r.EncodeListEnd(n)
+	case reflect.Map:
+		if v.IsNil() {
+			return r.EncodeNone()
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return lessKey(keys[i], keys[j])
+		})
+		err := r.EncodeDictStart(len(keys))
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			err = r.Encode(k.Interface())
+			if err != nil {
+				return err
+			}
+			err = r.Encode(v.MapIndex(k).Interface())
+			if err != nil {
+				return err
+			}
+		}
+		return r.EncodeDictEnd(len(keys))
+	case reflect.Struct:
+		fields := structFields(v.Type())
+		n := 0
+		for _, f := range fields {
+			if !f.omitEmpty || !isEmptyValue(v.FieldByIndex(f.index)) {
+				n++
+			}
+		}
+		err := r.EncodeDictStart(n)
+		if err != nil {
+			return err
+		}
+		for _, f := range fields {
+			fv := v.FieldByIndex(f.index)
+			if f.omitEmpty && isEmptyValue(fv) {
+				continue
+			}
+			err = r.EncodeString(f.name)
+			if err != nil {
+				return err
+			}
+			err = r.Encode(fv.Interface())
+			if err != nil {
+				return err
+			}
+		}
+		return r.EncodeDictEnd(n)
+	}
+
+	return fmt.Errorf("could not encode data of type %s", v.Type())
+}
+
+// lessKey orders map keys so that maps are always encoded the same way
+func lessKey(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.String:
+		return a.String() < b.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() < b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() < b.Float()
+	case reflect.Bool:
+		return !a.Bool() && b.Bool()
+	}
+	return fmt.Sprint(a.Interface()) < fmt.Sprint(b.Interface())
+}
+
+// Decode decodes the next value from the rencode stream and stores it in the value pointed to by v.
+//
+// Values are converted to the destination type with the reverse rules of Marshal; in addition:
+// * integers of any width can be stored in any integer type that can hold their value
+// * integers and floats can be stored in float types
+// * strings can be stored in both string and []byte types
+// * None stores the zero value of the destination type
+// * unknown dictionary keys are skipped when decoding into structs
+// * any value can be stored in an empty interface, as returned by DecodeNext
+// * byte string keys of maps with interface keys are stored as string
+// * time.Time accepts numbers of seconds since the Unix epoch and RFC 3339 strings
+// * time.Duration accepts numbers of seconds, net.IP and *url.URL accept strings
+// * json.Number accepts numbers and numeric strings
+// Types implementing Unmarshaler decode themselves.
+// If no more objects are available, an io.EOF error will be returned.
+func (r *Decoder) Decode(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("Decode requires a non-nil pointer, not %T", v)
+	}
+	return r.decodeValue(rv.Elem())
+}
+
+func (r *Decoder) decodeValue(dst reflect.Value) error {
+	if dst.Kind() == reflect.Ptr && dst.Type().Implements(unmarshalerType) {
+		b, err := r.peekByte()
+		if err != nil {
+			if len(r.containers) > 0 {
+				return unexpectedEOF(err)
+			}
+			return err
+		}
+		if b == CHR_NONE {
+			_, err = r.Token()
+			dst.Set(reflect.Zero(dst.Type()))
+			return err
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return dst.Interface().(Unmarshaler).DecodeRencode(r)
+	}
+	if dst.CanAddr() && dst.Kind() != reflect.Ptr && reflect.PtrTo(dst.Type()).Implements(unmarshalerType) {
+		return dst.Addr().Interface().(Unmarshaler).DecodeRencode(r)
+	}
+
+	tok, err := r.Token()
+	if err != nil {
+		return err
+	}
+
+	switch tok.(type) {
+	case ListStart:
+		return r.decodeListInto(dst)
+	case DictStart:
+		return r.decodeDictInto(dst)
+	case End:
+		return fmt.Errorf("unexpected end of container")
+	}
+	return assign(dst, tok)
+}
+
+// decodeListInto decodes the elements of a list whose ListStart token has already been consumed
+func (r *Decoder) decodeListInto(dst reflect.Value) error {
+	switch dst.Kind() {
+	case reflect.Ptr:
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return r.decodeListInto(dst.Elem())
+	case reflect.Slice:
+		if dst.Type().Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		s := reflect.MakeSlice(dst.Type(), 0, 0)
+		for i := 0; ; i++ {
+			more, err := r.More()
+			if err != nil {
+				return err
+			}
+			if !more {
+				break
+			}
+			s = reflect.Append(s, reflect.Zero(dst.Type().Elem()))
+			err = r.decodeValue(s.Index(i))
+			if err != nil {
+				return err
+			}
+		}
+		dst.Set(s)
+		return nil
+	case reflect.Array:
+		if dst.Type().Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		i := 0
+		for ; ; i++ {
+			more, err := r.More()
+			if err != nil {
+				return err
+			}
+			if !more {
+				break
+			}
+			if i >= dst.Len() {
+				err = r.Skip()
+			} else {
+				err = r.decodeValue(dst.Index(i))
+			}
+			if err != nil {
+				return err
+			}
+		}
+		for ; i < dst.Len(); i++ {
+			dst.Index(i).Set(reflect.Zero(dst.Type().Elem()))
+		}
+		return nil
+	}
+
+	if dst.Type() == listType || (dst.Kind() == reflect.Interface && listType.Implements(dst.Type())) {
+		l, err := r.remainingList()
+		if err != nil {
+			return err
+		}
+		dst.Set(reflect.ValueOf(l))
+		return nil
+	}
+
+	return &UnmarshalTypeError{Value: "list", Type: dst.Type()}
+}
+
+// decodeDictInto decodes the pairs of a dictionary whose DictStart token has already been consumed
+func (r *Decoder) decodeDictInto(dst reflect.Value) error {
+	switch dst.Kind() {
+	case reflect.Ptr:
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return r.decodeDictInto(dst.Elem())
+	case reflect.Map:
+		if dst.IsNil() {
+			dst.Set(reflect.MakeMap(dst.Type()))
+		}
+		for {
+			more, err := r.More()
+			if err != nil {
+				return err
+			}
+			if !more {
+				return nil
+			}
+			k := reflect.New(dst.Type().Key()).Elem()
+			err = r.decodeValue(k)
+			if err == nil {
+				err = checkMapKey(k)
+			}
+			if err != nil {
+				return err
+			}
+			v := reflect.New(dst.Type().Elem()).Elem()
+			err = r.decodeValue(v)
+			if err != nil {
+				return err
+			}
+			dst.SetMapIndex(k, v)
+		}
+	case reflect.Struct:
+		if dst.Type() == dictionaryType {
+			break
+		}
+		fields := structFields(dst.Type())
+		for {
+			more, err := r.More()
+			if err != nil {
+				return err
+			}
+			if !more {
+				return nil
+			}
+			key, err := r.DecodeBytes()
+			if err != nil {
+				return err
+			}
+			found := false
+			for _, f := range fields {
+				if f.name == string(key) {
+					err = r.decodeValue(dst.FieldByIndex(f.index))
+					found = true
+					break
+				}
+			}
+			if !found {
+				err = r.Skip()
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if dst.Type() == dictionaryType || (dst.Kind() == reflect.Interface && dictionaryType.Implements(dst.Type())) {
+		d, err := r.remainingDict()
+		if err != nil {
+			return err
+		}
+		dst.Set(reflect.ValueOf(d))
+		return nil
+	}
+
+	return &UnmarshalTypeError{Value: "dictionary", Type: dst.Type()}
+}
+
+// checkMapKey makes a decoded interface map key usable: byte strings are stored as string
+// and other values that cannot be map keys, such as lists, cause an error
+func checkMapKey(k reflect.Value) error {
+	if k.Kind() != reflect.Interface || k.IsNil() {
+		return nil
+	}
+	if b, ok := k.Interface().([]byte); ok {
+		k.Set(reflect.ValueOf(string(b)))
+		return nil
+	}
+	if !k.Elem().Type().Comparable() {
+		return &UnmarshalTypeError{Value: describe(k.Interface()) + " key", Type: k.Type()}
+	}
+	return nil
+}
+
+// remainingList decodes the elements of a list whose ListStart token has already been consumed
+func (r *Decoder) remainingList() (l List, err error) {
+	for {
+		more, err := r.More()
+		if err != nil || !more {
+			return l, err
+		}
+		v, err := r.DecodeNext()
+		if err != nil {
+			return l, err
+		}
+		l.Add(v)
+	}
+}
+
+// remainingDict decodes the pairs of a dictionary whose DictStart token has already been consumed
+func (r *Decoder) remainingDict() (d Dictionary, err error) {
+	for {
+		more, err := r.More()
+		if err != nil || !more {
+			return d, err
+		}
+		key, err := r.DecodeNext()
+		if err != nil {
+			return d, err
+		}
+		value, err := r.DecodeNext()
+		if err != nil {
+			return d, err
+		}
+		err = d.addDecoded(key, value)
+		if err != nil {
+			return d, err
+		}
+	}
+}
+
+// describe returns a short description of a decoded value for error messages
+func describe(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "None"
+	case List, ListStart:
+		return "list"
+	case Dictionary, DictStart:
+		return "dictionary"
+	case []byte, string:
+		return "string"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// toInt64 converts any of the decoded integer types to int64
+func toInt64(v interface{}) (int64, bool) {
+	switch x := v.(type) {
+	case big.Int:
+		if x.IsInt64() {
+			return x.Int64(), true
+		}
+		return 0, false
+	case *big.Int:
+		if x.IsInt64() {
+			return x.Int64(), true
+		}
+		return 0, false
+	}
+	return intValue(v)
+}
+
+// toUint64 converts any of the decoded non-negative integer types to uint64
+func toUint64(v interface{}) (uint64, bool) {
+	switch x := v.(type) {
+	case uint64:
+		return x, true
+	case uint:
+		return uint64(x), true
+	case big.Int:
+		if x.IsUint64() {
+			return x.Uint64(), true
+		}
+		return 0, false
+	case *big.Int:
+		if x.IsUint64() {
+			return x.Uint64(), true
+		}
+		return 0, false
+	}
+	i, ok := intValue(v)
+	if !ok || i < 0 {
+		return 0, false
+	}
+	return uint64(i), true
+}
+
+// toFloat64 converts any of the decoded numeric types to float64
+func toFloat64(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case float32:
+		return float64(x), true
+	case float64:
+		return x, true
+	case big.Int:
+		f, _ := new(big.Float).SetInt(&x).Float64()
+		return f, true
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(x).Float64()
+		return f, true
+	}
+	if i, ok := toInt64(v); ok {
+		return float64(i), true
+	}
+	if u, ok := toUint64(v); ok {
+		return float64(u), true
+	}
+	return 0, false
+}
+
+// assign stores a value as returned by DecodeNext in dst, converting it as described by Decoder.Decode
+func assign(dst reflect.Value, v interface{}) error {
+	if v == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+	if ok, err := assignStandard(dst, v); ok {
+		return err
+	}
+
+	src := reflect.ValueOf(v)
+	if src.Type().AssignableTo(dst.Type()) {
+		dst.Set(src)
+		return nil
+	}
+
+	switch dst.Kind() {
+	case reflect.Ptr:
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return assign(dst.Elem(), v)
+	case reflect.Bool:
+		if b, ok := v.(bool); ok {
+			dst.SetBool(b)
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i, ok := toInt64(v); ok && !dst.OverflowInt(i) {
+			dst.SetInt(i)
+			return nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if u, ok := toUint64(v); ok && !dst.OverflowUint(u) {
+			dst.SetUint(u)
+			return nil
+		}
+	case reflect.Float32, reflect.Float64:
+		if f, ok := toFloat64(v); ok && !dst.OverflowFloat(f) {
+			dst.SetFloat(f)
+			return nil
+		}
+	case reflect.String:
+		switch x := v.(type) {
+		case []byte:
+			dst.SetString(string(x))
+			return nil
+		case string:
+			dst.SetString(x)
+			return nil
+		}
+	case reflect.Slice:
+		if dst.Type().Elem().Kind() == reflect.Uint8 {
+			switch x := v.(type) {
+			case []byte:
+				dst.Set(reflect.ValueOf(x).Convert(dst.Type()))
+				return nil
+			case string:
+				dst.Set(reflect.ValueOf([]byte(x)).Convert(dst.Type()))
+				return nil
+			}
+			break
+		}
+		if l, ok := v.(List); ok {
+			s := reflect.MakeSlice(dst.Type(), l.Length(), l.Length())
+			for i, e := range l.Values() {
+				err := assign(s.Index(i), e)
+				if err != nil {
+					return err
+				}
+			}
+			dst.Set(s)
+			return nil
+		}
+		// Go slices and arrays stored in hand-built List and Dictionary values; byte slices are strings
+		if (src.Kind() == reflect.Slice || src.Kind() == reflect.Array) && src.Type().Elem().Kind() != reflect.Uint8 {
+			s := reflect.MakeSlice(dst.Type(), src.Len(), src.Len())
+			for i := 0; i < src.Len(); i++ {
+				err := assign(s.Index(i), src.Index(i).Interface())
+				if err != nil {
+					return err
+				}
+			}
+			dst.Set(s)
+			return nil
+		}
+	case reflect.Array:
+		if x, ok := v.(string); ok {
+			v = []byte(x)
+		}
+		if b, ok := v.([]byte); ok && dst.Type().Elem().Kind() == reflect.Uint8 {
+			if len(b) != dst.Len() {
+				break
+			}
+			reflect.Copy(dst, reflect.ValueOf(b))
+			return nil
+		}
+		if l, ok := v.(List); ok {
+			for i := 0; i < dst.Len(); i++ {
+				var e interface{}
+				if i < l.Length() {
+					e = l.values[i]
+				}
+				err := assign(dst.Index(i), e)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	case reflect.Map:
+		if d, ok := v.(Dictionary); ok {
+			m := reflect.MakeMapWithSize(dst.Type(), d.Length())
+			keys := d.Keys()
+			for i, e := range d.Values() {
+				k := reflect.New(dst.Type().Key()).Elem()
+				err := assign(k, keys[i])
+				if err == nil {
+					err = checkMapKey(k)
+				}
+				if err != nil {
+					return err
+				}
+				ev := reflect.New(dst.Type().Elem()).Elem()
+				err = assign(ev, e)
+				if err != nil {
+					return err
+				}
+				m.SetMapIndex(k, ev)
+			}
+			dst.Set(m)
+			return nil
+		}
+	case reflect.Struct:
+		if dst.Type() == bigIntType {
+			if x, ok := v.(*big.Int); ok {
+				dst.Set(reflect.ValueOf(*new(big.Int).Set(x)))
+				return nil
+			}
+			if i, ok := toInt64(v); ok {
+				dst.Set(reflect.ValueOf(*big.NewInt(i)))
+				return nil
+			}
+			if u, ok := toUint64(v); ok {
+				dst.Set(reflect.ValueOf(*new(big.Int).SetUint64(u)))
+				return nil
+			}
+			break
+		}
+		if d, ok := v.(Dictionary); ok {
+			fields := structFields(dst.Type())
+			keys := d.Keys()
+			for i, e := range d.Values() {
+				for _, f := range fields {
+					if Equal(keys[i], f.name) {
+						err := assign(dst.FieldByIndex(f.index), e)
+						if err != nil {
+							return err
+						}
+						break
+					}
+				}
+			}
+			return nil
+		}
+	}
+
+	return &UnmarshalTypeError{Value: describe(v), Type: dst.Type()}
+}
+
+// More reports whether there is another element in the list or dictionary being decoded with Token;
+// when there is none, the End token of the container is consumed.
+func (r *Decoder) More() (bool, error) {
+	n := len(r.containers)
+	if n == 0 {
+		return false, fmt.Errorf("not within a list or dictionary")
+	}
+	if r.containers[n-1] < 0 {
+		b, err := r.peekByte()
+		if err != nil {
+			return false, unexpectedEOF(err)
+		}
+		if b != CHR_TERM {
+			return true, nil
+		}
+	} else if r.containers[n-1] > 0 {
+		return true, nil
+	}
+
+	_, err := r.Token()
+	return false, err
+}
+
+// Skip consumes the next value in the rencode stream without decoding it
+func (r *Decoder) Skip() error {
+	typeCode, err := r.readByte()
+	if err != nil {
+		if len(r.containers) > 0 {
+			return unexpectedEOF(err)
+		}
+		return err
+	}
+	err = r.skip(typeCode)
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+	r.tokenDone()
+	return nil
+}
+
+// DecodeListStart consumes the ListStart token of the next value, see Token;
+// false is returned if the next value is None.
+func (r *Decoder) DecodeListStart() (bool, error) {
+	tok, err := r.Token()
+	if err != nil || tok == nil {
+		return false, err
+	}
+	if _, ok := tok.(ListStart); !ok {
+		return false, fmt.Errorf("expected list but %s found", describe(tok))
+	}
+	return true, nil
+}
+
+// DecodeDictStart consumes the DictStart token of the next value, see Token;
+// false is returned if the next value is None.
+func (r *Decoder) DecodeDictStart() (bool, error) {
+	tok, err := r.Token()
+	if err != nil || tok == nil {
+		return false, err
+	}
+	if _, ok := tok.(DictStart); !ok {
+		return false, fmt.Errorf("expected dictionary but %s found", describe(tok))
+	}
+	return true, nil
+}
+
+// scalarToken returns the next token, which must not be the beginning or end of a container
+func (r *Decoder) scalarToken() (Token, error) {
+	tok, err := r.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok.(type) {
+	case ListStart, DictStart, End:
+		return nil, fmt.Errorf("expected scalar value but %s found", describe(tok))
+	}
+	return tok, nil
+}
+
+var intTypesBySize = map[int]reflect.Type{
+	8:  reflect.TypeOf(int8(0)),
+	16: reflect.TypeOf(int16(0)),
+	32: reflect.TypeOf(int32(0)),
+	64: reflect.TypeOf(int64(0)),
+}
+
+var uintTypesBySize = map[int]reflect.Type{
+	8:  reflect.TypeOf(uint8(0)),
+	16: reflect.TypeOf(uint16(0)),
+	32: reflect.TypeOf(uint32(0)),
+	64: reflect.TypeOf(uint64(0)),
+}
+
+// DecodeInt decodes the next value as a signed integer of the specified bit size (8, 16, 32 or 64);
+// None decodes as zero.
+func (r *Decoder) DecodeInt(bitSize int) (int64, error) {
+	tok, err := r.scalarToken()
+	if err != nil || tok == nil {
+		return 0, err
+	}
+	i, ok := toInt64(tok)
+	if !ok || (bitSize < 64 && (i < -1<<uint(bitSize-1) || i > 1<<uint(bitSize-1)-1)) {
+		return 0, &UnmarshalTypeError{Value: describe(tok), Type: intTypesBySize[bitSize]}
+	}
+	return i, nil
+}
+
+// DecodeUint decodes the next value as an unsigned integer of the specified bit size (8, 16, 32 or 64);
+// None decodes as zero.
+func (r *Decoder) DecodeUint(bitSize int) (uint64, error) {
+	tok, err := r.scalarToken()
+	if err != nil || tok == nil {
+		return 0, err
+	}
+	u, ok := toUint64(tok)
+	if !ok || (bitSize < 64 && u > 1<<uint(bitSize)-1) {
+		return 0, &UnmarshalTypeError{Value: describe(tok), Type: uintTypesBySize[bitSize]}
+	}
+	return u, nil
+}
+
+// DecodeFloat decodes the next numeric value as a float of the specified bit size (32 or 64);
+// None decodes as zero.
+func (r *Decoder) DecodeFloat(bitSize int) (float64, error) {
+	tok, err := r.scalarToken()
+	if err != nil || tok == nil {
+		return 0, err
+	}
+	f, ok := toFloat64(tok)
+	if !ok || (bitSize == 32 && math.Abs(f) > math.MaxFloat32 && !math.IsInf(f, 0)) {
+		if bitSize == 32 {
+			return 0, &UnmarshalTypeError{Value: describe(tok), Type: reflect.TypeOf(float32(0))}
+		}
+		return 0, &UnmarshalTypeError{Value: describe(tok), Type: reflect.TypeOf(float64(0))}
+	}
+	return f, nil
+}
+
+// DecodeBool decodes the next value as a bool; None decodes as false.
+func (r *Decoder) DecodeBool() (bool, error) {
+	tok, err := r.scalarToken()
+	if err != nil || tok == nil {
+		return false, err
+	}
+	b, ok := tok.(bool)
+	if !ok {
+		return false, &UnmarshalTypeError{Value: describe(tok), Type: reflect.TypeOf(false)}
+	}
+	return b, nil
+}
+
+// DecodeBytes decodes the next value as a byte slice; None decodes as nil.
+func (r *Decoder) DecodeBytes() ([]byte, error) {
+	tok, err := r.scalarToken()
+	if err != nil || tok == nil {
+		return nil, err
+	}
+	switch x := tok.(type) {
+	case []byte:
+		return x, nil
+	case string:
+		return []byte(x), nil
+	}
+	return nil, &UnmarshalTypeError{Value: describe(tok), Type: reflect.TypeOf([]byte(nil))}
+}
+
+// DecodeString decodes the next value as a string; None decodes as the empty string.
+func (r *Decoder) DecodeString() (string, error) {
+	tok, err := r.scalarToken()
+	if err != nil || tok == nil {
+		return "", err
+	}
+	switch x := tok.(type) {
+	case []byte:
+		return string(x), nil
+	case string:
+		return x, nil
+	}
+	return "", &UnmarshalTypeError{Value: describe(tok), Type: reflect.TypeOf("")}
+}
@@ -0,0 +1,268 @@
+package rencode
+
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type peer struct {
+	IP       string  `rencode:"ip"`
+	Progress float32 `rencode:"progress"`
+	Client   []byte  `rencode:"client,omitempty"`
+}
+
+type torrentStatus struct {
+	Name     string            `rencode:"name"`
+	Size     uint64            `rencode:"total_size"`
+	Ratio    float64           `rencode:"ratio"`
+	Paused   bool              `rencode:"paused"`
+	Peers    []peer            `rencode:"peers"`
+	Trackers map[string]int16  `rencode:"trackers"`
+	Labels   []string          `rencode:"labels,omitempty"`
+	Parent   *torrentStatus    `rencode:"parent"`
+	Hash     [4]byte           `rencode:"hash"`
+	Extra    interface{}       `rencode:"extra"`
+	Files    map[int8][]string `rencode:"files"`
+	Ignored  int               `rencode:"-"`
+	private  int
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	var extra List
+	extra.Add(int8(1))
+	extra.Add([]byte("two"))
+
+	value := torrentStatus{
+		Name:   "debian.iso",
+		Size:   1 << 40,
+		Ratio:  1.5,
+		Paused: true,
+		Peers: []peer{
+			{IP: "10.0.0.1", Progress: 0.5, Client: []byte("Deluge")},
+			{IP: "10.0.0.2", Progress: 1},
+		},
+		Trackers: map[string]int16{"udp://a": 3, "udp://b": -400},
+		Parent:   &torrentStatus{Name: "parent"},
+		Hash:     [4]byte{1, 2, 3, 4},
+		Extra:    extra,
+		Files:    map[int8][]string{1: {"a", "b"}},
+		Ignored:  5,
+		private:  6,
+	}
+
+	data, err := Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var found torrentStatus
+	err = Unmarshal(data, &found)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value.Ignored = 0
+	value.private = 0
+	if !reflect.DeepEqual(value, found) {
+		t.Fatalf("expected %+v but %+v found", value, found)
+	}
+
+	// the same data must be available through the generic decoder
+	v, err := NewDecoder(bytes.NewReader(data)).DecodeNext()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := v.(Dictionary)
+	if _, err := d.Get("Ignored"); err != ErrKeyNotFound {
+		t.Fatal("ignored field was encoded")
+	}
+	if _, err := d.Get("labels"); err != ErrKeyNotFound {
+		t.Fatal("empty field with omitempty was encoded")
+	}
+	matches, err := Lookup(d, "peers.1.ip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 || string(matches[0].Value.([]byte)) != "10.0.0.2" {
+		t.Fatalf("unexpected matches %v", matches)
+	}
+}
+
+func TestDecodeConversions(t *testing.T) {
+	var d Dictionary
+	for _, kv := range [][2]interface{}{
+		{"ip", "10.0.0.1"},
+		{"progress", int8(1)},
+		{"unknown", torrentsFixture(t)},
+		{"client", nil},
+	} {
+		err := d.Add(kv[0], kv[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := peer{Client: []byte("previous")}
+	err = Unmarshal(data, &p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.IP != "10.0.0.1" || p.Progress != 1 || p.Client != nil {
+		t.Fatalf("unexpected value %+v", p)
+	}
+
+	// decoding into generic containers
+	var generic interface{}
+	err = Unmarshal(data, &generic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dict := generic.(Dictionary)
+	if !dict.Equals(&d) {
+		t.Fatalf("unexpected generic value %v", generic)
+	}
+	var m map[string]interface{}
+	err = Unmarshal(data, &m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 4 {
+		t.Fatalf("unexpected map %v", m)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	for _, tc := range []struct {
+		value  interface{}
+		target interface{}
+	}{
+		{int16(300), new(int8)},
+		{int8(-1), new(uint)},
+		{"x", new(int)},
+		{[]interface{}{1}, new(string)},
+		{map[string]int{"a": 1}, new([]int)},
+		{true, new(float32)},
+	} {
+		data, err := Marshal(tc.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = Unmarshal(data, tc.target)
+		if err == nil {
+			t.Errorf("expected an error decoding %v into %T", tc.value, tc.target)
+			continue
+		}
+		if _, ok := err.(*UnmarshalTypeError); !ok {
+			t.Errorf("unexpected error %v", err)
+		}
+	}
+
+	var i int
+	err := NewDecoder(strings.NewReader("")).Decode(i)
+	if err == nil {
+		t.Fatal("expected an error for a non-pointer")
+	}
+}
+
+func TestDecodeScalars(t *testing.T) {
+	b := bytes.Buffer{}
+	e := NewEncoder(&b)
+	for _, v := range []interface{}{int16(300), int16(300), uint64(1) << 63, 2.5, nil, "str", true} {
+		err := e.Encode(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d := NewDecoder(&b)
+	_, err := d.DecodeInt(8)
+	if err == nil {
+		t.Fatal("expected an overflow error")
+	}
+	i, err := d.DecodeInt(16)
+	if err != nil || i != 300 {
+		t.Fatalf("unexpected value %v (%v)", i, err)
+	}
+	u, err := d.DecodeUint(64)
+	if err != nil || u != 1<<63 {
+		t.Fatalf("unexpected value %v (%v)", u, err)
+	}
+	f, err := d.DecodeFloat(64)
+	if err != nil || f != 2.5 {
+		t.Fatalf("unexpected value %v (%v)", f, err)
+	}
+	s, err := d.DecodeString()
+	if err != nil || s != "" {
+		t.Fatalf("unexpected value %v (%v)", s, err)
+	}
+	s, err = d.DecodeString()
+	if err != nil || s != "str" {
+		t.Fatalf("unexpected value %v (%v)", s, err)
+	}
+	ok, err := d.DecodeBool()
+	if err != nil || !ok {
+		t.Fatalf("unexpected value %v (%v)", ok, err)
+	}
+}
+
+type selfEncoded struct {
+	n int
+}
+
+func (s selfEncoded) EncodeRencode(e *Encoder) error {
+	return e.EncodeInt(int64(s.n) * 2)
+}
+
+func (s *selfEncoded) DecodeRencode(d *Decoder) error {
+	i, err := d.DecodeInt(64)
+	s.n = int(i) / 2
+	return err
+}
+
+func TestMarshalerUnmarshaler(t *testing.T) {
+	type wrapper struct {
+		Value   selfEncoded
+		Pointer *selfEncoded
+		Nil     *selfEncoded
+	}
+
+	data, err := Marshal(wrapper{Value: selfEncoded{3}, Pointer: &selfEncoded{4}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var w wrapper
+	err = Unmarshal(data, &w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Value.n != 3 || w.Pointer.n != 4 || w.Nil != nil {
+		t.Fatalf("unexpected value %+v", w)
+	}
+}
@@ -0,0 +1,159 @@
+package rencode
+
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+import (
+	"math/bits"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HistogramBuckets is the number of buckets of a Histogram
+const HistogramBuckets = 64
+
+// Histogram counts values in buckets of exponentially growing width:
+// bucket 0 counts zeros and bucket i counts values from 2^(i-1) to 2^i - 1
+type Histogram [HistogramBuckets]int64
+
+func (h *Histogram) add(v int64) {
+	i := bits.Len64(uint64(v))
+	if v < 0 || i >= HistogramBuckets {
+		i = HistogramBuckets - 1
+	}
+	h[i]++
+}
+
+// Count returns the number of values counted
+func (h *Histogram) Count() int64 {
+	var n int64
+	for _, c := range h {
+		n += c
+	}
+	return n
+}
+
+// Quantile returns the upper bound of the bucket holding the q-quantile of the values, q being
+// between 0 and 1; for example Quantile(0.99) is at least the 99th percentile
+func (h *Histogram) Quantile(q float64) int64 {
+	rank := int64(q * float64(h.Count()))
+	var n int64
+	for i, c := range h {
+		n += c
+		if c > 0 && n > rank {
+			return 1<<uint(i) - 1
+		}
+	}
+	return 0
+}
+
+// TypeCodeStats are the statistics collected by Metrics for the values of a typecode
+type TypeCodeStats struct {
+	TypeCode byte
+	Kind     Kind
+	// Count is the number of values, of which Errors were interrupted by an error
+	Count, Errors int64
+	// Bytes is the total size of the values, elements of lists and dictionaries included
+	Bytes int64
+	// Sizes is the histogram of the sizes of the values
+	Sizes Histogram
+	// Durations is the histogram of the time taken by top-level values, in nanoseconds
+	Durations Histogram
+}
+
+// Metrics collects statistics per typecode about the values processed by the encoders and
+// decoders using its hooks, see Hook. It is safe for concurrent use.
+type Metrics struct {
+	mu    sync.Mutex
+	stats map[byte]*TypeCodeStats
+}
+
+// Hook returns a hook feeding the collector, to be set on a single encoder or decoder with SetHook
+func (m *Metrics) Hook() Hook {
+	return &metricsHook{m: m}
+}
+
+// Stats returns the statistics of the typecodes seen so far, those taking the most bytes first
+func (m *Metrics) Stats() []TypeCodeStats {
+	m.mu.Lock()
+	stats := make([]TypeCodeStats, 0, len(m.stats))
+	for _, s := range m.stats {
+		stats = append(stats, *s)
+	}
+	m.mu.Unlock()
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Bytes != stats[j].Bytes {
+			return stats[i].Bytes > stats[j].Bytes
+		}
+		return stats[i].TypeCode < stats[j].TypeCode
+	})
+	return stats
+}
+
+// Reset discards the statistics collected so far
+func (m *Metrics) Reset() {
+	m.mu.Lock()
+	m.stats = nil
+	m.mu.Unlock()
+}
+
+// add accounts for a value; elapsed is negative for values that are not timed
+func (m *Metrics) add(info ValueInfo, err error, elapsed time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stats == nil {
+		m.stats = make(map[byte]*TypeCodeStats)
+	}
+	s := m.stats[info.TypeCode]
+	if s == nil {
+		s = &TypeCodeStats{TypeCode: info.TypeCode, Kind: info.Kind}
+		m.stats[info.TypeCode] = s
+	}
+	s.Count++
+	if err != nil {
+		s.Errors++
+	}
+	s.Bytes += info.Size
+	s.Sizes.add(info.Size)
+	if elapsed >= 0 {
+		s.Durations.add(int64(elapsed))
+	}
+}
+
+// metricsHook times the top-level values of an encoder or decoder for a Metrics
+type metricsHook struct {
+	m     *Metrics
+	start time.Time
+}
+
+func (h *metricsHook) ValueStart(info ValueInfo) {
+	if info.Depth == 0 {
+		h.start = time.Now()
+	}
+}
+
+func (h *metricsHook) ValueEnd(info ValueInfo, err error) {
+	elapsed := time.Duration(-1)
+	if info.Depth == 0 {
+		elapsed = time.Since(h.start)
+	}
+	h.m.add(info, err, elapsed)
+}
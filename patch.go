@@ -0,0 +1,298 @@
+package rencode
+
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Operation kinds produced by Diff and accepted by Patch
+const (
+	OpAdd     = "add"
+	OpRemove  = "remove"
+	OpReplace = "replace"
+)
+
+var (
+	// ErrInvalidPath is the error returned when an operation path does not address a value
+	ErrInvalidPath = errors.New("invalid operation path")
+)
+
+// Operation is a single change to a value, addressed by the path of dictionary keys and list indexes
+// leading to the changed value; an empty path addresses the root value.
+type Operation struct {
+	Op    string
+	Path  []interface{}
+	Value interface{} // not used by OpRemove
+}
+
+// Operations is a list of operations as returned by Diff
+type Operations []Operation
+
+// EncodeRencode encodes the operation as a dictionary with "op", "path" and "value" keys
+func (o Operation) EncodeRencode(e *Encoder) error {
+	var d Dictionary
+	var path List
+
+	for _, p := range o.Path {
+		path.Add(p)
+	}
+	d.Set("op", o.Op)
+	d.Set("path", path)
+	if o.Op != OpRemove {
+		d.Set("value", o.Value)
+	}
+
+	return e.Encode(d)
+}
+
+// EncodeRencode encodes the operations as a list
+func (ops Operations) EncodeRencode(e *Encoder) error {
+	var l List
+	for _, op := range ops {
+		l.Add(op)
+	}
+	return e.Encode(l)
+}
+
+// ParseOperation converts a decoded dictionary back to an Operation
+func ParseOperation(v interface{}) (Operation, error) {
+	var o Operation
+
+	d, ok := v.(Dictionary)
+	if !ok {
+		return o, fmt.Errorf("operation must be a dictionary, not %T", v)
+	}
+
+	op, err := d.Get("op")
+	if err != nil {
+		return o, fmt.Errorf("operation without kind")
+	}
+	switch x := op.(type) {
+	case []byte:
+		o.Op = string(x)
+	case string:
+		o.Op = x
+	default:
+		return o, fmt.Errorf("invalid operation kind of type %T", op)
+	}
+	if o.Op != OpAdd && o.Op != OpRemove && o.Op != OpReplace {
+		return o, fmt.Errorf("unknown operation kind %q", o.Op)
+	}
+
+	path, err := d.Get("path")
+	if err != nil {
+		return o, fmt.Errorf("operation without path")
+	}
+	l, ok := path.(List)
+	if !ok {
+		return o, fmt.Errorf("operation path must be a list, not %T", path)
+	}
+	o.Path = l.Values()
+
+	if o.Op != OpRemove {
+		o.Value, err = d.Get("value")
+		if err != nil {
+			return o, fmt.Errorf("%s operation without value", o.Op)
+		}
+	}
+
+	return o, nil
+}
+
+// ParseOperations converts a decoded list of dictionaries back to Operations
+func ParseOperations(v interface{}) (Operations, error) {
+	l, ok := v.(List)
+	if !ok {
+		return nil, fmt.Errorf("operations must be a list, not %T", v)
+	}
+
+	ops := make(Operations, l.Length())
+	for i, x := range l.Values() {
+		var err error
+		ops[i], err = ParseOperation(x)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d: %v", i, err)
+		}
+	}
+	return ops, nil
+}
+
+// Diff returns the operations that transform a into b when applied with Patch.
+// Lists and dictionaries are compared recursively while any other value is
+// compared with the same rules as Dictionary.Equals and replaced as a whole.
+func Diff(a, b interface{}) Operations {
+	var ops Operations
+	diff(a, b, nil, &ops)
+	return ops
+}
+
+func diff(a, b interface{}, path []interface{}, ops *Operations) {
+	a = derefContainer(a)
+	b = derefContainer(b)
+
+	switch x := a.(type) {
+	case Dictionary:
+		y, ok := b.(Dictionary)
+		if !ok {
+			break
+		}
+		keys := x.Keys()
+		for i, v := range x.Values() {
+			w, err := y.Get(keys[i])
+			if err != nil {
+				*ops = append(*ops, Operation{Op: OpRemove, Path: appendPath(path, keys[i])})
+				continue
+			}
+			diff(v, w, appendPath(path, keys[i]), ops)
+		}
+		keys = y.Keys()
+		for i, w := range y.Values() {
+			if _, err := x.Get(keys[i]); err != nil {
+				*ops = append(*ops, Operation{Op: OpAdd, Path: appendPath(path, keys[i]), Value: w})
+			}
+		}
+		return
+	case List:
+		y, ok := b.(List)
+		if !ok {
+			break
+		}
+		common := x.Length()
+		if y.Length() < common {
+			common = y.Length()
+		}
+		for i := 0; i < common; i++ {
+			diff(x.values[i], y.values[i], appendPath(path, i), ops)
+		}
+		for i := common; i < y.Length(); i++ {
+			*ops = append(*ops, Operation{Op: OpAdd, Path: appendPath(path, i), Value: y.values[i]})
+		}
+		// remove from the tail so that indexes stay valid while patching
+		for i := x.Length() - 1; i >= common; i-- {
+			*ops = append(*ops, Operation{Op: OpRemove, Path: appendPath(path, i)})
+		}
+		return
+	}
+
+	if !Equal(a, b) {
+		*ops = append(*ops, Operation{Op: OpReplace, Path: copyPath(path), Value: b})
+	}
+}
+
+func appendPath(path []interface{}, key interface{}) []interface{} {
+	return append(copyPath(path), key)
+}
+
+func derefContainer(v interface{}) interface{} {
+	switch x := v.(type) {
+	case *List:
+		return *x
+	case *Dictionary:
+		return *x
+	}
+	return v
+}
+
+// Patch applies the operations in order to v and returns the resulting value;
+// v is not modified, lists and dictionaries along the operation paths are copied.
+func Patch(v interface{}, ops []Operation) (interface{}, error) {
+	var err error
+	for i, op := range ops {
+		v, err = patch(v, op.Path, op)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s %v): %v", i, op.Op, op.Path, err)
+		}
+	}
+	return v, nil
+}
+
+func patch(v interface{}, path []interface{}, op Operation) (interface{}, error) {
+	if len(path) == 0 {
+		switch op.Op {
+		case OpAdd, OpReplace:
+			return op.Value, nil
+		case OpRemove:
+			return nil, errors.New("cannot remove the root value")
+		}
+		return nil, fmt.Errorf("unknown operation kind %q", op.Op)
+	}
+
+	switch x := derefContainer(v).(type) {
+	case List:
+		i, ok := intValue(path[0])
+		if !ok {
+			return nil, ErrInvalidPath
+		}
+		values := append([]interface{}(nil), x.values...)
+		if len(path) == 1 && op.Op == OpAdd {
+			if i < 0 || i > int64(len(values)) {
+				return nil, ErrInvalidPath
+			}
+			values = append(values, nil)
+			copy(values[i+1:], values[i:])
+			values[i] = op.Value
+			return List{values: values}, nil
+		}
+		if i < 0 || i >= int64(len(values)) {
+			return nil, ErrInvalidPath
+		}
+		if len(path) == 1 && op.Op == OpRemove {
+			values = append(values[:i], values[i+1:]...)
+			return List{values: values}, nil
+		}
+		child, err := patch(values[i], path[1:], op)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = child
+		return List{values: values}, nil
+	case Dictionary:
+		d := Dictionary{
+			List: List{values: append([]interface{}(nil), x.values...)},
+			keys: append([]interface{}(nil), x.keys...),
+		}
+		if len(path) == 1 {
+			switch op.Op {
+			case OpAdd:
+				d.Set(path[0], op.Value)
+				return d, nil
+			case OpRemove:
+				if !d.Delete(path[0]) {
+					return nil, ErrKeyNotFound
+				}
+				return d, nil
+			}
+		}
+		current, err := d.Get(path[0])
+		if err != nil {
+			return nil, err
+		}
+		child, err := patch(current, path[1:], op)
+		if err != nil {
+			return nil, err
+		}
+		d.Set(path[0], child)
+		return d, nil
+	}
+
+	return nil, ErrInvalidPath
+}
@@ -0,0 +1,159 @@
+package rencode
+
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDiffPatch(t *testing.T) {
+	a := torrentsFixture(t)
+	b := torrentsFixture(t)
+
+	// change a nested value, add and remove keys and grow a nested list
+	v, err := b.Get("torrents")
+	if err != nil {
+		t.Fatal(err)
+	}
+	torrents := v.(Dictionary)
+	v, err = torrents.Get("hash1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	torrent := v.(Dictionary)
+
+	torrent.Set("progress", float32(0.75))
+	torrent.Delete("name")
+	err = torrent.Add("peers", int8(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err = torrent.Get("files")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := v.(List)
+	var newFiles List
+	newFiles.Add(files.values[0])
+	newFiles.Add([]byte("NEWS"))
+	newFiles.Add([]byte("COPYING"))
+	torrent.Set("files", newFiles)
+	torrents.Set("hash1", torrent)
+	b.Set("torrents", torrents)
+	b.Delete(int8(7))
+
+	ops := Diff(a, b)
+	if len(ops) != 6 {
+		t.Fatalf("expected 6 operations but %d found: %v", len(ops), ops)
+	}
+
+	// strings and byte slices are the same value
+	if found := Diff([]byte("foo"), "foo"); len(found) != 0 {
+		t.Fatalf("expected no operations but %v found", found)
+	}
+
+	// operations must survive a round-trip on the wire
+	buf := bytes.Buffer{}
+	e := NewEncoder(&buf)
+	err = e.Encode(ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := NewDecoder(&buf)
+	decoded, err := d.DecodeNext()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops, err = ParseOperations(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	patched, err := Patch(a, ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := patched.(Dictionary)
+	if !result.Equals(&b) {
+		t.Fatalf("patched value differs from target")
+	}
+
+	// original value must not have been touched
+	if !a.Equals(torrentsFixtureValue(t)) {
+		t.Fatalf("patch modified the original value")
+	}
+
+	if remaining := Diff(result, b); len(remaining) != 0 {
+		t.Fatalf("expected no differences but %v found", remaining)
+	}
+}
+
+func torrentsFixtureValue(t *testing.T) *Dictionary {
+	d := torrentsFixture(t)
+	return &d
+}
+
+func TestDiffListShrink(t *testing.T) {
+	var a, b List
+	for i := int8(0); i < 5; i++ {
+		a.Add(i)
+	}
+	b.Add(int8(0))
+	b.Add(int8(9))
+
+	ops := Diff(a, b)
+	patched, err := Patch(a, ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := patched.(List)
+	if !l.Equals(&b) {
+		t.Fatalf("expected %v but %v found", b.Values(), l.Values())
+	}
+}
+
+func TestPatchErrors(t *testing.T) {
+	var d Dictionary
+	err := d.Add("a", int8(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, op := range []Operation{
+		{Op: OpRemove, Path: []interface{}{"b"}},
+		{Op: OpReplace, Path: []interface{}{"a", "b"}},
+		{Op: OpRemove},
+		{Op: "move", Path: nil},
+	} {
+		_, err := Patch(d, []Operation{op})
+		if err == nil {
+			t.Errorf("expected an error for operation %v", op)
+		}
+	}
+
+	replaced, err := Patch(d, []Operation{{Op: OpReplace, Value: int8(2)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replaced != int8(2) {
+		t.Fatalf("expected root replacement but %v found", replaced)
+	}
+}
@@ -0,0 +1,101 @@
+package rencode
+
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+import (
+	"io"
+	"sync"
+)
+
+// maxPooledBufferSize is the capacity above which encoder buffers are not recycled,
+// so that a single huge value does not pin memory in the pool
+const maxPooledBufferSize = 64 << 10
+
+var (
+	encoderPool = sync.Pool{New: func() interface{} { return new(Encoder) }}
+	decoderPool = sync.Pool{New: func() interface{} { return new(Decoder) }}
+)
+
+// GetEncoder returns an encoder from a shared pool, reset to write on w and with the default options.
+// It can be returned to the pool with PutEncoder once it is no longer used.
+func GetEncoder(w io.Writer) *Encoder {
+	e := encoderPool.Get().(*Encoder)
+	e.Reset(w)
+	e.manualFlush = false
+	e.timeFormat = TimeUnixFloat
+	e.hook = nil
+	return e
+}
+
+// PutEncoder returns an encoder obtained with GetEncoder to the pool; it must not be used afterwards.
+// Data that has not been flushed is discarded.
+func PutEncoder(e *Encoder) {
+	e.Reset(nil)
+	if cap(e.buf) > maxPooledBufferSize {
+		e.buf = nil
+	}
+	encoderPool.Put(e)
+}
+
+// GetDecoder returns a decoder from a shared pool, reset to read from r and with the default options.
+// It can be returned to the pool with PutDecoder once it is no longer used.
+func GetDecoder(r io.Reader) *Decoder {
+	d := decoderPool.Get().(*Decoder)
+	d.Reset(r)
+	d.utf8Mode = UTF8Bytes
+	d.maxDepth = 0
+	d.hook = nil
+	return d
+}
+
+// PutDecoder returns a decoder obtained with GetDecoder to the pool; it must not be used afterwards
+func PutDecoder(d *Decoder) {
+	d.Reset(nil)
+	decoderPool.Put(d)
+}
+
+// SyncEncoder is an encoder that can be used concurrently by multiple goroutines.
+// Each value is encoded in a separate buffer and written with a single Write call
+// while holding a lock, so that values from different goroutines are never interleaved.
+type SyncEncoder struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewSyncEncoder returns a concurrent-safe encoder that writes whole values on w
+func NewSyncEncoder(w io.Writer) *SyncEncoder {
+	return &SyncEncoder{w: w}
+}
+
+// Encode encodes data as with Encoder.Encode and writes it on the underlying writer;
+// nothing is written if encoding fails
+func (s *SyncEncoder) Encode(data interface{}) error {
+	e := GetEncoder(s.w)
+	defer PutEncoder(e)
+	e.SetAutoFlush(false)
+	err := e.Encode(data)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return e.Flush()
+}
@@ -0,0 +1,199 @@
+package rencode
+
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestEncoderDecoderReset(t *testing.T) {
+	var b1, b2 bytes.Buffer
+	e := NewEncoder(&b1)
+	err := e.Encode(int16(300))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Reset(&b2)
+	err = e.Encode("second")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDecoder(&b1)
+	// leave a container open before resetting
+	_, err = d.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Reset(strings.NewReader(string([]byte{CHR_LIST})))
+	_, err = d.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Reset(&b2)
+	v, err := d.DecodeNext()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(v.([]byte)) != "second" {
+		t.Fatalf("unexpected value %v", v)
+	}
+	_, err = d.DecodeNext()
+	if err != io.EOF {
+		t.Fatalf("expected EOF but %v found", err)
+	}
+}
+
+func TestPooledCoders(t *testing.T) {
+	var b bytes.Buffer
+	e := GetEncoder(&b)
+	err := e.Encode([]interface{}{1, "two"})
+	PutEncoder(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := GetDecoder(&b)
+	d.SetUTF8Mode(UTF8Strict)
+	var l []string
+	err = d.Decode(&l)
+	PutDecoder(d)
+	if err == nil {
+		t.Fatal("expected an error decoding an integer into a string")
+	}
+
+	// options of a recycled decoder are back to the defaults
+	d = GetDecoder(strings.NewReader(string([]byte{STR_FIXED_START + 1, 'x'})))
+	defer PutDecoder(d)
+	v, err := d.DecodeNext()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := v.([]byte); !ok {
+		t.Fatalf("unexpected value of type %T", v)
+	}
+}
+
+// chunkedWriter reports each Write call as a separate chunk
+type chunkedWriter struct {
+	chunks [][]byte
+}
+
+func (w *chunkedWriter) Write(p []byte) (int, error) {
+	w.chunks = append(w.chunks, append([]byte(nil), p...))
+	return len(p), nil
+}
+
+func TestSyncEncoder(t *testing.T) {
+	const goroutines = 32
+	const values = 100
+
+	var w chunkedWriter
+	s := NewSyncEncoder(&w)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, goroutines)
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < values; i++ {
+				var d Dictionary
+				d.Add("goroutine", g)
+				d.Add("index", i)
+				d.Add("payload", strings.Repeat("x", i))
+				err := s.Encode(d)
+				if err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	if len(w.chunks) != goroutines*values {
+		t.Fatalf("expected %d writes but %d found", goroutines*values, len(w.chunks))
+	}
+	next := make([]int64, goroutines)
+	for _, chunk := range w.chunks {
+		// each write holds exactly one value
+		d := NewDecoder(bytes.NewReader(chunk))
+		var value struct {
+			Goroutine int    `rencode:"goroutine"`
+			Index     int64  `rencode:"index"`
+			Payload   string `rencode:"payload"`
+		}
+		err := d.Decode(&value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = d.DecodeNext(); err != io.EOF {
+			t.Fatalf("trailing data in chunk %v", chunk)
+		}
+		if value.Index != next[value.Goroutine] || len(value.Payload) != int(value.Index) {
+			t.Fatalf("unexpected value %+v", value)
+		}
+		next[value.Goroutine]++
+	}
+
+	// a failed value writes nothing
+	err := s.Encode([]interface{}{1, make(chan int)})
+	if err == nil {
+		t.Fatal("expected an error for an unsupported type")
+	}
+	if len(w.chunks) != goroutines*values {
+		t.Fatal("a failed value was written")
+	}
+}
+
+func TestConcurrentMarshal(t *testing.T) {
+	var wg sync.WaitGroup
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				expected := fmt.Sprint(g, "-", i)
+				data, err := Marshal(expected)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				var found string
+				err = Unmarshal(data, &found)
+				if err != nil || found != expected {
+					t.Errorf("expected %q but %q found (%v)", expected, found, err)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
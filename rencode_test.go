@@ -23,8 +23,11 @@ import (
 	"bytes"
 	"encoding/hex"
 	"fmt"
+	"io/ioutil"
+	"math"
 	"math/big"
 	"math/rand"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -54,6 +57,78 @@ func TestFixedPosInts(t *testing.T) {
 	}
 }
 
+func TestEncodeUnsigned(t *testing.T) {
+	for _, c := range []struct {
+		value    interface{}
+		typeCode byte
+	}{
+		{uint8(math.MaxInt8), CHR_INT1},
+		{uint8(math.MaxInt8 + 1), CHR_INT2},
+		{uint8(math.MaxUint8), CHR_INT2},
+		{uint16(math.MaxInt16), CHR_INT2},
+		{uint16(math.MaxInt16 + 1), CHR_INT4},
+		{uint16(math.MaxUint16), CHR_INT4},
+		{uint32(math.MaxInt32), CHR_INT4},
+		{uint32(math.MaxInt32 + 1), CHR_INT8},
+		{uint32(math.MaxUint32), CHR_INT8},
+	} {
+		b := bytes.Buffer{}
+		e := NewEncoder(&b)
+
+		err := e.Encode(c.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b.Bytes()[0] != c.typeCode {
+			t.Fatalf("%T(%v): expected typecode %d but %d found", c.value, c.value, c.typeCode, b.Bytes()[0])
+		}
+
+		d := NewDecoder(&b)
+		found, err := d.DecodeNext()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(found) != fmt.Sprint(c.value) {
+			t.Fatalf("expected %v but %v found", c.value, found)
+		}
+	}
+
+	// reflection and typed containers go through the same path
+	type unsigned struct {
+		A uint8
+		B uint16
+		C uint32
+	}
+	in := unsigned{200, 40000, 3000000000}
+	data, err := Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out unsigned
+	err = Unmarshal(data, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != in {
+		t.Fatalf("expected %v but %v found", in, out)
+	}
+
+	var typed TypedList[uint8]
+	typed.Add(200)
+	data, err = Marshal(typed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found TypedList[uint8]
+	err = Unmarshal(data, &found)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := found.Get(0); v != 200 {
+		t.Fatalf("unexpected value %v", v)
+	}
+}
+
 func TestDecodeChar(t *testing.T) {
 	for _, value := range []int8{100, -100} {
 		b := bytes.Buffer{}
@@ -183,26 +258,136 @@ func TestDecodeBigNumber(t *testing.T) {
 
 	value.Mul(&value, big.NewInt(32))
 
+	negative := new(big.Int).Neg(&value)
+
 	b := bytes.Buffer{}
 	e := NewEncoder(&b)
 
-	err := e.Encode(value)
-	if err != nil {
-		t.Fatal(err)
+	for _, v := range []interface{}{value, negative, (*big.Int)(nil)} {
+		err := e.Encode(v)
+		if err != nil {
+			t.Fatal(err)
+		}
 	}
 
 	t.Log(hex.Dump(b.Bytes()))
 
 	d := NewDecoder(&b)
 
+	for _, expected := range []*big.Int{&value, negative} {
+		found, err := d.DecodeNext()
+		if err != nil {
+			t.Fatal(err)
+		}
+		i := found.(*big.Int)
+
+		if i.Cmp(expected) != 0 {
+			t.Fatalf("expected %v but %v found", expected, found)
+		}
+	}
+
 	found, err := d.DecodeNext()
 	if err != nil {
 		t.Fatal(err)
 	}
-	i := found.(big.Int)
+	if found != nil {
+		t.Fatalf("expected nil but %v found", found)
+	}
+}
 
-	if i.Cmp(&value) != 0 {
-		t.Fatalf("expected %v but %v found", value, found)
+func TestDecodeBigNumberFits(t *testing.T) {
+	for _, tc := range []struct {
+		encoded  string
+		expected interface{}
+	}{
+		{"18446744073709551615", ^uint64(0)},
+		{"9223372036854775808", uint64(1) << 63},
+		{"9223372036854775807", int64(math.MaxInt64)},
+		{"-9223372036854775808", int64(math.MinInt64)},
+		{"5", int64(5)},
+	} {
+		b := bytes.Buffer{}
+		e := NewEncoder(&b)
+		err := e.EncodeBigNumber(tc.encoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		found, err := NewDecoder(&b).DecodeNext()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found != tc.expected {
+			t.Fatalf("expected %T %v but %T %v found", tc.expected, tc.expected, found, found)
+		}
+	}
+
+	// a number that fits in an int64 uses the smallest representation
+	b := bytes.Buffer{}
+	e := NewEncoder(&b)
+	err := e.Encode(big.NewInt(-300))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b.Bytes(), []byte{CHR_INT2, 0xfe, 0xd4}) {
+		t.Fatalf("unexpected encoding %v", b.Bytes())
+	}
+}
+
+func TestBigNumberLength(t *testing.T) {
+	// numbers of MAX_INT_LENGTH characters or more are rejected, as by the reference implementation
+	long := new(big.Int).Exp(big.NewInt(10), big.NewInt(MAX_INT_LENGTH-1), nil)
+	if len(long.String()) != MAX_INT_LENGTH {
+		t.Fatalf("unexpected length of %v", long)
+	}
+
+	e := NewEncoder(ioutil.Discard)
+	err := e.Encode(long)
+	if err == nil {
+		t.Fatal("expected an error encoding a number of MAX_INT_LENGTH characters")
+	}
+	for _, s := range []string{long.String(), "-" + strings.Repeat("9", MAX_INT_LENGTH-1), "", "-", "12x", "+1", " 1"} {
+		err = e.EncodeBigNumber(s)
+		if err == nil {
+			t.Fatalf("expected an error encoding big number %q", s)
+		}
+	}
+	if e.Buffered() != 0 {
+		t.Fatalf("expected rejected numbers not to be written but %d bytes buffered", e.Buffered())
+	}
+
+	bigNumber := func(s string) string {
+		return string([]byte{CHR_INT}) + s + string([]byte{CHR_TERM})
+	}
+	for _, data := range []string{
+		bigNumber(long.String()),
+		bigNumber("-" + strings.Repeat("9", MAX_INT_LENGTH-1)),
+		bigNumber("12x"),
+		bigNumber(""),
+	} {
+		_, err = NewDecoder(strings.NewReader(data)).DecodeNext()
+		if err == nil {
+			t.Fatalf("expected an error decoding %q", data)
+		}
+	}
+
+	// the longest accepted number
+	s := "-" + strings.Repeat("9", MAX_INT_LENGTH-2)
+	var b bytes.Buffer
+	e = NewEncoder(&b)
+	err = e.EncodeBigNumber(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != bigNumber(s) {
+		t.Fatalf("unexpected encoding %q", b.Bytes())
+	}
+	found, err := NewDecoder(&b).DecodeNext()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.(*big.Int).String() != s {
+		t.Fatalf("unexpected value %v", found)
 	}
 }
 
@@ -669,3 +854,154 @@ func TestDictionaryCompare(t *testing.T) {
 		t.Fatal("for some reason, dictionaries that should be different are the same")
 	}
 }
+
+func TestDictionaryKeys(t *testing.T) {
+	var d Dictionary
+
+	// string and []byte keys match each other
+	err := d.Add("key", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = d.Add([]byte("key"), 2); err != ErrKeyAlreadyExists {
+		t.Fatalf("expected ErrKeyAlreadyExists but %v found", err)
+	}
+
+	// integer keys of different types are distinct, unlike with Equal
+	err = d.Add(int8(1), "int8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = d.Add(int64(1), "int64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := d.Get(int64(1)); err != nil || v != "int64" {
+		t.Fatalf("unexpected value %v (%v)", v, err)
+	}
+	if _, err = d.Get(1); err != ErrKeyNotFound {
+		t.Fatalf("expected ErrKeyNotFound but %v found", err)
+	}
+	if d.Set(int8(1), "updated") != true || d.Length() != 3 {
+		t.Fatalf("unexpected dictionary %v", d)
+	}
+
+	var l List
+	l.Add(1)
+	err = d.Add(l, "list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := d.Get(l); err != nil || v != "list" {
+		t.Fatalf("unexpected value %v (%v)", v, err)
+	}
+	if _, err = d.Get([]byte("list")); err != ErrKeyNotFound {
+		t.Fatalf("expected ErrKeyNotFound but %v found", err)
+	}
+}
+
+func TestDecodeEmptyString(t *testing.T) {
+	b := bytes.Buffer{}
+	e := NewEncoder(&b)
+
+	err := e.Encode("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDecoder(&b)
+
+	found, err := d.DecodeNext()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(found.([]byte)) != 0 {
+		t.Fatalf("expected empty string but %v found", found)
+	}
+}
+
+func TestUTF8Mode(t *testing.T) {
+	b := bytes.Buffer{}
+	e := NewEncoder(&b)
+	var d Dictionary
+	d.Add("name", "héllo")
+	d.Add("hash", []byte{0xff, 0xfe})
+	err := e.Encode(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := b.Bytes()
+
+	type value struct {
+		Name string `rencode:"name"`
+		Hash []byte `rencode:"hash"`
+	}
+
+	for _, tc := range []struct {
+		mode UTF8Mode
+		name interface{}
+		hash interface{}
+	}{
+		{UTF8Bytes, []byte("héllo"), []byte{0xff, 0xfe}},
+		{UTF8Strings, "héllo", []byte{0xff, 0xfe}},
+	} {
+		dec := NewDecoder(bytes.NewReader(data))
+		dec.SetUTF8Mode(tc.mode)
+		v, err := dec.DecodeNext()
+		if err != nil {
+			t.Fatal(err)
+		}
+		found := v.(Dictionary)
+		for i, k := range found.Keys() {
+			if _, ok := k.(string); ok != (tc.mode == UTF8Strings) {
+				t.Errorf("mode %d: unexpected key type %T", tc.mode, k)
+			}
+			expected := tc.name
+			if i == 1 {
+				expected = tc.hash
+			}
+			if !reflect.DeepEqual(found.Values()[i], expected) {
+				t.Errorf("mode %d: expected %#v but %#v found", tc.mode, expected, found.Values()[i])
+			}
+		}
+
+		// typed decoding is independent of the mode
+		dec = NewDecoder(bytes.NewReader(data))
+		dec.SetUTF8Mode(tc.mode)
+		var typed value
+		err = dec.Decode(&typed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if typed.Name != "héllo" || !bytes.Equal(typed.Hash, []byte{0xff, 0xfe}) {
+			t.Errorf("mode %d: unexpected value %+v", tc.mode, typed)
+		}
+	}
+
+	dec := NewDecoder(bytes.NewReader(data))
+	dec.SetUTF8Mode(UTF8Strict)
+	_, err = dec.DecodeNext()
+	if err == nil {
+		t.Fatal("expected an error for invalid UTF-8 in strict mode")
+	}
+
+	dec = NewDecoder(bytes.NewReader(data[:len(data)-3]))
+	dec.SetUTF8Mode(UTF8Strict)
+	tok, err := dec.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := tok.(DictStart); !ok {
+		t.Fatalf("unexpected token %v", tok)
+	}
+	for _, expected := range []string{"name", "héllo", "hash"} {
+		tok, err = dec.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tok != expected {
+			t.Fatalf("expected %q but %#v found", expected, tok)
+		}
+	}
+}
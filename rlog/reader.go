@@ -0,0 +1,310 @@
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+package rlog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/gdm85/go-rencode"
+)
+
+// Reader reads the records of a log file in order
+type Reader struct {
+	f      *os.File
+	br     *bufio.Reader
+	opts   Options
+	index  []indexEntry
+	dec    *rencode.Decoder
+	msg    bytes.Reader
+	body   []byte
+	torn   bool
+	offset int64 // offset of the next record
+	record int64 // number of the next record
+}
+
+// OpenReader opens the log file at path for reading, along with its index if any
+func OpenReader(path string, opts Options) (*Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := newReader(f, opts)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	st, err := f.Stat()
+	if err == nil {
+		r.index, err = loadIndex(path, st.Size())
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// newReader returns a reader of the records of f, positioned on the first one
+func newReader(f *os.File, opts Options) (*Reader, error) {
+	r := &Reader{f: f, br: bufio.NewReader(f), opts: opts.withDefaults()}
+	r.dec = rencode.NewDecoder(&r.msg)
+
+	var sig [len(magic)]byte
+	n, err := io.ReadFull(r.br, sig[:])
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		// the signature of a new log was not completely written
+		if string(sig[:n]) != magic[:n] {
+			return nil, ErrNotLog
+		}
+		r.torn = n > 0
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if string(sig[:]) != magic {
+		zero, err := r.zeroTail(0)
+		if err != nil {
+			return nil, err
+		}
+		if !zero {
+			return nil, ErrNotLog
+		}
+		r.torn = true
+		return r, nil
+	}
+	r.offset = int64(len(magic))
+	return r, nil
+}
+
+// Close closes the log file
+func (r *Reader) Close() error {
+	return r.f.Close()
+}
+
+// Record returns the number of the next record, starting from 0
+func (r *Reader) Record() int64 {
+	return r.record
+}
+
+// Offset returns the offset in the file of the next record
+func (r *Reader) Offset() int64 {
+	return r.offset
+}
+
+// Torn reports whether the end of the log was a record that was not completely written
+func (r *Reader) Torn() bool {
+	return r.torn
+}
+
+// tornOrCorrupt returns io.EOF if the record at the current offset, declaring a body of length bytes,
+// extends to the end of the file or is followed only by zeros up to there, marking the log as torn;
+// otherwise the record is corrupt
+func (r *Reader) tornOrCorrupt(length int64, reason string) error {
+	st, err := r.f.Stat()
+	if err != nil {
+		return err
+	}
+	torn := r.offset+frameHeaderSize+length >= st.Size()
+	if !torn {
+		// file systems can extend a file with zeros before the data appended to it is written
+		torn, err = r.zeroTail(r.offset)
+		if err != nil {
+			return err
+		}
+	}
+	if torn {
+		r.torn = true
+		return io.EOF
+	}
+	return fmt.Errorf("%w %d at offset %d: %s", ErrCorrupt, r.record, r.offset, reason)
+}
+
+// zeroTail returns true if the file holds only zeros from offset to its end
+func (r *Reader) zeroTail(offset int64) (bool, error) {
+	buf := make([]byte, 32<<10)
+	for {
+		n, err := r.f.ReadAt(buf, offset)
+		for _, c := range buf[:n] {
+			if c != 0 {
+				return false, nil
+			}
+		}
+		offset += int64(n)
+		if err == io.EOF {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+}
+
+// readFrame reads the header of the next record, returning the length and checksum of its body
+func (r *Reader) readFrame() (length int64, sum uint32, err error) {
+	var header [frameHeaderSize]byte
+	_, err = io.ReadFull(r.br, header[:])
+	if err == io.ErrUnexpectedEOF {
+		r.torn = true
+		err = io.EOF
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	length = int64(binary.BigEndian.Uint32(header[:]))
+	if length == 0 {
+		// a rencode value is at least one byte long
+		return 0, 0, r.tornOrCorrupt(length, "empty record")
+	}
+	if length > int64(r.opts.MaxRecordSize) {
+		return 0, 0, r.tornOrCorrupt(length, "record too large")
+	}
+	return length, binary.BigEndian.Uint32(header[4:]), nil
+}
+
+// next reads and verifies the body of the next record
+func (r *Reader) next() error {
+	if r.torn {
+		return io.EOF
+	}
+	length, sum, err := r.readFrame()
+	if err != nil {
+		return err
+	}
+	if int64(cap(r.body)) < length {
+		r.body = make([]byte, length)
+	}
+	r.body = r.body[:length]
+	_, err = io.ReadFull(r.br, r.body)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		r.torn = true
+		return io.EOF
+	}
+	if err != nil {
+		return err
+	}
+	if crc32.Checksum(r.body, castagnoli) != sum {
+		return r.tornOrCorrupt(length, "checksum mismatch")
+	}
+
+	r.offset += frameHeaderSize + length
+	r.record++
+	r.msg.Reset(r.body)
+	r.dec.Reset(&r.msg)
+	return nil
+}
+
+// Next returns the value of the next record, decoded as with rencode.Decoder.DecodeNext.
+// At the end of the log, including a torn tail, it returns io.EOF.
+func (r *Reader) Next() (interface{}, error) {
+	err := r.next()
+	if err != nil {
+		return nil, err
+	}
+	v, err := r.dec.DecodeNext()
+	if err != nil {
+		return nil, fmt.Errorf("%w %d: %v", ErrCorrupt, r.record-1, err)
+	}
+	err = r.checkEnd()
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Decode stores the value of the next record in the value pointed to by v, as with rencode.Decoder.Decode.
+// At the end of the log, including a torn tail, it returns io.EOF. An error that the value cannot be
+// stored in v, such as a *rencode.UnmarshalTypeError, is returned as is, since the record is valid.
+func (r *Reader) Decode(v interface{}) error {
+	err := r.next()
+	if err != nil {
+		return err
+	}
+	err = r.dec.Decode(v)
+	if err != nil {
+		if r.wellFormed() {
+			return err
+		}
+		return fmt.Errorf("%w %d: %v", ErrCorrupt, r.record-1, err)
+	}
+	return r.checkEnd()
+}
+
+// checkEnd returns an error if the value decoded from the body of the last record did not take all of it
+func (r *Reader) checkEnd() error {
+	if n := int64(len(r.body)) - r.dec.InputOffset(); n > 0 {
+		return fmt.Errorf("%w %d: %d bytes after the value", ErrCorrupt, r.record-1, n)
+	}
+	return nil
+}
+
+// wellFormed returns true if the body of the last record holds a single valid value,
+// telling a failure of Decode caused by the Go value from a malformed record
+func (r *Reader) wellFormed() bool {
+	r.msg.Reset(r.body)
+	r.dec.Reset(&r.msg)
+	_, err := r.dec.DecodeNext()
+	return err == nil && r.checkEnd() == nil
+}
+
+// SeekRecord positions the reader on record n, using the sparse index to skip most of the preceding records.
+// It returns io.EOF if the log has fewer than n records.
+func (r *Reader) SeekRecord(n int64) error {
+	start := indexEntry{record: 0, offset: int64(len(magic))}
+	if i := sort.Search(len(r.index), func(i int) bool { return r.index[i].record > n }); i > 0 {
+		start = r.index[i-1]
+	}
+	if n < r.record || start.record > r.record {
+		_, err := r.f.Seek(start.offset, io.SeekStart)
+		if err != nil {
+			return err
+		}
+		r.br.Reset(r.f)
+		r.offset, r.record, r.torn = start.offset, start.record, false
+	}
+
+	// the records are skipped without verifying their checksum
+	for r.record < n {
+		if r.torn {
+			return io.EOF
+		}
+		length, _, err := r.readFrame()
+		if err != nil {
+			return err
+		}
+		_, err = r.br.Discard(int(length))
+		if err == io.EOF {
+			r.torn = true
+		}
+		if err != nil {
+			return err
+		}
+		r.offset += frameHeaderSize + length
+		r.record++
+	}
+	return nil
+}
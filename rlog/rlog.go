@@ -0,0 +1,159 @@
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+/*
+Package rlog implements an append-only log file of rencode records.
+
+A log file starts with the 4 bytes "RLG1", followed by records made of a 4-byte big-endian
+length, the 4-byte big-endian CRC32C (Castagnoli) checksum of the body and the body itself,
+the rencode encoding of a single value.
+
+A record that was not completely written, because of a crash while appending it, can only be
+the last one of the file: Reader treats such a torn tail as the end of the log, and Open truncates
+the file at the end of the last valid record before appending to it. Since a file system can extend
+a file with zeros before writing the appended data, an invalid record followed only by zeros is torn
+too, and records with an empty body are invalid. Any other invalid record, or a record whose value
+cannot be decoded, causes an ErrCorrupt error. Likewise, a file holding an incomplete signature is
+an empty log with a torn tail.
+
+Writer maintains a sparse index in a file with the ".idx" suffix next to the log, made of
+16-byte entries holding a record number and the offset of that record, both big-endian;
+Reader uses it to seek to a record without reading all the preceding ones. The index can be
+deleted at any time and is rebuilt when the log is next opened by Open.
+*/
+package rlog
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"math"
+	"os"
+	"time"
+)
+
+const (
+	magic           = "RLG1"
+	frameHeaderSize = 8
+	indexEntrySize  = 16
+	// IndexSuffix is appended to the path of a log to obtain the path of its sparse index
+	IndexSuffix = ".idx"
+	// DefaultIndexInterval is the default number of records between two index entries
+	DefaultIndexInterval = 1024
+	// DefaultMaxRecordSize is the default maximum size of the body of a record
+	DefaultMaxRecordSize = 64 << 20
+)
+
+var (
+	// ErrCorrupt is returned when a record other than the last one is invalid
+	ErrCorrupt = errors.New("rlog: corrupt record")
+	// ErrNotLog is returned when a file does not start with the log file signature
+	ErrNotLog = errors.New("rlog: not a record log file")
+	// ErrRecordTooLarge is returned when appending a record larger than the maximum size
+	ErrRecordTooLarge = errors.New("rlog: record too large")
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// SyncPolicy selects when a Writer makes appended records durable with fsync
+type SyncPolicy int
+
+// Policies for syncing appended records to stable storage
+const (
+	// SyncOnClose syncs only when the writer is closed or Sync is called; this is the default
+	SyncOnClose SyncPolicy = iota
+	// SyncAlways syncs after each record
+	SyncAlways
+	// SyncBatch syncs after every Options.SyncEvery records
+	SyncBatch
+	// SyncInterval syncs when appending a record at least Options.SyncInterval after the last sync
+	SyncInterval
+)
+
+// Options configure a Writer or Reader; zero values select the defaults
+type Options struct {
+	Sync         SyncPolicy
+	SyncEvery    int
+	SyncInterval time.Duration
+	// IndexInterval is the number of records between two entries of the sparse index
+	IndexInterval int
+	// MaxRecordSize is the maximum size of the body of a record, at most math.MaxUint32
+	// as the length of records is stored in 4 bytes
+	MaxRecordSize int
+}
+
+// maxRecordSize is the largest length that fits in the header of a record; it is a variable
+// so that it converts to int on 32-bit platforms too, where it cannot be exceeded
+var maxRecordSize int64 = math.MaxUint32
+
+func (o Options) withDefaults() Options {
+	if o.IndexInterval <= 0 {
+		o.IndexInterval = DefaultIndexInterval
+	}
+	if o.MaxRecordSize <= 0 {
+		o.MaxRecordSize = DefaultMaxRecordSize
+	} else if int64(o.MaxRecordSize) > maxRecordSize {
+		o.MaxRecordSize = int(maxRecordSize)
+	}
+	if o.SyncEvery <= 0 {
+		o.SyncEvery = 1
+	}
+	return o
+}
+
+// indexEntry is the offset of a record in the log file
+type indexEntry struct {
+	record int64
+	offset int64
+}
+
+// loadIndex returns the entries of the index of the log at path that are consistent with a log of size bytes;
+// a missing index has no entries
+func loadIndex(path string, size int64) ([]indexEntry, error) {
+	data, err := os.ReadFile(path + IndexSuffix)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []indexEntry
+	for ; len(data) >= indexEntrySize; data = data[indexEntrySize:] {
+		e := indexEntry{
+			record: int64(binary.BigEndian.Uint64(data)),
+			offset: int64(binary.BigEndian.Uint64(data[8:])),
+		}
+		if e.offset < int64(len(magic)) || e.offset >= size || e.record < 0 {
+			break
+		}
+		if n := len(entries); n > 0 && (e.record <= entries[n-1].record || e.offset <= entries[n-1].offset) {
+			break
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func (e indexEntry) bytes() []byte {
+	var b [indexEntrySize]byte
+	binary.BigEndian.PutUint64(b[:], uint64(e.record))
+	binary.BigEndian.PutUint64(b[8:], uint64(e.offset))
+	return b[:]
+}
@@ -0,0 +1,507 @@
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+package rlog
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdm85/go-rencode"
+)
+
+type entry struct {
+	Seq  int64  `rencode:"seq"`
+	Name string `rencode:"name"`
+}
+
+func tempLog(t *testing.T) string {
+	return filepath.Join(t.TempDir(), "test.rlog")
+}
+
+func appendEntries(t *testing.T, path string, opts Options, from, to int64) {
+	w, err := Open(path, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Records() != from {
+		t.Fatalf("expected %d records but %d found", from, w.Records())
+	}
+	for i := from; i < to; i++ {
+		n, err := w.Append(entry{Seq: i, Name: strings.Repeat("x", int(i%7))})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != i {
+			t.Fatalf("expected record %d but %d appended", i, n)
+		}
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readEntries reads all records and checks that they are numbered from 0
+func readEntries(t *testing.T, path string) (*Reader, int64) {
+	r, err := OpenReader(path, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.Close() })
+	for {
+		var e entry
+		err = r.Decode(&e)
+		if err == io.EOF {
+			return r, r.Record()
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Seq != r.Record()-1 {
+			t.Fatalf("expected record %d but %d found", r.Record()-1, e.Seq)
+		}
+	}
+}
+
+// frameOffsets returns the offset of each record in the log
+func frameOffsets(t *testing.T, path string) []int64 {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var offsets []int64
+	for offset := int64(len(magic)); offset < int64(len(data)); {
+		offsets = append(offsets, offset)
+		offset += frameHeaderSize + int64(binary.BigEndian.Uint32(data[offset:]))
+	}
+	return offsets
+}
+
+func TestAppendRead(t *testing.T) {
+	path := tempLog(t)
+	opts := Options{IndexInterval: 3}
+	appendEntries(t, path, opts, 0, 10)
+	appendEntries(t, path, opts, 10, 15)
+
+	r, n := readEntries(t, path)
+	if n != 15 || r.Torn() {
+		t.Fatalf("expected 15 records but %d found (torn %v)", n, r.Torn())
+	}
+	if len(r.index) != 4 {
+		t.Errorf("expected 4 index entries but %d found", len(r.index))
+	}
+
+	r, err := OpenReader(path, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	v, err := r.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := v.(rencode.Dictionary); !ok {
+		t.Errorf("expected a dictionary but %T found", v)
+	}
+}
+
+func TestTornTail(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		damage func(data []byte, last int64) []byte
+	}{
+		{"partial header", func(data []byte, last int64) []byte { return data[:last+3] }},
+		{"partial body", func(data []byte, last int64) []byte { return data[:len(data)-2] }},
+		{"checksum mismatch", func(data []byte, last int64) []byte {
+			data[len(data)-1] ^= 0xff
+			return data
+		}},
+		{"garbage length", func(data []byte, last int64) []byte {
+			binary.BigEndian.PutUint32(data[last:], 0xffffffff)
+			return data
+		}},
+		{"zeroed record", func(data []byte, last int64) []byte {
+			return append(data[:last], make([]byte, 64)...)
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := tempLog(t)
+			appendEntries(t, path, Options{}, 0, 5)
+			offsets := frameOffsets(t, path)
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = os.WriteFile(path, tc.damage(data, offsets[4]), 0666)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r, n := readEntries(t, path)
+			if n != 4 || !r.Torn() {
+				t.Fatalf("expected 4 records and a torn tail but %d found (torn %v)", n, r.Torn())
+			}
+
+			appendEntries(t, path, Options{}, 4, 6)
+			if recovered := frameOffsets(t, path); len(recovered) != 6 || recovered[4] != offsets[4] {
+				t.Errorf("expected record 4 at offset %d after recovery but records at %v found", offsets[4], recovered)
+			}
+			r, n = readEntries(t, path)
+			if n != 6 || r.Torn() {
+				t.Fatalf("expected 6 records but %d found (torn %v)", n, r.Torn())
+			}
+		})
+	}
+}
+
+func TestZeroTail(t *testing.T) {
+	path := tempLog(t)
+	appendEntries(t, path, Options{}, 0, 5)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = f.Write(make([]byte, 64))
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// empty records are invalid, so that zeros are not read as records
+	r, n := readEntries(t, path)
+	if n != 5 || !r.Torn() {
+		t.Fatalf("expected 5 records and a torn tail but %d found (torn %v)", n, r.Torn())
+	}
+	appendEntries(t, path, Options{}, 5, 6)
+	r, n = readEntries(t, path)
+	if n != 6 || r.Torn() {
+		t.Fatalf("expected 6 records but %d found (torn %v)", n, r.Torn())
+	}
+}
+
+func TestTornSignature(t *testing.T) {
+	for _, data := range []string{"", "R", "RLG", "\x00\x00\x00\x00\x00"} {
+		path := tempLog(t)
+		err := os.WriteFile(path, []byte(data), 0666)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r, n := readEntries(t, path)
+		if n != 0 || r.Torn() != (data != "") {
+			t.Fatalf("%q: expected no records but %d found (torn %v)", data, n, r.Torn())
+		}
+
+		appendEntries(t, path, Options{}, 0, 2)
+		r, n = readEntries(t, path)
+		if n != 2 || r.Torn() {
+			t.Fatalf("%q: expected 2 records but %d found (torn %v)", data, n, r.Torn())
+		}
+	}
+}
+
+func TestCorrupt(t *testing.T) {
+	path := tempLog(t)
+	appendEntries(t, path, Options{}, 0, 5)
+	offsets := frameOffsets(t, path)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[offsets[3]-1] ^= 0xff
+	err = os.WriteFile(path, data, 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := OpenReader(path, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	for i := 0; i < 2; i++ {
+		_, err = r.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = r.Next()
+	if !errors.Is(err, ErrCorrupt) {
+		t.Errorf("expected ErrCorrupt but %v found", err)
+	}
+
+	_, err = Open(path, Options{})
+	if !errors.Is(err, ErrCorrupt) {
+		t.Errorf("expected ErrCorrupt when opening for append but %v found", err)
+	}
+}
+
+func TestSeekRecord(t *testing.T) {
+	path := tempLog(t)
+	appendEntries(t, path, Options{IndexInterval: 8}, 0, 100)
+
+	seek := func(t *testing.T, r *Reader, n int64) {
+		err := r.SeekRecord(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var e entry
+		err = r.Decode(&e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Seq != n {
+			t.Errorf("expected record %d after seek but %d found", n, e.Seq)
+		}
+	}
+	check := func(t *testing.T, entries int) {
+		r, err := OpenReader(path, Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		if len(r.index) != entries {
+			t.Errorf("expected %d index entries but %d found", entries, len(r.index))
+		}
+		for _, n := range []int64{0, 7, 8, 9, 50, 99, 3, 64, 63} {
+			seek(t, r, n)
+		}
+		err = r.SeekRecord(100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = r.Next(); err != io.EOF {
+			t.Errorf("expected io.EOF past the last record but %v found", err)
+		}
+		if err = r.SeekRecord(101); err != io.EOF {
+			t.Errorf("expected io.EOF seeking past the end but %v found", err)
+		}
+		seek(t, r, 10)
+	}
+
+	t.Run("index", func(t *testing.T) { check(t, 12) })
+
+	err := os.Remove(path + IndexSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Run("no index", func(t *testing.T) { check(t, 0) })
+
+	// an index pointing in the middle of a record is rebuilt
+	err = os.WriteFile(path+IndexSuffix, indexEntry{record: 8, offset: 9}.bytes(), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendEntries(t, path, Options{IndexInterval: 8}, 100, 100)
+	t.Run("rebuilt index", func(t *testing.T) { check(t, 12) })
+}
+
+func TestAppendErrors(t *testing.T) {
+	path := tempLog(t)
+	w, err := Open(path, Options{MaxRecordSize: 16})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	_, err = w.Append(strings.Repeat("x", 16))
+	if err != ErrRecordTooLarge {
+		t.Errorf("expected ErrRecordTooLarge but %v found", err)
+	}
+	_, err = w.Append(make(chan int))
+	if err == nil {
+		t.Error("expected an error encoding a channel")
+	}
+	_, err = w.Append("small")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Records() != 1 || w.size != int64(len(magic)+frameHeaderSize+6) {
+		t.Errorf("expected 1 record of 6 bytes but %d records and %d bytes found", w.Records(), w.size)
+	}
+}
+
+func TestDecodeCorrupt(t *testing.T) {
+	// a record with a valid checksum but an incomplete value
+	body := []byte{rencode.CHR_LIST, 1}
+	frame := make([]byte, frameHeaderSize, frameHeaderSize+len(body))
+	binary.BigEndian.PutUint32(frame, uint32(len(body)))
+	binary.BigEndian.PutUint32(frame[4:], crc32.Checksum(body, castagnoli))
+	path := tempLog(t)
+	err := os.WriteFile(path, append([]byte(magic), append(frame, body...)...), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, read := range []func(r *Reader) error{
+		func(r *Reader) error { _, err := r.Next(); return err },
+		func(r *Reader) error { var v interface{}; return r.Decode(&v) },
+	} {
+		r, err := OpenReader(path, Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = read(r)
+		r.Close()
+		if !errors.Is(err, ErrCorrupt) {
+			t.Errorf("expected ErrCorrupt but %v found", err)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	path := tempLog(t)
+	w, err := Open(path, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = w.Append(entry{Seq: 1})
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a valid record that does not fit the Go value
+	r, err := OpenReader(path, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s string
+	err = r.Decode(&s)
+	r.Close()
+	var typeErr *rencode.UnmarshalTypeError
+	if !errors.As(err, &typeErr) || errors.Is(err, ErrCorrupt) {
+		t.Errorf("expected an UnmarshalTypeError but %v found", err)
+	}
+
+	// a record with bytes after its value
+	body := []byte{rencode.CHR_TRUE, rencode.CHR_TRUE}
+	frame := make([]byte, frameHeaderSize, frameHeaderSize+len(body))
+	binary.BigEndian.PutUint32(frame, uint32(len(body)))
+	binary.BigEndian.PutUint32(frame[4:], crc32.Checksum(body, castagnoli))
+	err = os.WriteFile(path, append([]byte(magic), append(frame, body...)...), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, read := range []func(r *Reader) error{
+		func(r *Reader) error { _, err := r.Next(); return err },
+		func(r *Reader) error { var v bool; return r.Decode(&v) },
+		func(r *Reader) error { var v string; return r.Decode(&v) },
+	} {
+		r, err := OpenReader(path, Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = read(r)
+		r.Close()
+		if !errors.Is(err, ErrCorrupt) {
+			t.Errorf("expected ErrCorrupt but %v found", err)
+		}
+	}
+
+	if o := (Options{MaxRecordSize: math.MaxInt}).withDefaults(); int64(o.MaxRecordSize) > math.MaxUint32 {
+		t.Errorf("expected MaxRecordSize to be limited but %d found", o.MaxRecordSize)
+	}
+}
+
+func TestIndexWriteError(t *testing.T) {
+	path := tempLog(t)
+	w, err := Open(path, Options{IndexInterval: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the index cannot be written anymore
+	w.idx.Close()
+	for i := int64(0); i < 5; i++ {
+		_, err = w.Append(entry{Seq: i})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the index is rebuilt when the log is opened again
+	appendEntries(t, path, Options{IndexInterval: 2}, 5, 6)
+	r, n := readEntries(t, path)
+	if n != 6 || len(r.index) != 2 {
+		t.Fatalf("expected 6 records and 2 index entries but %d and %d found", n, len(r.index))
+	}
+}
+
+func TestNotLog(t *testing.T) {
+	path := tempLog(t)
+	err := os.WriteFile(path, []byte("not a log"), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Open(path, Options{})
+	if err != ErrNotLog {
+		t.Errorf("expected ErrNotLog but %v found", err)
+	}
+	_, err = OpenReader(path, Options{})
+	if err != ErrNotLog {
+		t.Errorf("expected ErrNotLog but %v found", err)
+	}
+}
+
+func TestSyncPolicies(t *testing.T) {
+	for _, tc := range []struct {
+		opts     Options
+		unsynced []int
+	}{
+		{Options{}, []int{1, 2, 3, 4}},
+		{Options{Sync: SyncAlways}, []int{0, 0, 0, 0}},
+		{Options{Sync: SyncBatch, SyncEvery: 3}, []int{1, 2, 0, 1}},
+		{Options{Sync: SyncInterval, SyncInterval: time.Hour}, []int{1, 2, 3, 4}},
+		{Options{Sync: SyncInterval, SyncInterval: time.Nanosecond}, []int{0, 0, 0, 0}},
+	} {
+		w, err := Open(tempLog(t), tc.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, expected := range tc.unsynced {
+			time.Sleep(time.Microsecond)
+			_, err = w.Append(i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if w.unsynced != expected {
+				t.Errorf("%+v: expected %d unsynced records after %d appends but %d found", tc.opts, expected, i+1, w.unsynced)
+			}
+		}
+		err = w.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
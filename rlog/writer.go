@@ -0,0 +1,240 @@
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+package rlog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"os"
+	"time"
+
+	"github.com/gdm85/go-rencode"
+)
+
+// Writer appends records to a log file
+type Writer struct {
+	f        *os.File
+	idx      *os.File
+	opts     Options
+	buf      bytes.Buffer
+	enc      rencode.Encoder
+	size     int64
+	records  int64
+	unsynced int
+	lastSync time.Time
+}
+
+// Open opens the log file at path for appending, creating it if it does not exist.
+// A torn tail left by an interrupted append is truncated and the sparse index is
+// rebuilt if it does not match the log.
+func Open(path string, opts Options) (*Writer, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return nil, err
+	}
+	w := &Writer{f: f, opts: opts.withDefaults(), lastSync: time.Now()}
+	w.enc = rencode.NewEncoder(&w.buf)
+	err = w.recover(path)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// scan verifies the records of the log starting from the entry of index at position i, or from the
+// first record if i is -1; it returns the index entries for the valid records and the reader
+// positioned after the last one of them
+func (w *Writer) scan(index []indexEntry, i int) ([]indexEntry, *Reader, error) {
+	_, err := w.f.Seek(0, io.SeekStart)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := newReader(w.f, w.opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	var entries []indexEntry
+	if i >= 0 {
+		r.index = index[:i+1]
+		entries = append(entries, r.index...)
+		err = r.SeekRecord(index[i].record)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for {
+		offset, record := r.offset, r.record
+		err = r.next()
+		if err == io.EOF {
+			return entries, r, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if record > 0 && record%int64(w.opts.IndexInterval) == 0 && (len(entries) == 0 || entries[len(entries)-1].record < record) {
+			entries = append(entries, indexEntry{record: record, offset: offset})
+		}
+	}
+}
+
+// recover positions the writer after the last valid record of the log, truncating a torn tail,
+// and rewrites the index with the entries it should contain
+func (w *Writer) recover(path string) error {
+	st, err := w.f.Stat()
+	if err != nil {
+		return err
+	}
+	index, err := loadIndex(path, st.Size())
+	if err != nil {
+		return err
+	}
+
+	// records up to the last index entry are trusted; if the log does not continue
+	// cleanly from there, the index is discarded and all records are verified
+	entries, r, err := w.scan(index, len(index)-1)
+	if len(index) > 0 && (err != nil || r.Torn()) {
+		entries, r, err = w.scan(nil, -1)
+	}
+	if err != nil {
+		return err
+	}
+	if r.Torn() || r.offset == 0 {
+		err = w.f.Truncate(r.offset)
+		if err != nil {
+			return err
+		}
+	}
+	if r.offset == 0 {
+		// a new log, or one whose signature was not completely written
+		_, err = w.f.WriteAt([]byte(magic), 0)
+		if err != nil {
+			return err
+		}
+		r.offset = int64(len(magic))
+	}
+	_, err = w.f.Seek(r.offset, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	w.size, w.records = r.offset, r.record
+
+	w.idx, err = os.OpenFile(path+IndexSuffix, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	var b []byte
+	for _, e := range entries {
+		b = append(b, e.bytes()...)
+	}
+	_, err = w.idx.Write(b)
+	if err != nil {
+		w.idx.Close()
+	}
+	return err
+}
+
+// Records returns the number of records in the log
+func (w *Writer) Records() int64 {
+	return w.records
+}
+
+// Append encodes v with a rencode.Encoder and appends it as a new record, returning its number.
+// Nothing is written if encoding fails.
+func (w *Writer) Append(v interface{}) (int64, error) {
+	w.buf.Reset()
+	w.buf.Write(make([]byte, frameHeaderSize))
+	err := w.enc.Encode(v)
+	if err != nil {
+		return 0, err
+	}
+	frame := w.buf.Bytes()
+	body := frame[frameHeaderSize:]
+	if len(body) > w.opts.MaxRecordSize {
+		return 0, ErrRecordTooLarge
+	}
+	binary.BigEndian.PutUint32(frame, uint32(len(body)))
+	binary.BigEndian.PutUint32(frame[4:], crc32.Checksum(body, castagnoli))
+
+	_, err = w.f.Write(frame)
+	if err != nil {
+		// do not leave a partial record that following ones would turn into a corrupt one
+		if w.f.Truncate(w.size) == nil {
+			w.f.Seek(w.size, io.SeekStart)
+		}
+		return 0, err
+	}
+
+	record := w.records
+	if w.idx != nil && record > 0 && record%int64(w.opts.IndexInterval) == 0 {
+		_, idxErr := w.idx.Write(indexEntry{record: record, offset: w.size}.bytes())
+		if idxErr != nil {
+			// the index is only an optimization, rebuilt by the next Open; it is no longer
+			// updated so that a partially written entry is its last one
+			w.idx.Close()
+			w.idx = nil
+		}
+	}
+	w.size += int64(len(frame))
+	w.records++
+	w.unsynced++
+
+	switch w.opts.Sync {
+	case SyncAlways:
+		err = w.Sync()
+	case SyncBatch:
+		if w.unsynced >= w.opts.SyncEvery {
+			err = w.Sync()
+		}
+	case SyncInterval:
+		if time.Since(w.lastSync) >= w.opts.SyncInterval {
+			err = w.Sync()
+		}
+	}
+	return record, err
+}
+
+// Sync commits the appended records to stable storage
+func (w *Writer) Sync() error {
+	err := w.f.Sync()
+	if err != nil {
+		return err
+	}
+	w.unsynced = 0
+	w.lastSync = time.Now()
+	return nil
+}
+
+// Close syncs and closes the log file and its index
+func (w *Writer) Close() error {
+	err := w.Sync()
+	if w.idx != nil {
+		if err2 := w.idx.Close(); err == nil {
+			err = err2
+		}
+	}
+	if err2 := w.f.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
@@ -0,0 +1,202 @@
+package rencode
+
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/rpc"
+)
+
+// rpcHeader is the header preceding the body of each request and response;
+// a failed call has an error and a None body. The sequence number is signed so that it is
+// encoded as a plain integer rather than as a big number, as uint64 values are.
+type rpcHeader struct {
+	Method string `rencode:"method"`
+	Seq    int64  `rencode:"seq"`
+	Error  string `rencode:"error,omitempty"`
+}
+
+type rpcCodec struct {
+	conn io.ReadWriteCloser
+	enc  Encoder
+	dec  *Decoder
+}
+
+func newRPCCodec(conn io.ReadWriteCloser) *rpcCodec {
+	c := &rpcCodec{conn: conn, enc: NewEncoder(conn), dec: NewDecoder(conn)}
+	// each message is written with a single call once both header and body are encoded
+	c.enc.SetAutoFlush(false)
+	return c
+}
+
+// encode encodes a message in the buffer of the encoder; nothing is left there if encoding fails
+func (c *rpcCodec) encode(h *rpcHeader, body interface{}) error {
+	err := c.enc.Encode(h)
+	if err == nil {
+		err = c.enc.Encode(body)
+	}
+	if err != nil {
+		c.enc.Reset(c.conn)
+	}
+	return err
+}
+
+func (c *rpcCodec) readHeader(h *rpcHeader) error {
+	*h = rpcHeader{}
+	err := c.dec.Decode(h)
+	if err == nil && h.Seq < 0 {
+		err = fmt.Errorf("invalid sequence number %d", h.Seq)
+	}
+	return err
+}
+
+// headerSeq returns the sequence number of net/rpc as stored in headers
+func headerSeq(seq uint64) (int64, error) {
+	if seq > math.MaxInt64 {
+		return 0, fmt.Errorf("sequence number %d out of range", seq)
+	}
+	return int64(seq), nil
+}
+
+func (c *rpcCodec) readBody(body interface{}) error {
+	if body == nil {
+		return c.dec.Skip()
+	}
+	start := c.dec.InputOffset()
+	err := c.dec.Decode(body)
+	if err != nil {
+		// a body that does not fit the Go value only fails its call, so that the next
+		// message is read from its start
+		c.skipBody(start)
+	}
+	return err
+}
+
+// skipBody consumes what is left of a body whose decoding failed after reading from start;
+// the failed decoding stopped between tokens, possibly within lists and dictionaries
+func (c *rpcCodec) skipBody(start int64) {
+	if c.dec.InputOffset() == start {
+		c.dec.Skip()
+		return
+	}
+	for len(c.dec.containers) > 0 {
+		more, err := c.dec.More()
+		if err == nil && more {
+			err = c.dec.Skip()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (c *rpcCodec) Close() error {
+	return c.conn.Close()
+}
+
+type clientCodec struct {
+	*rpcCodec
+	header rpcHeader
+}
+
+// NewClientCodec returns a net/rpc client codec exchanging rencode messages on conn.
+// Each message is a dictionary with "method", "seq" and, for failed calls, "error" keys,
+// followed by the request or response body.
+func NewClientCodec(conn io.ReadWriteCloser) rpc.ClientCodec {
+	return &clientCodec{rpcCodec: newRPCCodec(conn)}
+}
+
+func (c *clientCodec) WriteRequest(r *rpc.Request, body interface{}) error {
+	seq, err := headerSeq(r.Seq)
+	if err != nil {
+		return err
+	}
+	err = c.encode(&rpcHeader{Method: r.ServiceMethod, Seq: seq}, body)
+	if err != nil {
+		return err
+	}
+	return c.enc.Flush()
+}
+
+func (c *clientCodec) ReadResponseHeader(r *rpc.Response) error {
+	err := c.readHeader(&c.header)
+	if err != nil {
+		return err
+	}
+	r.ServiceMethod = c.header.Method
+	r.Seq = uint64(c.header.Seq)
+	r.Error = c.header.Error
+	return nil
+}
+
+func (c *clientCodec) ReadResponseBody(body interface{}) error {
+	return c.readBody(body)
+}
+
+type serverCodec struct {
+	*rpcCodec
+	header rpcHeader
+}
+
+// NewServerCodec returns a net/rpc server codec exchanging rencode messages on conn,
+// see NewClientCodec for the format of messages.
+func NewServerCodec(conn io.ReadWriteCloser) rpc.ServerCodec {
+	return &serverCodec{rpcCodec: newRPCCodec(conn)}
+}
+
+func (c *serverCodec) ReadRequestHeader(r *rpc.Request) error {
+	err := c.readHeader(&c.header)
+	if err != nil {
+		return err
+	}
+	r.ServiceMethod = c.header.Method
+	r.Seq = uint64(c.header.Seq)
+	return nil
+}
+
+func (c *serverCodec) ReadRequestBody(body interface{}) error {
+	return c.readBody(body)
+}
+
+func (c *serverCodec) WriteResponse(r *rpc.Response, body interface{}) error {
+	seq, err := headerSeq(r.Seq)
+	if err != nil {
+		return err
+	}
+	h := rpcHeader{Method: r.ServiceMethod, Seq: seq, Error: r.Error}
+	if r.Error != "" {
+		body = nil
+	}
+	err = c.encode(&h, body)
+	if err != nil {
+		// the client is still waiting for a response
+		h.Error = err.Error()
+		if c.encode(&h, nil) != nil {
+			return err
+		}
+	}
+	flushErr := c.enc.Flush()
+	if err == nil {
+		err = flushErr
+	}
+	return err
+}
@@ -0,0 +1,170 @@
+package rencode
+
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+import (
+	"errors"
+	"net"
+	"net/rpc"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type ArithArgs struct {
+	A int `rencode:"a"`
+	B int `rencode:"b"`
+}
+
+type ArithReply struct {
+	Product  int      `rencode:"product"`
+	Operands []string `rencode:"operands"`
+}
+
+type Arith struct{}
+
+func (Arith) Multiply(args ArithArgs, reply *ArithReply) error {
+	reply.Product = args.A * args.B
+	reply.Operands = []string{"a", "b"}
+	return nil
+}
+
+func (Arith) Divide(args ArithArgs, reply *int) error {
+	if args.B == 0 {
+		return errors.New("divide by zero")
+	}
+	*reply = args.A / args.B
+	return nil
+}
+
+func (Arith) Unencodable(args ArithArgs, reply *interface{}) error {
+	*reply = make(chan int)
+	return nil
+}
+
+func newRPCPipe(t *testing.T) *rpc.Client {
+	server := rpc.NewServer()
+	err := server.Register(Arith{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverConn, clientConn := net.Pipe()
+	go server.ServeCodec(NewServerCodec(serverConn))
+	return rpc.NewClientWithCodec(NewClientCodec(clientConn))
+}
+
+func TestRPC(t *testing.T) {
+	client := newRPCPipe(t)
+	defer client.Close()
+
+	var reply ArithReply
+	err := client.Call("Arith.Multiply", ArithArgs{A: 7, B: 6}, &reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.Product != 42 || len(reply.Operands) != 2 {
+		t.Fatalf("unexpected reply %+v", reply)
+	}
+
+	var quotient int
+	err = client.Call("Arith.Divide", ArithArgs{A: 7, B: 0}, &quotient)
+	if err == nil || err.Error() != "divide by zero" {
+		t.Fatalf("unexpected error %v", err)
+	}
+	err = client.Call("Arith.Missing", ArithArgs{}, &quotient)
+	if err == nil || !strings.Contains(err.Error(), "can't find method") {
+		t.Fatalf("unexpected error %v", err)
+	}
+	var v interface{}
+	err = client.Call("Arith.Unencodable", ArithArgs{}, &v)
+	if err == nil || !strings.Contains(err.Error(), "could not encode") {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// a request body that does not fit the arguments fails its call only
+	err = client.Call("Arith.Multiply", struct {
+		A string `rencode:"a"`
+		B int    `rencode:"b"`
+	}{A: "seven", B: 6}, &reply)
+	if err == nil || !strings.Contains(err.Error(), "cannot decode") {
+		t.Fatalf("unexpected error %v", err)
+	}
+	reply = ArithReply{}
+	err = client.Call("Arith.Multiply", ArithArgs{A: 2, B: 3}, &reply)
+	if err != nil || reply.Product != 6 {
+		t.Fatalf("unexpected reply %+v (%v)", reply, err)
+	}
+
+	// the connection is still usable after failed calls, also concurrently
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var quotient int
+			err := client.Call("Arith.Divide", ArithArgs{A: i * 3, B: 3}, &quotient)
+			if err != nil || quotient != i {
+				t.Errorf("expected %d but %d found (%v)", i, quotient, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestRPCWireFormat(t *testing.T) {
+	// a client in another language only needs to exchange plain rencode values
+	server := rpc.NewServer()
+	err := server.Register(Arith{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverConn, conn := net.Pipe()
+	defer conn.Close()
+	go server.ServeCodec(NewServerCodec(serverConn))
+
+	var header, body Dictionary
+	header.Add("method", "Arith.Divide")
+	header.Add("seq", 9)
+	body.Add("a", 10)
+	body.Add("b", 2)
+	e := NewEncoder(conn)
+	go func() {
+		e.Encode(header)
+		e.Encode(body)
+	}()
+
+	d := NewDecoder(conn)
+	var response map[string]interface{}
+	err = d.Decode(&response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(response["method"].([]byte)) != "Arith.Divide" || !Equal(response["seq"], 9) || response["error"] != nil {
+		t.Fatalf("unexpected response header %v", response)
+	}
+	// the sequence number is a plain integer, which a big number would not decode to
+	if _, ok := response["seq"].(int8); !ok {
+		t.Fatalf("unexpected sequence number of type %T", response["seq"])
+	}
+	result, err := d.DecodeNext()
+	if err != nil || !Equal(result, 5) {
+		t.Fatalf("unexpected result %v (%v)", result, err)
+	}
+}
@@ -0,0 +1,806 @@
+package rencode
+
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// SchemaType is the type of the values accepted by a Schema
+type SchemaType int
+
+// Types of schemas
+const (
+	// SchemaAny accepts any value
+	SchemaAny SchemaType = iota
+	SchemaNone
+	SchemaBool
+	// SchemaInt accepts integers of any width
+	SchemaInt
+	// SchemaFloat accepts float32 and float64 values
+	SchemaFloat
+	// SchemaNumber accepts integers and floats
+	SchemaNumber
+	// SchemaBytes accepts any string
+	SchemaBytes
+	// SchemaString accepts strings that are valid UTF-8
+	SchemaString
+	SchemaList
+	SchemaDict
+	// SchemaUnion accepts values accepted by any of the Union schemas
+	SchemaUnion
+)
+
+var schemaTypeNames = [...]string{
+	SchemaAny:    "any",
+	SchemaNone:   "none",
+	SchemaBool:   "bool",
+	SchemaInt:    "int",
+	SchemaFloat:  "float",
+	SchemaNumber: "number",
+	SchemaBytes:  "bytes",
+	SchemaString: "string",
+	SchemaList:   "list",
+	SchemaDict:   "dict",
+	SchemaUnion:  "union",
+}
+
+func (t SchemaType) String() string {
+	if t < 0 || int(t) >= len(schemaTypeNames) {
+		return "invalid"
+	}
+	return schemaTypeNames[t]
+}
+
+// Schema describes the values expected in a decoded rencode value; a nil *Schema accepts any value.
+// Schemas can be built in Go, see ListOf, DictOf and OneOf, or loaded from a description with ParseSchema,
+// encoding/json or Unmarshal.
+type Schema struct {
+	Type SchemaType
+	// Min and Max are the inclusive bounds of numbers, of any numeric type other than NaN; nil when unbounded
+	Min, Max interface{}
+	// MinLength and MaxLength bound the length of strings and the number of elements of lists and dictionaries;
+	// a zero MaxLength means no maximum
+	MinLength, MaxLength int
+	// Elem is the schema of the elements of a list
+	Elem *Schema
+	// Fields are the known keys of a dictionary
+	Fields []SchemaField
+	// Values is the schema of the values of the keys of a dictionary that are not in Fields
+	Values *Schema
+	// Strict rejects the keys of a dictionary that are not in Fields
+	Strict bool
+	// Union are the alternatives of a SchemaUnion
+	Union []*Schema
+}
+
+// SchemaField is a known key of a dictionary, matched against string keys
+type SchemaField struct {
+	Key      string
+	Schema   *Schema
+	Optional bool
+}
+
+// ListOf returns the schema of a list whose elements are accepted by elem
+func ListOf(elem *Schema) *Schema {
+	return &Schema{Type: SchemaList, Elem: elem}
+}
+
+// DictOf returns the schema of a dictionary with the specified fields
+func DictOf(fields ...SchemaField) *Schema {
+	return &Schema{Type: SchemaDict, Fields: fields}
+}
+
+// OneOf returns the schema of a union of the specified alternatives
+func OneOf(alternatives ...*Schema) *Schema {
+	return &Schema{Type: SchemaUnion, Union: alternatives}
+}
+
+// Field returns a required dictionary field
+func Field(key string, s *Schema) SchemaField {
+	return SchemaField{Key: key, Schema: s}
+}
+
+// OptionalField returns a dictionary field that may be missing
+func OptionalField(key string, s *Schema) SchemaField {
+	return SchemaField{Key: key, Schema: s, Optional: true}
+}
+
+// Range sets the inclusive bounds of numbers and returns the schema; a nil bound leaves it unbounded.
+// It panics if a bound is not a number or is NaN.
+func (s *Schema) Range(min, max interface{}) *Schema {
+	for _, err := range []error{checkBound("minimum", min), checkBound("maximum", max)} {
+		if err != nil {
+			panic("rencode: Schema.Range " + err.Error())
+		}
+	}
+	s.Min, s.Max = min, max
+	return s
+}
+
+// checkBound returns an error if v is neither nil nor a number that can be compared with others
+func checkBound(which string, v interface{}) error {
+	if v == nil {
+		return nil
+	}
+	if rankOf(normalize(v)) != rankNumber {
+		return fmt.Errorf("%s %v of type %T is not a number", which, v, v)
+	}
+	if f, ok := floatValue(v); ok && math.IsNaN(f) {
+		return fmt.Errorf("%s is NaN", which)
+	}
+	return nil
+}
+
+// Length sets the bounds of the length of strings, lists and dictionaries and returns the schema;
+// a zero max means no maximum
+func (s *Schema) Length(min, max int) *Schema {
+	s.MinLength, s.MaxLength = min, max
+	return s
+}
+
+// accepts returns whether values of the specified kind can be accepted by the schema
+func (s *Schema) accepts(kind Kind) bool {
+	if s == nil {
+		return true
+	}
+	switch s.Type {
+	case SchemaAny:
+		return true
+	case SchemaNone:
+		return kind == KindNone
+	case SchemaBool:
+		return kind == KindBool
+	case SchemaInt:
+		return kind == KindInt
+	case SchemaFloat:
+		return kind == KindFloat
+	case SchemaNumber:
+		return kind == KindInt || kind == KindFloat
+	case SchemaBytes, SchemaString:
+		return kind == KindString
+	case SchemaList:
+		return kind == KindList
+	case SchemaDict:
+		return kind == KindDict
+	case SchemaUnion:
+		for _, alt := range s.Union {
+			if alt.accepts(kind) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// typeName describes the values accepted by the schema, listing the alternatives of unions
+func (s *Schema) typeName() string {
+	if s == nil {
+		return SchemaAny.String()
+	}
+	if s.Type != SchemaUnion {
+		return s.Type.String()
+	}
+	names := make([]string, len(s.Union))
+	for i, alt := range s.Union {
+		names[i] = alt.typeName()
+	}
+	return strings.Join(names, " or ")
+}
+
+// kindOfValue returns the kind of a decoded value
+func kindOfValue(v interface{}) Kind {
+	switch rankOf(normalize(v)) {
+	case rankNone:
+		return KindNone
+	case rankBool:
+		return KindBool
+	case rankNumber:
+		if _, ok := floatValue(v); ok {
+			return KindFloat
+		}
+		return KindInt
+	case rankString:
+		return KindString
+	case rankList:
+		return KindList
+	case rankDict:
+		return KindDict
+	}
+	return KindInvalid
+}
+
+// SchemaViolation is a part of a value that does not conform to a schema;
+// Path is its location, as in Match, and for a missing key ends with the key.
+type SchemaViolation struct {
+	Path    []interface{}
+	Message string
+}
+
+// String returns the violation prefixed with its path, in the syntax of ParseSelector
+func (v SchemaViolation) String() string {
+	if len(v.Path) == 0 {
+		return "(root): " + v.Message
+	}
+	parts := make([]string, len(v.Path))
+	for i, p := range v.Path {
+		if key, ok := toString(p); ok {
+			parts[i] = escapePathKey(key)
+		} else {
+			parts[i] = fmt.Sprint(p)
+		}
+	}
+	return strings.Join(parts, ".") + ": " + v.Message
+}
+
+// SchemaError is the error returned when a value does not conform to a schema, listing all violations
+type SchemaError struct {
+	Violations []SchemaViolation
+}
+
+func (e *SchemaError) Error() string {
+	parts := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		parts[i] = v.String()
+	}
+	return "schema violation: " + strings.Join(parts, "; ")
+}
+
+// schemaValidator collects the violations found while validating a value
+type schemaValidator struct {
+	violations []SchemaViolation
+	// invalid is the error about the schema itself, when one of its bounds is not a number
+	invalid error
+}
+
+func (c *schemaValidator) report(path []interface{}, format string, args ...interface{}) {
+	c.violations = append(c.violations, SchemaViolation{Path: copyPath(path), Message: fmt.Sprintf(format, args...)})
+}
+
+func (c *schemaValidator) err() error {
+	if c.invalid != nil {
+		return c.invalid
+	}
+	if len(c.violations) == 0 {
+		return nil
+	}
+	return &SchemaError{Violations: c.violations}
+}
+
+// Validate checks that v, as returned by DecodeNext, conforms to the schema and
+// returns a *SchemaError listing all violations otherwise. An error that is not a *SchemaError
+// is returned for a schema whose Min or Max is not a number.
+func (s *Schema) Validate(v interface{}) error {
+	var c schemaValidator
+	c.value(s, v, nil)
+	return c.err()
+}
+
+func (c *schemaValidator) value(s *Schema, v interface{}, path []interface{}) {
+	if s == nil || s.Type == SchemaAny {
+		return
+	}
+	if s.Type == SchemaUnion {
+		c.union(s, v, path)
+		return
+	}
+	v = normalize(v)
+	kind := kindOfValue(v)
+	if !s.accepts(kind) {
+		c.report(path, "expected %s but %s found", s.typeName(), kind)
+		return
+	}
+
+	switch x := v.(type) {
+	case List:
+		for i, e := range x.values {
+			c.value(s.Elem, e, append(path, i))
+		}
+		c.length(s, x.Length(), path)
+	case Dictionary:
+		seen := make([]bool, len(s.Fields))
+		for i, k := range x.keys {
+			if vs, ok := c.keySchema(s, seen, k, path); ok {
+				c.value(vs, x.values[i], append(path, k))
+			}
+		}
+		c.length(s, x.Length(), path)
+		c.missing(s, seen, path)
+	default:
+		c.scalar(s, v, path)
+	}
+}
+
+// union accepts v when any alternative does; otherwise the violations of the only alternative
+// accepting the kind of v are reported, if there is one
+func (c *schemaValidator) union(s *Schema, v interface{}, path []interface{}) {
+	kind := kindOfValue(normalize(v))
+	var candidates [][]SchemaViolation
+	for _, alt := range s.Union {
+		var sub schemaValidator
+		sub.value(alt, v, path)
+		if sub.invalid != nil {
+			c.invalid = sub.invalid
+			return
+		}
+		if len(sub.violations) == 0 {
+			return
+		}
+		if alt.accepts(kind) {
+			candidates = append(candidates, sub.violations)
+		}
+	}
+	if len(candidates) == 1 {
+		c.violations = append(c.violations, candidates[0]...)
+		return
+	}
+	c.report(path, "expected %s but %s found", s.typeName(), kind)
+}
+
+func (c *schemaValidator) scalar(s *Schema, v interface{}, path []interface{}) {
+	switch s.Type {
+	case SchemaString, SchemaBytes:
+		data := stringBytes(v)
+		if s.Type == SchemaString && !utf8.Valid(data) {
+			c.report(path, "invalid UTF-8 string %q", data)
+		}
+		c.length(s, len(data), path)
+	case SchemaInt, SchemaFloat, SchemaNumber:
+		if s.Min == nil && s.Max == nil {
+			return
+		}
+		// Range rejects these bounds, but Min and Max can be set directly
+		for _, err := range []error{checkBound("minimum", s.Min), checkBound("maximum", s.Max)} {
+			if err != nil && c.invalid == nil {
+				c.invalid = fmt.Errorf("invalid schema: %v", err)
+			}
+		}
+		if c.invalid != nil {
+			return
+		}
+		if f, ok := floatValue(v); ok && math.IsNaN(f) {
+			c.report(path, "NaN is out of range")
+			return
+		}
+		if s.Min != nil && compareNumbers(v, s.Min) < 0 {
+			c.report(path, "%v is less than the minimum %v", v, s.Min)
+		}
+		if s.Max != nil && compareNumbers(v, s.Max) > 0 {
+			c.report(path, "%v is greater than the maximum %v", v, s.Max)
+		}
+	}
+}
+
+func (c *schemaValidator) length(s *Schema, n int, path []interface{}) {
+	if n < s.MinLength {
+		c.report(path, "length %d is less than the minimum %d", n, s.MinLength)
+	}
+	if s.MaxLength > 0 && n > s.MaxLength {
+		c.report(path, "length %d is greater than the maximum %d", n, s.MaxLength)
+	}
+}
+
+// keySchema returns the schema of the value of a dictionary key and marks its field as seen;
+// false is returned for a key that the schema rejects, which is reported
+func (c *schemaValidator) keySchema(s *Schema, seen []bool, key interface{}, path []interface{}) (*Schema, bool) {
+	if name, ok := toString(key); ok {
+		for i, f := range s.Fields {
+			if f.Key == name {
+				seen[i] = true
+				return f.Schema, true
+			}
+		}
+	}
+	if s.Strict {
+		c.report(append(path, key), "unexpected key")
+		return nil, false
+	}
+	return s.Values, true
+}
+
+func (c *schemaValidator) missing(s *Schema, seen []bool, path []interface{}) {
+	for i, f := range s.Fields {
+		if !seen[i] && !f.Optional {
+			c.report(append(path, f.Key), "missing required key")
+		}
+	}
+}
+
+// Validate decodes the next value from the rencode stream and checks that it conforms to the schema,
+// returning a *SchemaError listing all violations otherwise. Lists and dictionaries are validated
+// while they are read, without being decoded as a whole, and parts accepted by a nil or SchemaAny
+// schema are skipped; the value is decoded only for unions. See DecodeValid to also obtain the value.
+// If no more objects are available, an io.EOF error will be returned.
+func (r *Decoder) Validate(s *Schema) error {
+	_, err := r.validateNext(s, false)
+	return err
+}
+
+// DecodeValid returns the next available object stored in the rencode stream, as DecodeNext does,
+// checking while it is read that it conforms to the schema. If it does not, the value is returned
+// along with a *SchemaError listing all violations.
+// If no more objects are available, an io.EOF error will be returned.
+func (r *Decoder) DecodeValid(s *Schema) (interface{}, error) {
+	return r.validateNext(s, true)
+}
+
+// validateNext validates the next value, decoding it if keep is true
+func (r *Decoder) validateNext(s *Schema, keep bool) (interface{}, error) {
+	typeCode, err := r.readByte()
+	if err != nil {
+		if len(r.containers) > 0 {
+			return nil, unexpectedEOF(err)
+		}
+		return nil, err
+	}
+
+	var c schemaValidator
+	v, err := r.validateValue(&c, s, typeCode, nil, keep)
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+
+	r.tokenDone()
+	return v, c.err()
+}
+
+// validateValue validates the value identified by typeCode; it is decoded and returned
+// if keep is true, otherwise the parts that need not be decoded are skipped
+func (r *Decoder) validateValue(c *schemaValidator, s *Schema, typeCode byte, path []interface{}, keep bool) (interface{}, error) {
+	if s == nil || s.Type == SchemaAny {
+		if keep {
+			return r.decode(typeCode)
+		}
+		return nil, r.skip(typeCode)
+	}
+
+	kind := kindOf(typeCode)
+	if s.Type == SchemaUnion || kind != KindList && kind != KindDict {
+		v, err := r.decode(typeCode)
+		if err != nil {
+			return nil, err
+		}
+		c.value(s, v, path)
+		return v, nil
+	}
+	if !s.accepts(kind) {
+		c.report(path, "expected %s but %s found", s.typeName(), kind)
+		if keep {
+			return r.decode(typeCode)
+		}
+		return nil, r.skip(typeCode)
+	}
+
+	n := 0
+	if kind == KindList {
+		var l List
+		err := r.eachElement(typeCode, func(elementCode byte) error {
+			v, err := r.validateValue(c, s.Elem, elementCode, append(path, n), keep)
+			if keep {
+				l.Add(v)
+			}
+			n++
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		c.length(s, n, path)
+		if keep {
+			return l, nil
+		}
+		return nil, nil
+	}
+
+	var d Dictionary
+	seen := make([]bool, len(s.Fields))
+	err := r.eachElement(typeCode, func(keyCode byte) error {
+		key, err := r.decode(keyCode)
+		if err != nil {
+			return err
+		}
+		valueCode, err := r.readByte()
+		if err != nil {
+			return err
+		}
+		if valueCode == CHR_TERM {
+			return fmt.Errorf("incomplete key-value pair in dictionary data")
+		}
+		n++
+		var v interface{}
+		vs, ok := c.keySchema(s, seen, key, path)
+		switch {
+		case ok:
+			v, err = r.validateValue(c, vs, valueCode, append(path, key), keep)
+		case keep:
+			v, err = r.decode(valueCode)
+		default:
+			err = r.skip(valueCode)
+		}
+		if err != nil || !keep {
+			return err
+		}
+		return d.addDecoded(key, v)
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.length(s, n, path)
+	c.missing(s, seen, path)
+	if keep {
+		return d, nil
+	}
+	return nil, nil
+}
+
+// ParseSchema builds a schema from its description, made of values as returned by DecodeNext or encoding/json.
+// A description can be:
+// * the name of a type, as returned by SchemaType.String, except "union"
+// * a list of descriptions, for the union of them
+// * a dictionary with a "type" key naming the type and these other keys, all optional
+// * "min" and "max", for the bounds of numbers
+// * "min_length" and "max_length", for the bounds of lengths
+// * "elem", the description of the elements of a list
+// * "fields", a dictionary of the descriptions of the known keys of a dictionary
+// * "optional", true in the dictionary description of a field that may be missing
+// * "values", the description of the values of the keys of a dictionary that are not in "fields"
+// * "strict", true to reject the keys of a dictionary that are not in "fields"
+// * "of", the list of the descriptions of the alternatives of a union
+func ParseSchema(desc interface{}) (*Schema, error) {
+	s, _, err := parseSchema(desc, nil)
+	return s, err
+}
+
+// UnmarshalJSON loads the schema from its JSON description, see ParseSchema
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	d := json.NewDecoder(strings.NewReader(string(data)))
+	d.UseNumber()
+	var desc interface{}
+	err := d.Decode(&desc)
+	if err != nil {
+		return err
+	}
+	parsed, err := ParseSchema(desc)
+	if err != nil {
+		return err
+	}
+	*s = *parsed
+	return nil
+}
+
+// DecodeRencode loads the schema from its rencode description, see ParseSchema
+func (s *Schema) DecodeRencode(d *Decoder) error {
+	desc, err := d.DecodeNext()
+	if err != nil {
+		return err
+	}
+	parsed, err := ParseSchema(desc)
+	if err != nil {
+		return err
+	}
+	*s = *parsed
+	return nil
+}
+
+// schemaDescriptionError is an invalid part of a schema description
+func schemaDescriptionError(path []interface{}, format string, args ...interface{}) error {
+	return fmt.Errorf("invalid schema description: %s", SchemaViolation{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// descriptionPairs returns the (key, value) pairs of a dictionary description, keys being strings
+func descriptionPairs(desc interface{}, path []interface{}) ([]string, []interface{}, error) {
+	switch x := normalize(desc).(type) {
+	case Dictionary:
+		keys := make([]string, len(x.keys))
+		for i, k := range x.keys {
+			key, ok := toString(k)
+			if !ok {
+				return nil, nil, schemaDescriptionError(path, "key %v is not a string", k)
+			}
+			keys[i] = key
+		}
+		return keys, x.values, nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		values := make([]interface{}, len(keys))
+		for i, k := range keys {
+			values[i] = x[k]
+		}
+		return keys, values, nil
+	}
+	return nil, nil, schemaDescriptionError(path, "expected a dictionary but %T found", desc)
+}
+
+// descriptionList returns the elements of a list description
+func descriptionList(desc interface{}) ([]interface{}, bool) {
+	switch x := normalize(desc).(type) {
+	case List:
+		return x.values, true
+	case []interface{}:
+		return x, true
+	}
+	return nil, false
+}
+
+// descriptionNumber returns a number of a description, converting the numbers of encoding/json
+func descriptionNumber(v interface{}, path []interface{}) (interface{}, error) {
+	if n, ok := v.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			return i, nil
+		}
+		if i, ok := new(big.Int).SetString(string(n), 10); ok {
+			return i, nil
+		}
+		return n.Float64()
+	}
+	if rankOf(normalize(v)) != rankNumber {
+		return nil, schemaDescriptionError(path, "expected a number but %T found", v)
+	}
+	return normalize(v), nil
+}
+
+func descriptionInt(v interface{}, path []interface{}) (int, error) {
+	n, err := descriptionNumber(v, path)
+	if err != nil {
+		return 0, err
+	}
+	if f, ok := floatValue(n); ok && f == math.Trunc(f) && f >= 0 && f <= math.MaxInt32 {
+		return int(f), nil
+	}
+	if i, ok := intValue(n); ok && i >= 0 && i <= math.MaxInt32 {
+		return int(i), nil
+	}
+	return 0, schemaDescriptionError(path, "invalid length %v", v)
+}
+
+func descriptionBool(v interface{}, path []interface{}) (bool, error) {
+	b, ok := v.(bool)
+	if !ok {
+		return false, schemaDescriptionError(path, "expected a bool but %T found", v)
+	}
+	return b, nil
+}
+
+func parseSchemaType(v interface{}, path []interface{}) (SchemaType, error) {
+	name, ok := toString(v)
+	if !ok {
+		return 0, schemaDescriptionError(path, "expected a type name but %T found", v)
+	}
+	for t, n := range schemaTypeNames {
+		if n == name {
+			return SchemaType(t), nil
+		}
+	}
+	return 0, schemaDescriptionError(path, "unknown type %s", strconv.Quote(name))
+}
+
+// parseSchema parses a description, returning whether it is an optional field
+func parseSchema(desc interface{}, path []interface{}) (*Schema, bool, error) {
+	if _, ok := toString(desc); ok {
+		t, err := parseSchemaType(desc, path)
+		if err == nil && t == SchemaUnion {
+			err = schemaDescriptionError(path, "union without alternatives")
+		}
+		return &Schema{Type: t}, false, err
+	}
+	if alternatives, ok := descriptionList(desc); ok {
+		s, err := parseUnion(alternatives, path)
+		return s, false, err
+	}
+
+	keys, values, err := descriptionPairs(desc, path)
+	if err != nil {
+		return nil, false, err
+	}
+	s := &Schema{}
+	optional := false
+	hasType := false
+	for i, key := range keys {
+		v := values[i]
+		at := append(path, key)
+		switch key {
+		case "type":
+			s.Type, err = parseSchemaType(v, at)
+			hasType = true
+		case "min":
+			s.Min, err = descriptionNumber(v, at)
+		case "max":
+			s.Max, err = descriptionNumber(v, at)
+		case "min_length":
+			s.MinLength, err = descriptionInt(v, at)
+		case "max_length":
+			s.MaxLength, err = descriptionInt(v, at)
+		case "elem":
+			s.Elem, _, err = parseSchema(v, at)
+		case "values":
+			s.Values, _, err = parseSchema(v, at)
+		case "strict":
+			s.Strict, err = descriptionBool(v, at)
+		case "optional":
+			optional, err = descriptionBool(v, at)
+		case "fields":
+			s.Fields, err = parseFields(v, at)
+		case "of":
+			alternatives, ok := descriptionList(v)
+			if !ok {
+				return nil, false, schemaDescriptionError(at, "expected a list but %T found", v)
+			}
+			var u *Schema
+			u, err = parseUnion(alternatives, at)
+			if err == nil {
+				s.Union = u.Union
+			}
+		default:
+			err = schemaDescriptionError(path, "unknown key %s", strconv.Quote(key))
+		}
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	if !hasType {
+		return nil, false, schemaDescriptionError(path, "missing type")
+	}
+	if s.Type == SchemaUnion && len(s.Union) == 0 {
+		return nil, false, schemaDescriptionError(path, "union without alternatives")
+	}
+	return s, optional, nil
+}
+
+func parseUnion(alternatives []interface{}, path []interface{}) (*Schema, error) {
+	if len(alternatives) == 0 {
+		return nil, schemaDescriptionError(path, "union without alternatives")
+	}
+	s := &Schema{Type: SchemaUnion, Union: make([]*Schema, len(alternatives))}
+	for i, alt := range alternatives {
+		var err error
+		s.Union[i], _, err = parseSchema(alt, append(path, i))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func parseFields(desc interface{}, path []interface{}) ([]SchemaField, error) {
+	keys, values, err := descriptionPairs(desc, path)
+	if err != nil {
+		return nil, err
+	}
+	fields := make([]SchemaField, len(keys))
+	for i, key := range keys {
+		fields[i].Key = key
+		fields[i].Schema, fields[i].Optional, err = parseSchema(values[i], append(path, key))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return fields, nil
+}
@@ -0,0 +1,322 @@
+package rencode
+
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// peersSchema returns the schema of a peer list response, as built in Go
+func peersSchema() *Schema {
+	return DictOf(
+		Field("peers", ListOf(DictOf(
+			Field("ip", &Schema{Type: SchemaBytes}),
+			Field("progress", (&Schema{Type: SchemaFloat}).Range(0, 1)),
+			OptionalField("client", &Schema{Type: SchemaString}),
+		)).Length(0, 50)),
+		Field("state", OneOf((&Schema{Type: SchemaInt}).Range(0, 5), &Schema{Type: SchemaString})),
+		OptionalField("ratio", &Schema{Type: SchemaNumber}),
+	)
+}
+
+const peersSchemaJSON = `{
+	"type": "dict",
+	"fields": {
+		"peers": {
+			"type": "list",
+			"max_length": 50,
+			"elem": {
+				"type": "dict",
+				"fields": {
+					"ip": "bytes",
+					"progress": {"type": "float", "min": 0, "max": 1},
+					"client": {"type": "string", "optional": true}
+				}
+			}
+		},
+		"state": [{"type": "int", "min": 0, "max": 5}, "string"],
+		"ratio": {"type": "number", "optional": true}
+	}
+}`
+
+func peersValue(peers ...interface{}) Dictionary {
+	return NewDictionary("peers", NewList(peers...), "state", 3)
+}
+
+func schemaViolations(err error) []string {
+	if err == nil {
+		return nil
+	}
+	var found []string
+	for _, v := range err.(*SchemaError).Violations {
+		found = append(found, v.String())
+	}
+	return found
+}
+
+// checkSchema validates v both in memory and from its encoding, which is also decoded with DecodeValid
+func checkSchema(t *testing.T, s *Schema, v interface{}, expected ...string) {
+	t.Helper()
+	check := func(how string, err error) {
+		found := schemaViolations(err)
+		if strings.Join(found, "\n") != strings.Join(expected, "\n") {
+			t.Errorf("%s: expected violations %q but %q found", how, expected, found)
+		}
+	}
+	check("in memory", s.Validate(v))
+
+	var b bytes.Buffer
+	e := NewEncoder(&b)
+	err := e.Encode(v)
+	if err == nil {
+		err = e.Encode("trailer")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := b.Bytes()
+	d := NewDecoder(bytes.NewReader(data))
+	check("streaming", d.Validate(s))
+	trailer, err := d.DecodeNext()
+	if err != nil || string(trailer.([]byte)) != "trailer" {
+		t.Errorf("streaming: value not entirely consumed, %v (%v) found after it", trailer, err)
+	}
+
+	original, err := NewDecoder(bytes.NewReader(data)).DecodeNext()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d = NewDecoder(bytes.NewReader(data))
+	decoded, err := d.DecodeValid(s)
+	check("decoding", err)
+	if !Equal(decoded, original) {
+		t.Errorf("decoding: expected %v but %v found", original, decoded)
+	}
+	trailer, err = d.DecodeNext()
+	if err != nil || string(trailer.([]byte)) != "trailer" {
+		t.Errorf("decoding: value not entirely consumed, %v (%v) found after it", trailer, err)
+	}
+}
+
+func TestSchemaPeers(t *testing.T) {
+	var fromJSON Schema
+	err := json.Unmarshal([]byte(peersSchemaJSON), &fromJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the rencode description is the JSON one, numbers included
+	d := json.NewDecoder(strings.NewReader(peersSchemaJSON))
+	d.UseNumber()
+	var desc interface{}
+	err = d.Decode(&desc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := Marshal(desc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromRencode Schema
+	err = Unmarshal(data, &fromRencode)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := peersValue(
+		NewDictionary("ip", "1.2.3.4", "progress", 0.5),
+		NewDictionary("ip", []byte("::1"), "progress", float32(1), "client", "qBittorrent"),
+	)
+	invalid := NewDictionary(
+		"peers", NewList(
+			NewDictionary("ip", 7, "progress", 1.5),
+			NewDictionary("progress", 0.1, "client", []byte{0xff}),
+		),
+		"state", 9,
+		"ratio", "high",
+	)
+	for name, s := range map[string]*Schema{"go": peersSchema(), "json": &fromJSON, "rencode": &fromRencode} {
+		t.Run(name, func(t *testing.T) {
+			checkSchema(t, s, valid)
+			checkSchema(t, s, invalid,
+				"peers.0.ip: expected bytes but int found",
+				"peers.0.progress: 1.5 is greater than the maximum 1",
+				`peers.1.client: invalid UTF-8 string "\xff"`,
+				"peers.1.ip: missing required key",
+				"state: 9 is greater than the maximum 5",
+				"ratio: expected number but string found",
+			)
+			checkSchema(t, s, NewList(), "(root): expected dict but list found")
+		})
+	}
+}
+
+func TestSchemaUnion(t *testing.T) {
+	s := OneOf(&Schema{Type: SchemaInt}, &Schema{Type: SchemaNone})
+	checkSchema(t, s, 1)
+	checkSchema(t, s, nil)
+	checkSchema(t, s, "x", "(root): expected int or none but string found")
+
+	ranges := OneOf((&Schema{Type: SchemaInt}).Range(0, 1), (&Schema{Type: SchemaInt}).Range(10, 11))
+	checkSchema(t, ranges, 10)
+	checkSchema(t, ranges, 5, "(root): expected int or int but int found")
+
+	nested := ListOf(OneOf(&Schema{Type: SchemaBytes}, DictOf(Field("id", &Schema{Type: SchemaInt}))))
+	checkSchema(t, nested, NewList("a", NewDictionary("id", 1)))
+	checkSchema(t, nested, NewList(NewDictionary("id", "1")), "0.id: expected int but string found")
+}
+
+func TestSchemaBounds(t *testing.T) {
+	big70 := new(big.Int).Lsh(big.NewInt(1), 70)
+	ints := (&Schema{Type: SchemaInt}).Range(-1, big70)
+	checkSchema(t, ints, uint64(math.MaxUint64))
+	checkSchema(t, ints, big70)
+	checkSchema(t, ints, new(big.Int).Add(big70, big.NewInt(1)), "(root): 1180591620717411303425 is greater than the maximum 1180591620717411303424")
+	checkSchema(t, ints, int8(-2), "(root): -2 is less than the minimum -1")
+	checkSchema(t, ints, 1.0, "(root): expected int but float found")
+
+	floats := (&Schema{Type: SchemaNumber}).Range(nil, 0.5)
+	checkSchema(t, floats, math.Inf(-1))
+	checkSchema(t, floats, math.NaN(), "(root): NaN is out of range")
+
+	strs := (&Schema{Type: SchemaString}).Length(1, 3)
+	checkSchema(t, strs, "abc")
+	checkSchema(t, strs, "", "(root): length 0 is less than the minimum 1")
+	checkSchema(t, strs, "abcd", "(root): length 4 is greater than the maximum 3")
+
+	lists := ListOf(nil).Length(1, 0)
+	checkSchema(t, lists, NewList(make([]interface{}, 100)...))
+	checkSchema(t, lists, NewList(), "(root): length 0 is less than the minimum 1")
+
+	dicts := (&Schema{Type: SchemaDict}).Length(0, 1)
+	checkSchema(t, dicts, NewDictionary("a", 1, "b", 2), "(root): length 2 is greater than the maximum 1")
+}
+
+func TestSchemaDictKeys(t *testing.T) {
+	strict := DictOf(Field("a", &Schema{Type: SchemaInt}))
+	strict.Strict = true
+	checkSchema(t, strict, NewDictionary("a", 1))
+	checkSchema(t, strict, NewDictionary("a", 1, "b.c", NewList(), 3, 4),
+		`b\.c: unexpected key`,
+		"3: unexpected key",
+	)
+
+	values := &Schema{Type: SchemaDict, Fields: []SchemaField{Field("name", &Schema{Type: SchemaString})}, Values: &Schema{Type: SchemaInt}}
+	checkSchema(t, values, NewDictionary("name", "x", "count", 1))
+	checkSchema(t, values, NewDictionary("name", 2, "count", "1"),
+		"name: expected string but int found",
+		"count: expected int but string found",
+	)
+
+	// keys are not checked when Values is nil
+	checkSchema(t, DictOf(), NewDictionary(1, 2, "x", NewList()))
+}
+
+func TestSchemaInvalidBounds(t *testing.T) {
+	for _, bounds := range [][2]interface{}{{"0", nil}, {nil, []byte("1")}, {math.NaN(), nil}, {0, true}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Range(%v, %v): expected a panic", bounds[0], bounds[1])
+				}
+			}()
+			(&Schema{Type: SchemaInt}).Range(bounds[0], bounds[1])
+		}()
+	}
+
+	// bounds set directly are rejected when a number is validated
+	s := ListOf(OneOf(&Schema{Type: SchemaInt, Max: "10"}, &Schema{Type: SchemaString}))
+	v := NewList("a", 1)
+	const expected = `invalid schema: maximum 10 of type string is not a number`
+	if err := s.Validate(v); err == nil || err.Error() != expected {
+		t.Errorf("expected error %q but %v found", expected, err)
+	}
+	var b bytes.Buffer
+	e := NewEncoder(&b)
+	err := e.Encode(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = NewDecoder(&b).DecodeValid(s)
+	if _, ok := err.(*SchemaError); ok || err == nil || err.Error() != expected {
+		t.Errorf("expected error %q but %v found", expected, err)
+	}
+}
+
+func TestDecodeValid(t *testing.T) {
+	var b bytes.Buffer
+	e := NewEncoder(&b)
+	err := e.Encode(NewDictionary("a", NewList(1, "x"), "b", 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := DictOf(Field("a", ListOf(&Schema{Type: SchemaInt})))
+	s.Strict = true
+	v, err := NewDecoder(&b).DecodeValid(s)
+	found := schemaViolations(err)
+	expected := []string{`a.1: expected int but string found`, `b: unexpected key`}
+	if strings.Join(found, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected violations %q but %q found", expected, found)
+	}
+	// the value is returned along with the violations, including the rejected parts
+	if !Equal(v, NewDictionary("a", NewList(1, "x"), "b", 2)) {
+		t.Errorf("unexpected value %v", v)
+	}
+}
+
+func TestDecoderValidateErrors(t *testing.T) {
+	d := NewDecoder(bytes.NewReader(nil))
+	if err := d.Validate(nil); err != io.EOF {
+		t.Errorf("expected io.EOF but %v found", err)
+	}
+
+	// a truncated list is an error rather than a violation
+	d = NewDecoder(bytes.NewReader([]byte{CHR_LIST, 1}))
+	err := d.Validate(ListOf(&Schema{Type: SchemaInt}))
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("expected io.ErrUnexpectedEOF but %v found", err)
+	}
+}
+
+func TestParseSchemaErrors(t *testing.T) {
+	for desc, expected := range map[string]string{
+		`{"type": "flaot"}`: `invalid schema description: type: unknown type "flaot"`,
+		`{"type": "dict", "fields": {"a": {"type": "int", "mni": 1}}}`: `invalid schema description: fields.a: unknown key "mni"`,
+		`{"min": 1}`:                             `invalid schema description: (root): missing type`,
+		`[]`:                                     `invalid schema description: (root): union without alternatives`,
+		`{"type": "list", "elem": ["int", 3]}`:   `invalid schema description: elem.1: expected a dictionary but json.Number found`,
+		`{"type": "string", "max_length": -1}`:   `invalid schema description: max_length: invalid length -1`,
+		`{"type": "string", "min_length": -3.0}`: `invalid schema description: min_length: invalid length -3.0`,
+		`{"type": "union", "of": []}`:            `invalid schema description: of: union without alternatives`,
+		`{"type": "dict", "fields": {"a": "int"}, "strict": "yes"}`: `invalid schema description: strict: expected a bool but string found`,
+	} {
+		var s Schema
+		err := json.Unmarshal([]byte(desc), &s)
+		if err == nil || err.Error() != expected {
+			t.Errorf("%s: expected error %q but %v found", desc, expected, err)
+		}
+	}
+}
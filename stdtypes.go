@@ -0,0 +1,203 @@
+package rencode
+
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"net/url"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// TimeFormat is the representation used to encode time.Time values
+type TimeFormat int
+
+// Representations of time.Time values
+const (
+	// TimeUnixFloat encodes times as a float64 number of seconds since the Unix epoch,
+	// as returned by Python's time.time(); this is the default
+	TimeUnixFloat TimeFormat = iota
+	// TimeUnix encodes times as an integer number of seconds since the Unix epoch
+	TimeUnix
+	// TimeRFC3339 encodes times as RFC 3339 strings with nanoseconds, when not zero
+	TimeRFC3339
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	ipType       = reflect.TypeOf(net.IP{})
+	urlType      = reflect.TypeOf(url.URL{})
+	numberType   = reflect.TypeOf(json.Number(""))
+)
+
+// SetTimeFormat selects the representation of the time.Time values encoded by Encode and EncodeTime
+func (r *Encoder) SetTimeFormat(format TimeFormat) {
+	r.timeFormat = format
+}
+
+// EncodeTime encodes a time.Time value as selected with SetTimeFormat
+func (r *Encoder) EncodeTime(t time.Time) error {
+	switch r.timeFormat {
+	case TimeUnix:
+		return r.EncodeInt(t.Unix())
+	case TimeRFC3339:
+		return r.EncodeString(t.Format(time.RFC3339Nano))
+	}
+	return r.EncodeFloat64(float64(t.Unix()) + float64(t.Nanosecond())/1e9)
+}
+
+// EncodeDuration encodes a time.Duration value as a number of seconds; whole seconds are encoded
+// as an integer and any other duration as a float64
+func (r *Encoder) EncodeDuration(d time.Duration) error {
+	if d%time.Second == 0 {
+		return r.EncodeInt(int64(d / time.Second))
+	}
+	return r.EncodeFloat64(d.Seconds())
+}
+
+// EncodeNumber encodes a numeric string as an integer when possible, otherwise as a float64
+func (r *Encoder) EncodeNumber(n json.Number) error {
+	if i, err := strconv.ParseInt(string(n), 10, 64); err == nil {
+		return r.EncodeInt(i)
+	}
+	if x, ok := new(big.Int).SetString(string(n), 10); ok {
+		return r.EncodeBigInt(x)
+	}
+	f, err := strconv.ParseFloat(string(n), 64)
+	if err != nil {
+		return fmt.Errorf("invalid number %q", n)
+	}
+	return r.EncodeFloat64(f)
+}
+
+// assignStandard stores v in dst when dst is one of the standard types with a built-in conversion,
+// reporting whether it is one of them
+func assignStandard(dst reflect.Value, v interface{}) (bool, error) {
+	switch dst.Type() {
+	case timeType:
+		t, err := toTime(v)
+		if err == nil {
+			dst.Set(reflect.ValueOf(t))
+		}
+		return true, err
+	case durationType:
+		d, err := toDuration(v)
+		if err == nil {
+			dst.SetInt(int64(d))
+		}
+		return true, err
+	case ipType:
+		if s, ok := toString(v); ok {
+			ip := net.ParseIP(s)
+			if ip == nil {
+				return true, fmt.Errorf("invalid IP address %q", s)
+			}
+			dst.Set(reflect.ValueOf(ip))
+			return true, nil
+		}
+	case urlType:
+		if s, ok := toString(v); ok {
+			u, err := url.Parse(s)
+			if err == nil {
+				dst.Set(reflect.ValueOf(*u))
+			}
+			return true, err
+		}
+	case numberType:
+		n, ok := toNumber(v)
+		if ok {
+			dst.SetString(n)
+			return true, nil
+		}
+	default:
+		return false, nil
+	}
+	return true, &UnmarshalTypeError{Value: describe(v), Type: dst.Type()}
+}
+
+// toString returns the content of a decoded string
+func toString(v interface{}) (string, bool) {
+	switch x := v.(type) {
+	case []byte:
+		return string(x), true
+	case string:
+		return x, true
+	}
+	return "", false
+}
+
+// toTime converts a number of seconds since the Unix epoch or an RFC 3339 string to a UTC time
+func toTime(v interface{}) (time.Time, error) {
+	if s, ok := toString(v); ok {
+		return time.Parse(time.RFC3339Nano, s)
+	}
+	if i, ok := toInt64(v); ok {
+		return time.Unix(i, 0).UTC(), nil
+	}
+	if f, ok := toFloat64(v); ok && f >= math.MinInt64 && f < math.MaxInt64 {
+		sec := math.Floor(f)
+		return time.Unix(int64(sec), int64(math.Round((f-sec)*1e9))).UTC(), nil
+	}
+	return time.Time{}, &UnmarshalTypeError{Value: describe(v), Type: timeType}
+}
+
+// toDuration converts a number of seconds to a duration
+func toDuration(v interface{}) (time.Duration, error) {
+	if i, ok := toInt64(v); ok {
+		if i >= math.MinInt64/int64(time.Second) && i <= math.MaxInt64/int64(time.Second) {
+			return time.Duration(i) * time.Second, nil
+		}
+	} else if f, ok := toFloat64(v); ok {
+		ns := math.Round(f * 1e9)
+		if ns >= math.MinInt64 && ns < math.MaxInt64 {
+			return time.Duration(ns), nil
+		}
+	}
+	return 0, &UnmarshalTypeError{Value: describe(v), Type: durationType}
+}
+
+// toNumber returns the decimal representation of a decoded number or numeric string
+func toNumber(v interface{}) (string, bool) {
+	switch x := v.(type) {
+	case float32:
+		return strconv.FormatFloat(float64(x), 'g', -1, 32), !math.IsInf(float64(x), 0) && !math.IsNaN(float64(x))
+	case float64:
+		return strconv.FormatFloat(x, 'g', -1, 64), !math.IsInf(x, 0) && !math.IsNaN(x)
+	case *big.Int:
+		return x.String(), true
+	}
+	if i, ok := toInt64(v); ok {
+		return strconv.FormatInt(i, 10), true
+	}
+	if u, ok := toUint64(v); ok {
+		return strconv.FormatUint(u, 10), true
+	}
+	if s, ok := toString(v); ok {
+		// a valid JSON value starting with a minus sign or a digit is a number
+		return s, s != "" && (s[0] == '-' || '0' <= s[0] && s[0] <= '9') && json.Valid([]byte(s))
+	}
+	return "", false
+}
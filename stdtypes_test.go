@@ -0,0 +1,176 @@
+package rencode
+
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestEncodeTime(t *testing.T) {
+	when := time.Date(2015, 3, 14, 9, 26, 53, 500000000, time.UTC)
+	for _, test := range []struct {
+		format   TimeFormat
+		expected interface{}
+	}{
+		{TimeUnixFloat, 1426325213.5},
+		{TimeUnix, int64(1426325213)},
+		{TimeRFC3339, "2015-03-14T09:26:53.5Z"},
+	} {
+		var b bytes.Buffer
+		e := NewEncoder(&b)
+		e.SetTimeFormat(test.format)
+		err := e.Encode(when)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, err := Marshal(test.expected)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b.Bytes(), expected) {
+			t.Errorf("format %d: expected %v but %v found", test.format, expected, b.Bytes())
+		}
+
+		var found time.Time
+		err = Unmarshal(b.Bytes(), &found)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.format == TimeUnix {
+			if !found.Equal(when.Truncate(time.Second)) {
+				t.Errorf("format %d: expected %v but %v decoded", test.format, when, found)
+			}
+		} else if !found.Equal(when) {
+			t.Errorf("format %d: expected %v but %v decoded", test.format, when, found)
+		}
+	}
+}
+
+func TestEncodeDuration(t *testing.T) {
+	for _, test := range []struct {
+		d        time.Duration
+		expected interface{}
+	}{
+		{90 * time.Second, int8(90)},
+		{-2 * time.Hour, int16(-7200)},
+		{1500 * time.Millisecond, 1.5},
+	} {
+		data, err := Marshal(test.d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, _ := Marshal(test.expected)
+		if !bytes.Equal(data, expected) {
+			t.Errorf("%v: expected %v but %v found", test.d, expected, data)
+		}
+		var found time.Duration
+		err = Unmarshal(data, &found)
+		if err != nil || found != test.d {
+			t.Errorf("expected %v but %v decoded (%v)", test.d, found, err)
+		}
+	}
+
+	// durations beyond the range of time.Duration
+	data, _ := Marshal(int64(1) << 40)
+	var d time.Duration
+	if err := Unmarshal(data, &d); err == nil {
+		t.Fatal("expected an overflow error")
+	}
+}
+
+func TestStandardTypes(t *testing.T) {
+	type standard struct {
+		IP      net.IP        `rencode:"ip"`
+		URL     *url.URL      `rencode:"url"`
+		Number  json.Number   `rencode:"number"`
+		Big     json.Number   `rencode:"big"`
+		Float   json.Number   `rencode:"float"`
+		Created time.Time     `rencode:"created"`
+		Timeout time.Duration `rencode:"timeout"`
+		Missing *url.URL      `rencode:"missing"`
+	}
+	u, _ := url.Parse("http://example.com/announce?x=1")
+	value := standard{
+		IP:      net.ParseIP("10.0.0.1"),
+		URL:     u,
+		Number:  "42",
+		Big:     "123456789012345678901234567890",
+		Float:   "0.25",
+		Created: time.Unix(1426325213, 0).UTC(),
+		Timeout: 30 * time.Second,
+	}
+	data, err := Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var generic map[string]interface{}
+	err = Unmarshal(data, &generic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(generic["ip"].([]byte)) != "10.0.0.1" || string(generic["url"].([]byte)) != u.String() {
+		t.Fatalf("unexpected encoding %v", generic)
+	}
+	if generic["number"] != int8(42) || generic["float"] != 0.25 || generic["missing"] != nil {
+		t.Fatalf("unexpected encoding %v", generic)
+	}
+
+	var found standard
+	err = Unmarshal(data, &found)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found.IP.Equal(value.IP) || found.URL.String() != u.String() || found.Missing != nil ||
+		found.Number != value.Number || found.Big != value.Big || found.Float != value.Float ||
+		!found.Created.Equal(value.Created) || found.Timeout != value.Timeout {
+		t.Fatalf("expected %+v but %+v found", value, found)
+	}
+
+	// numeric strings are accepted as json.Number
+	var n json.Number
+	data, _ = Marshal("-1.5e3")
+	if err = Unmarshal(data, &n); err != nil || n != "-1.5e3" {
+		t.Fatalf("unexpected number %q (%v)", n, err)
+	}
+
+	for _, test := range []struct {
+		value  interface{}
+		target interface{}
+	}{
+		{"not a number", new(json.Number)},
+		{"300.1.1.1", new(net.IP)},
+		{"yesterday", new(time.Time)},
+		{true, new(time.Duration)},
+	} {
+		data, _ := Marshal(test.value)
+		if err := Unmarshal(data, test.target); err == nil {
+			t.Errorf("expected an error decoding %v into %T", test.value, test.target)
+		}
+	}
+	if _, err := Marshal(json.Number("x")); err == nil {
+		t.Error("expected an error encoding an invalid number")
+	}
+}
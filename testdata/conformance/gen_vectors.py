@@ -0,0 +1,281 @@
+#!/usr/bin/env python3
+#
+# go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+#                  object serialization similar to bencode
+# Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+#
+# This program is free software; you can redistribute it and/or
+# modify it under the terms of the GNU General Public License
+# as published by the Free Software Foundation; either version 2
+# of the License, or (at your option) any later version.
+#
+# Generates vectors.json, the conformance vectors used by conformance_test.go.
+#
+# The bytes are produced by dumps() of the reference implementation
+# (https://github.com/aresch/rencode, "pip install rencode"), which must be
+# importable. The --port option uses the encoder below instead, a port of the
+# reference pure-Python rencode.py, for working on the vectors offline. The
+# generator that was used is recorded in the output file; conformance_test.go
+# fails on vectors produced with --port, which would only check the Go
+# implementation against the port.
+#
+# Rejection vectors are values that the reference refuses to encode, along with
+# the encoding that a decoder must refuse; when the reference is importable,
+# both refusals are verified with its dumps() and loads().
+#
+# Values are described with single-key JSON objects:
+#   {"int": "<decimal>"}, {"float32": <number or "inf">}, {"float64": ...},
+#   {"str": "<text>"}, {"bytes": "<hex>"}, {"bool": true}, {"none": null},
+#   {"list": [<value>, ...]}, {"dict": [[<key>, <value>], ...]}
+
+import json
+import math
+import struct
+import sys
+
+CHR_LIST = 59
+CHR_DICT = 60
+CHR_INT = 61
+CHR_INT1 = 62
+CHR_INT2 = 63
+CHR_INT4 = 64
+CHR_INT8 = 65
+CHR_FLOAT32 = 66
+CHR_FLOAT64 = 44
+CHR_TRUE = 67
+CHR_FALSE = 68
+CHR_NONE = 69
+CHR_TERM = 127
+
+MAX_INT_LENGTH = 64
+INT_POS_FIXED_START = 0
+INT_POS_FIXED_COUNT = 44
+DICT_FIXED_START = 102
+DICT_FIXED_COUNT = 25
+INT_NEG_FIXED_START = 70
+INT_NEG_FIXED_COUNT = 32
+STR_FIXED_START = 128
+STR_FIXED_COUNT = 64
+LIST_FIXED_START = STR_FIXED_START + STR_FIXED_COUNT
+LIST_FIXED_COUNT = 64
+
+
+def port_dumps(x, float_bits=32):
+    r = bytearray()
+
+    def encode(x):
+        if x is None:
+            r.append(CHR_NONE)
+        elif isinstance(x, bool):
+            r.append(CHR_TRUE if x else CHR_FALSE)
+        elif isinstance(x, int):
+            if 0 <= x < INT_POS_FIXED_COUNT:
+                r.append(INT_POS_FIXED_START + x)
+            elif -INT_NEG_FIXED_COUNT <= x < 0:
+                r.append(INT_NEG_FIXED_START - 1 - x)
+            elif -128 <= x < 128:
+                r.append(CHR_INT1)
+                r.extend(struct.pack('!b', x))
+            elif -32768 <= x < 32768:
+                r.append(CHR_INT2)
+                r.extend(struct.pack('!h', x))
+            elif -2147483648 <= x < 2147483648:
+                r.append(CHR_INT4)
+                r.extend(struct.pack('!l', x))
+            elif -9223372036854775808 <= x < 9223372036854775808:
+                r.append(CHR_INT8)
+                r.extend(struct.pack('!q', x))
+            else:
+                s = str(x).encode()
+                if len(s) >= MAX_INT_LENGTH:
+                    raise ValueError('overflow')
+                r.append(CHR_INT)
+                r.extend(s)
+                r.append(CHR_TERM)
+        elif isinstance(x, float):
+            if float_bits == 32:
+                r.append(CHR_FLOAT32)
+                r.extend(struct.pack('!f', x))
+            else:
+                r.append(CHR_FLOAT64)
+                r.extend(struct.pack('!d', x))
+        elif isinstance(x, (str, bytes)):
+            if isinstance(x, str):
+                x = x.encode('utf8')
+            if len(x) < STR_FIXED_COUNT:
+                r.append(STR_FIXED_START + len(x))
+            else:
+                r.extend(str(len(x)).encode())
+                r.append(ord(':'))
+            r.extend(x)
+        elif isinstance(x, (list, tuple)):
+            if len(x) < LIST_FIXED_COUNT:
+                r.append(LIST_FIXED_START + len(x))
+                for i in x:
+                    encode(i)
+            else:
+                r.append(CHR_LIST)
+                for i in x:
+                    encode(i)
+                r.append(CHR_TERM)
+        elif isinstance(x, dict):
+            if len(x) < DICT_FIXED_COUNT:
+                r.append(DICT_FIXED_START + len(x))
+                for k, v in x.items():
+                    encode(k)
+                    encode(v)
+            else:
+                r.append(CHR_DICT)
+                for k, v in x.items():
+                    encode(k)
+                    encode(v)
+                r.append(CHR_TERM)
+        else:
+            raise TypeError(type(x))
+
+    encode(x)
+    return bytes(r)
+
+
+def to_python(spec):
+    (kind, v), = spec.items()
+    if kind == 'int':
+        return int(v)
+    if kind in ('float32', 'float64'):
+        return float(v)
+    if kind == 'str':
+        return v
+    if kind == 'bytes':
+        return bytes.fromhex(v)
+    if kind == 'bool':
+        return v
+    if kind == 'none':
+        return None
+    if kind == 'list':
+        return [to_python(e) for e in v]
+    if kind == 'dict':
+        return {to_python(k): to_python(e) for k, e in v}
+    raise ValueError(kind)
+
+
+def float_bits(spec):
+    (kind, v), = spec.items()
+    if kind == 'float64':
+        return 64
+    if kind == 'list':
+        v = [e for e in v]
+    elif kind == 'dict':
+        v = [e for pair in v for e in pair]
+    else:
+        return 32
+    for e in v:
+        if float_bits(e) == 64:
+            return 64
+    return 32
+
+
+def i(x):
+    return {'int': str(x)}
+
+
+def s(x):
+    return {'str': x}
+
+
+def vectors():
+    yield 'nil', {'none': None}
+    yield 'true', {'bool': True}
+    yield 'false', {'bool': False}
+
+    for x in [0, 1, 43, 44, 45, -1, -32, -33, 127, -128, 128, -129,
+              32767, -32768, 32768, -32769,
+              2**31 - 1, -2**31, 2**31, -2**31 - 1,
+              2**63 - 1, -2**63, 2**63, -2**63 - 1,
+              2**64 - 1, 2**64, -2**64, 10**40, -(10**40), 10**62]:
+        yield 'int %d' % x, i(x)
+
+    for x in [0.0, 1.5, -1234.5, 3.4028234663852886e38, 1e-45, 'inf', '-inf']:
+        yield 'float32 %s' % x, {'float32': x}
+    for x in [0.0, 1234.56, -1e300, 5e-324, 'inf']:
+        yield 'float64 %s' % x, {'float64': x}
+
+    for n in [0, 1, 63, 64, 65, 255, 1000]:
+        yield 'string length %d' % n, s('x' * n)
+    yield 'unicode string', s('fööbar')
+    yield 'cjk string', s('日本語')
+    yield 'binary string', {'bytes': '00ff807f'}
+
+    for n in [0, 1, 63, 64, 65]:
+        yield 'list length %d' % n, {'list': [i(k) for k in range(n)]}
+    yield 'nested lists', {'list': [{'list': []}, {'list': [{'list': []}]}]}
+    yield 'mixed list', {'list': [i(1), s('a'), {'none': None}, {'bool': True}, {'float32': 1.5}, i(-1000)]}
+    yield 'open list of lists', {'list': [{'list': [i(k)]} for k in range(70)]}
+
+    for n in [0, 1, 24, 25, 26]:
+        yield 'dict length %d' % n, {'dict': [[s('k%02d' % k), i(k * 1000)] for k in range(n)]}
+    yield 'integer keys', {'dict': [[i(1), s('a')], [i(-1), s('b')], [i(100000), s('c')]]}
+    yield 'nested dict', {'dict': [
+        [s('name'), s('debian.iso')],
+        [s('peers'), {'list': [{'dict': [[s('ip'), s('10.0.0.1')], [s('progress'), {'float64': 0.5}]]}]}],
+        [s('size'), i(2**40)],
+        [s('empty'), {'dict': []}],
+    ]}
+
+
+def big_number(digits):
+    return bytes([CHR_INT]) + digits.encode() + bytes([CHR_TERM])
+
+
+def rejections():
+    # numbers of MAX_INT_LENGTH characters or more, sign included
+    for name, x in [('64-digit int', 10**63), ('64-character negative int', -(10**63 - 1)), ('65-digit int', 10**64)]:
+        yield name, i(x), big_number(str(x))
+    # malformed encodings that cannot be produced by any value
+    yield 'big number with a non-digit', None, big_number('12x')
+    yield 'empty big number', None, big_number('')
+    yield 'truncated string', None, b'5:ab'
+    yield 'unterminated list', None, bytes([CHR_LIST, 1])
+
+
+def refused(fn, *args):
+    try:
+        fn(*args)
+    except Exception:
+        return True
+    return False
+
+
+def main():
+    loads = None
+    try:
+        import rencode
+        dumps, loads = rencode.dumps, rencode.loads
+        generator = 'rencode %s' % getattr(rencode, '__version__', '(unknown version)')
+    except ImportError:
+        if sys.argv[1:] != ['--port']:
+            sys.exit('the reference rencode module is not importable, install it with "pip install rencode"')
+        dumps = port_dumps
+        generator = 'port of the reference rencode.py encoder (gen_vectors.py --port)'
+
+    out = []
+    for name, spec in vectors():
+        data = dumps(to_python(spec), float_bits(spec))
+        out.append({'name': name, 'value': spec, 'hex': data.hex()})
+
+    rejects = []
+    for name, spec, data in rejections():
+        if spec is not None and not refused(dumps, to_python(spec)):
+            sys.exit('%s: value accepted by the encoder' % name)
+        if loads is not None and not refused(loads, data):
+            sys.exit('%s: encoding accepted by the decoder' % name)
+        reject = {'name': name, 'hex': data.hex()}
+        if spec is not None:
+            reject['value'] = spec
+        rejects.append(reject)
+
+    json.dump({'generator': generator, 'vectors': out, 'rejects': rejects}, sys.stdout, indent=1, ensure_ascii=False)
+    sys.stdout.write('\n')
+
+
+if __name__ == '__main__':
+    main()
@@ -0,0 +1,124 @@
+package rencode
+
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+import (
+	"fmt"
+)
+
+// Token holds a value of one of these types:
+// * ListStart, at the beginning of a list
+// * DictStart, at the beginning of a dictionary
+// * End, at the end of a list or dictionary
+// * any of the scalar values returned by DecodeNext
+type Token interface{}
+
+// ListStart is the token marking the beginning of a list of Length elements;
+// Length is -1 when the list is terminated rather than of fixed length.
+type ListStart struct {
+	Length int
+}
+
+// DictStart is the token marking the beginning of a dictionary of Length (key, value) pairs;
+// Length is -1 when the dictionary is terminated rather than of fixed length.
+type DictStart struct {
+	Length int
+}
+
+// End is the token marking the end of the current list or dictionary
+type End struct{}
+
+// Token returns the next token in the rencode stream, allowing to process
+// lists and dictionaries without decoding them as a whole.
+// DecodeNext can be called in place of Token to decode the next element of a container entirely.
+// If no more tokens are available, an io.EOF error will be returned.
+func (r *Decoder) Token() (Token, error) {
+	n := len(r.containers)
+	if n > 0 && r.containers[n-1] == 0 {
+		// fixed-length container is complete
+		r.containers = r.containers[:n-1]
+		r.tokenDone()
+		if r.hook != nil {
+			r.hookEnd()
+		}
+		return End{}, nil
+	}
+
+	typeCode, err := r.readByte()
+	if err != nil {
+		if n > 0 {
+			return nil, unexpectedEOF(err)
+		}
+		return nil, err
+	}
+
+	if kind := kindOf(typeCode); kind == KindList || kind == KindDict {
+		err = r.checkDepth()
+		if err != nil {
+			return nil, err
+		}
+		if r.hook != nil {
+			r.hookStart(typeCode)
+		}
+	}
+
+	switch {
+	case typeCode == CHR_TERM:
+		if n == 0 || r.containers[n-1] >= 0 {
+			return nil, fmt.Errorf("unexpected container terminator")
+		}
+		r.containers = r.containers[:n-1]
+		r.tokenDone()
+		if r.hook != nil {
+			r.hookEnd()
+		}
+		return End{}, nil
+	case typeCode == CHR_LIST:
+		r.containers = append(r.containers, -1)
+		return ListStart{-1}, nil
+	case typeCode == CHR_DICT:
+		r.containers = append(r.containers, -1)
+		return DictStart{-1}, nil
+	case LIST_FIXED_START <= typeCode && typeCode <= LIST_FIXED_START+LIST_FIXED_COUNT-1:
+		size := int(typeCode - LIST_FIXED_START)
+		r.containers = append(r.containers, size)
+		return ListStart{size}, nil
+	case DICT_FIXED_START <= typeCode && typeCode < DICT_FIXED_START+DICT_FIXED_COUNT:
+		size := int(typeCode - DICT_FIXED_START)
+		// both keys and values are counted
+		r.containers = append(r.containers, 2*size)
+		return DictStart{size}, nil
+	}
+
+	v, err := r.decode(typeCode)
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	r.tokenDone()
+	return v, nil
+}
+
+// tokenDone accounts for a complete value within the current fixed-length container, if any
+func (r *Decoder) tokenDone() {
+	n := len(r.containers)
+	if n > 0 && r.containers[n-1] > 0 {
+		r.containers[n-1]--
+	}
+}
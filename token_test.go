@@ -0,0 +1,179 @@
+package rencode
+
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestToken(t *testing.T) {
+	var inner, long List
+	inner.Add(int8(1))
+	inner.Add(int8(2))
+	for i := 0; i < 70; i++ {
+		long.Add(true)
+	}
+
+	var d Dictionary
+	err := d.Add("a", inner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = d.Add("b", long)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = d.Add("c", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := bytes.Buffer{}
+	e := NewEncoder(&b)
+	err = e.Encode(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = e.Encode(int8(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dec := NewDecoder(&b)
+	var tokens []string
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s, ok := tok.([]byte); ok {
+			tok = string(s)
+		}
+		tokens = append(tokens, fmt.Sprintf("%v", tok))
+	}
+
+	expected := "{3} a {2} 1 2 {} b {-1} " + strings.Repeat("true ", 70) + "{} c <nil> {} 5"
+	if found := strings.Join(tokens, " "); found != expected {
+		t.Fatalf("expected %s but %s found", expected, found)
+	}
+}
+
+func TestTokenMixedWithDecodeNext(t *testing.T) {
+	var inner, outer List
+	inner.Add(int8(1))
+	outer.Add(inner)
+	outer.Add([]byte("x"))
+
+	b := bytes.Buffer{}
+	e := NewEncoder(&b)
+	err := e.Encode(outer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDecoder(&b)
+	tok, err := d.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok != (ListStart{2}) {
+		t.Fatalf("expected list start but %v found", tok)
+	}
+
+	v, err := d.DecodeNext()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := v.(List); l.Length() != 1 {
+		t.Fatalf("unexpected nested list %v", l.Values())
+	}
+
+	v, err = d.DecodeNext()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(v.([]byte)) != "x" {
+		t.Fatalf("unexpected value %v", v)
+	}
+
+	tok, err = d.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok != (End{}) {
+		t.Fatalf("expected end but %v found", tok)
+	}
+}
+
+func TestEncodeContainerStreaming(t *testing.T) {
+	b := bytes.Buffer{}
+	e := NewEncoder(&b)
+
+	err := e.EncodeDictStart(-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 30; i++ {
+		err = e.Encode(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = e.EncodeListStart(-1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = e.Encode("v")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = e.EncodeListEnd(-1)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = e.EncodeDictEnd(-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDecoder(&b)
+	v, err := d.DecodeNext()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dict := v.(Dictionary)
+	if dict.Length() != 30 {
+		t.Fatalf("expected 30 pairs but %d found", dict.Length())
+	}
+	v, err = dict.Get(int8(29))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := v.(List); l.Length() != 1 {
+		t.Fatalf("unexpected list %v", l.Values())
+	}
+}
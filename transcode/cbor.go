@@ -0,0 +1,452 @@
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+package transcode
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/gdm85/go-rencode"
+)
+
+// CBOR major types
+const (
+	cborUint byte = iota << 5
+	cborNegInt
+	cborBytes
+	cborText
+	cborArray
+	cborMap
+	cborTag
+	cborSimple
+)
+
+const (
+	cborIndefinite = 31
+	cborBreak      = 0xff
+	cborFalse      = cborSimple | 20
+	cborTrue       = cborSimple | 21
+	cborNull       = cborSimple | 22
+	cborFloat16    = cborSimple | 25
+	cborFloat32    = cborSimple | 26
+	cborFloat64    = cborSimple | 27
+	// tags of positive and negative big numbers, and of self-described CBOR
+	cborTagPosBignum    = 2
+	cborTagNegBignum    = 3
+	cborTagSelfDescribe = 55799
+)
+
+// RencodeToCBOR converts the next value read from d to CBOR and writes it on w as it is converted;
+// part of the value may thus have been written when the conversion fails.
+// Lists and dictionaries of unknown length are written as indefinite-length arrays and maps.
+// If no more objects are available, an io.EOF error will be returned.
+func RencodeToCBOR(w io.Writer, d *rencode.Decoder) error {
+	var out []byte
+	// indefinite holds whether each open container has an indefinite length
+	var indefinite []bool
+	err := eachToken(d, func(tok rencode.Token) error {
+		switch x := tok.(type) {
+		case rencode.ListStart:
+			out = appendCBORContainer(out, cborArray, x.Length)
+			indefinite = append(indefinite, x.Length < 0)
+		case rencode.DictStart:
+			out = appendCBORContainer(out, cborMap, x.Length)
+			indefinite = append(indefinite, x.Length < 0)
+		case rencode.End:
+			if indefinite[len(indefinite)-1] {
+				out = append(out, cborBreak)
+			}
+			indefinite = indefinite[:len(indefinite)-1]
+		default:
+			var err error
+			out, err = appendCBORScalar(out, tok)
+			if err != nil {
+				return err
+			}
+		}
+		if len(out) >= chunkSize {
+			err := write(w, out)
+			out = out[:0]
+			return err
+		}
+		return nil
+	})
+	if err != nil || len(out) == 0 {
+		return err
+	}
+	return write(w, out)
+}
+
+// appendCBORHead appends the head of a data item of the specified major type and argument
+func appendCBORHead(b []byte, major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return append(b, major|byte(n))
+	case n <= math.MaxUint8:
+		return append(b, major|24, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, major|25), uint16(n))
+	case n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, major|26), uint32(n))
+	}
+	return binary.BigEndian.AppendUint64(append(b, major|27), n)
+}
+
+func appendCBORContainer(b []byte, major byte, n int) []byte {
+	if n < 0 {
+		return append(b, major|cborIndefinite)
+	}
+	return appendCBORHead(b, major, uint64(n))
+}
+
+func appendCBORInt(b []byte, i int64) []byte {
+	if i < 0 {
+		return appendCBORHead(b, cborNegInt, uint64(-1-i))
+	}
+	return appendCBORHead(b, cborUint, uint64(i))
+}
+
+// appendCBORBigInt appends an integer beyond 64 bits as a big number, or as an integer when
+// it fits in the argument of a negative integer
+func appendCBORBigInt(b []byte, x *big.Int) []byte {
+	if x.Sign() >= 0 {
+		if x.IsUint64() {
+			return appendCBORHead(b, cborUint, x.Uint64())
+		}
+		b = appendCBORHead(b, cborTag, cborTagPosBignum)
+		return appendCBORString(b, cborBytes, x.Bytes())
+	}
+	// the argument of negative integers is -1 - x
+	m := new(big.Int).Neg(x)
+	m.Sub(m, big.NewInt(1))
+	if m.IsUint64() {
+		return appendCBORHead(b, cborNegInt, m.Uint64())
+	}
+	b = appendCBORHead(b, cborTag, cborTagNegBignum)
+	return appendCBORString(b, cborBytes, m.Bytes())
+}
+
+func appendCBORString(b []byte, major byte, data []byte) []byte {
+	b = appendCBORHead(b, major, uint64(len(data)))
+	return append(b, data...)
+}
+
+func appendCBORScalar(b []byte, v interface{}) ([]byte, error) {
+	switch x := v.(type) {
+	case nil:
+		return append(b, cborNull), nil
+	case bool:
+		if x {
+			return append(b, cborTrue), nil
+		}
+		return append(b, cborFalse), nil
+	case int8:
+		return appendCBORInt(b, int64(x)), nil
+	case int16:
+		return appendCBORInt(b, int64(x)), nil
+	case int32:
+		return appendCBORInt(b, int64(x)), nil
+	case int64:
+		return appendCBORInt(b, x), nil
+	case uint64:
+		return appendCBORHead(b, cborUint, x), nil
+	case *big.Int:
+		return appendCBORBigInt(b, x), nil
+	case float32:
+		return binary.BigEndian.AppendUint32(append(b, cborFloat32), math.Float32bits(x)), nil
+	case float64:
+		return binary.BigEndian.AppendUint64(append(b, cborFloat64), math.Float64bits(x)), nil
+	case []byte:
+		if utf8.Valid(x) {
+			return appendCBORString(b, cborText, x), nil
+		}
+		return appendCBORString(b, cborBytes, x), nil
+	case string:
+		if utf8.ValidString(x) {
+			return appendCBORString(b, cborText, []byte(x)), nil
+		}
+		return appendCBORString(b, cborBytes, []byte(x)), nil
+	}
+	return nil, fmt.Errorf("transcode: unexpected token %T", v)
+}
+
+// CBORToRencode reads the next CBOR data item from r and encodes it with e.
+// Big numbers (tags 2 and 3) are converted to rencode integers and half-precision floats to float32;
+// the self-described CBOR tag is ignored and any other tag is reported as unrepresentable.
+// No data is read from r beyond the data item; r should be buffered as it is read in small parts.
+// If no more objects are available, an io.EOF error will be returned.
+func CBORToRencode(e *rencode.Encoder, r io.Reader) error {
+	cr := newReader(r)
+	return e.Encode(encoderFunc(func(e *rencode.Encoder) error {
+		initial, err := cr.readByte()
+		if err != nil {
+			return err
+		}
+		return cr.cborValue(e, initial)
+	}))
+}
+
+// readCBORArgument reads the argument of a data item from its additional information;
+// indefinite is returned for an indefinite length
+func (r *reader) readCBORArgument(info byte) (n uint64, indefinite bool, err error) {
+	switch {
+	case info < 24:
+		return uint64(info), false, nil
+	case info == cborIndefinite:
+		return 0, true, nil
+	case info > 27:
+		return 0, false, fmt.Errorf("transcode: invalid CBOR additional information %d", info)
+	}
+	data, err := r.readFixed(1 << (info - 24))
+	if err != nil {
+		return 0, false, err
+	}
+	for _, c := range data {
+		n = n<<8 | uint64(c)
+	}
+	return n, false, nil
+}
+
+func (r *reader) cborValue(e *rencode.Encoder, initial byte) error {
+	if initial == cborBreak {
+		return fmt.Errorf("transcode: unexpected CBOR break")
+	}
+	major, info := initial&0xe0, initial&0x1f
+	if major == cborSimple {
+		return r.cborSimple(e, info)
+	}
+
+	n, indefinite, err := r.readCBORArgument(info)
+	if err != nil {
+		return err
+	}
+	if indefinite && (major == cborUint || major == cborNegInt || major == cborTag) {
+		return fmt.Errorf("transcode: invalid CBOR additional information %d", info)
+	}
+
+	switch major {
+	case cborUint:
+		if n <= math.MaxInt64 {
+			return e.EncodeInt(int64(n))
+		}
+		return e.EncodeBigNumber(strconv.FormatUint(n, 10))
+	case cborNegInt:
+		if n <= math.MaxInt64 {
+			return e.EncodeInt(-1 - int64(n))
+		}
+		x := new(big.Int).SetUint64(n)
+		return e.EncodeBigInt(x.Neg(x).Sub(x, big.NewInt(1)))
+	case cborBytes, cborText:
+		data, err := r.cborString(major, n, indefinite)
+		if err != nil {
+			return err
+		}
+		return e.EncodeBytes(data)
+	case cborArray, cborMap:
+		return r.cborContainer(e, major, n, indefinite)
+	}
+	return r.cborTagged(e, n)
+}
+
+// cborString reads the content of a byte or text string, concatenating the chunks of indefinite-length ones
+func (r *reader) cborString(major byte, n uint64, indefinite bool) ([]byte, error) {
+	if !indefinite {
+		return r.readString(n)
+	}
+	var data []byte
+	for {
+		initial, err := r.readByte()
+		if err != nil {
+			return nil, err
+		}
+		if initial == cborBreak {
+			return data, nil
+		}
+		if initial&0xe0 != major || initial&0x1f == cborIndefinite {
+			return nil, fmt.Errorf("transcode: invalid chunk in CBOR indefinite-length string")
+		}
+		n, _, err := r.readCBORArgument(initial & 0x1f)
+		if err != nil {
+			return nil, err
+		}
+		if uint64(len(data))+n > maxStringLength {
+			return nil, fmt.Errorf("transcode: string of more than %d bytes is too long", maxStringLength)
+		}
+		chunk, err := r.readString(n)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, chunk...)
+	}
+}
+
+func (r *reader) cborContainer(e *rencode.Encoder, major byte, n uint64, indefinite bool) error {
+	err := r.enter()
+	if err != nil {
+		return err
+	}
+	defer r.leave()
+
+	length := int(n)
+	if indefinite || n > math.MaxInt32 {
+		length = -1
+	}
+	if major == cborMap {
+		err = e.EncodeDictStart(length)
+	} else {
+		err = e.EncodeListStart(length)
+	}
+	if err != nil {
+		return err
+	}
+
+	for i := uint64(0); indefinite || i < n; i++ {
+		initial, err := r.readByte()
+		if err != nil {
+			return err
+		}
+		if indefinite && initial == cborBreak {
+			break
+		}
+		err = r.cborValue(e, initial)
+		if err != nil {
+			return err
+		}
+		if major != cborMap {
+			continue
+		}
+		// the value of the key
+		initial, err = r.readByte()
+		if err != nil {
+			return err
+		}
+		if initial == cborBreak {
+			return fmt.Errorf("incomplete key-value pair in dictionary data")
+		}
+		err = r.cborValue(e, initial)
+		if err != nil {
+			return err
+		}
+	}
+
+	if major == cborMap {
+		return e.EncodeDictEnd(length)
+	}
+	return e.EncodeListEnd(length)
+}
+
+func (r *reader) cborTagged(e *rencode.Encoder, tag uint64) error {
+	switch tag {
+	case cborTagSelfDescribe:
+		initial, err := r.readByte()
+		if err != nil {
+			return err
+		}
+		return r.cborValue(e, initial)
+	case cborTagPosBignum, cborTagNegBignum:
+		initial, err := r.readByte()
+		if err != nil {
+			return err
+		}
+		if initial&0xe0 != cborBytes {
+			return fmt.Errorf("transcode: CBOR big number is not a byte string")
+		}
+		n, indefinite, err := r.readCBORArgument(initial & 0x1f)
+		if err != nil {
+			return err
+		}
+		data, err := r.cborString(cborBytes, n, indefinite)
+		if err != nil {
+			return err
+		}
+		x := new(big.Int).SetBytes(data)
+		if tag == cborTagNegBignum {
+			x.Neg(x).Sub(x, big.NewInt(1))
+		}
+		if s := x.String(); len(s) >= rencode.MAX_INT_LENGTH {
+			return &UnrepresentableError{Value: fmt.Sprintf("integer of %d digits", len(s)), Format: "rencode"}
+		}
+		return e.EncodeBigInt(x)
+	}
+	return &UnrepresentableError{Value: fmt.Sprintf("CBOR tag %d", tag), Format: "rencode"}
+}
+
+func (r *reader) cborSimple(e *rencode.Encoder, info byte) error {
+	switch info {
+	case cborFalse & 0x1f, cborTrue & 0x1f:
+		return e.EncodeBool(info == cborTrue&0x1f)
+	case cborNull & 0x1f:
+		return e.EncodeNone()
+	case cborFloat16 & 0x1f:
+		data, err := r.readFixed(2)
+		if err != nil {
+			return err
+		}
+		return e.EncodeFloat32(float16(binary.BigEndian.Uint16(data)))
+	case cborFloat32 & 0x1f:
+		data, err := r.readFixed(4)
+		if err != nil {
+			return err
+		}
+		return e.EncodeFloat32(math.Float32frombits(binary.BigEndian.Uint32(data)))
+	case cborFloat64 & 0x1f:
+		data, err := r.readFixed(8)
+		if err != nil {
+			return err
+		}
+		return e.EncodeFloat64(math.Float64frombits(binary.BigEndian.Uint64(data)))
+	case 23:
+		return &UnrepresentableError{Value: "CBOR undefined", Format: "rencode"}
+	case 24:
+		v, err := r.readFixed(1)
+		if err != nil {
+			return err
+		}
+		return &UnrepresentableError{Value: fmt.Sprintf("CBOR simple value %d", v[0]), Format: "rencode"}
+	case 28, 29, 30, cborIndefinite:
+		return fmt.Errorf("transcode: invalid CBOR additional information %d", info)
+	}
+	return &UnrepresentableError{Value: fmt.Sprintf("CBOR simple value %d", info), Format: "rencode"}
+}
+
+// float16 converts an IEEE 754 half-precision float, which float32 represents exactly
+func float16(h uint16) float32 {
+	sign := uint32(h>>15) << 31
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h) & 0x3ff
+	switch {
+	case exp == 0x1f:
+		// infinities and NaNs
+		return math.Float32frombits(sign | 0xff<<23 | mant<<13)
+	case exp == 0:
+		// zeros and subnormal numbers
+		f := float32(mant) / (1 << 24)
+		if sign != 0 {
+			f = -f
+		}
+		return f
+	}
+	return math.Float32frombits(sign | (exp+127-15)<<23 | mant<<13)
+}
@@ -0,0 +1,209 @@
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+package transcode
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/gdm85/go-rencode"
+)
+
+func bigInt(t *testing.T, s string) *big.Int {
+	x, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		t.Fatalf("invalid integer %s", s)
+	}
+	return x
+}
+
+// cborCases are from the examples of RFC 8949, appendix A
+func cborCases(t *testing.T) []struct {
+	value interface{}
+	hex   string
+} {
+	return []struct {
+		value interface{}
+		hex   string
+	}{
+		{0, "00"},
+		{23, "17"},
+		{24, "1818"},
+		{100, "1864"},
+		{1000, "1903e8"},
+		{int64(1000000000000), "1b000000e8d4a51000"},
+		{uint64(math.MaxUint64), "1bffffffffffffffff"},
+		{bigInt(t, "18446744073709551616"), "c249010000000000000000"},
+		{bigInt(t, "-18446744073709551616"), "3bffffffffffffffff"},
+		{bigInt(t, "-18446744073709551617"), "c349010000000000000000"},
+		{-1, "20"},
+		{-100, "3863"},
+		{float32(100000), "fa47c35000"},
+		{1.1, "fb3ff199999999999a"},
+		{math.Inf(-1), "fbfff0000000000000"},
+		{false, "f4"},
+		{true, "f5"},
+		{nil, "f6"},
+		{"", "60"},
+		{"ü", "62c3bc"},
+		{[]byte{0xff, 1}, "42ff01"},
+		{rencode.NewList(), "80"},
+		{rencode.NewList(1, rencode.NewList(2, 3), rencode.NewList(4, 5)), "8301820203820405"},
+		{rencode.NewDictionary(1, 2, 3, 4), "a201020304"},
+		{rencode.NewDictionary("a", 1, "b", rencode.NewList(2, 3)), "a26161016162820203"},
+	}
+}
+
+func TestRencodeToCBOR(t *testing.T) {
+	for _, tc := range cborCases(t) {
+		var b bytes.Buffer
+		err := RencodeToCBOR(&b, rencode.NewDecoder(bytes.NewReader(encodeRencode(t, tc.value))))
+		if err != nil {
+			t.Errorf("%v: %v", tc.value, err)
+			continue
+		}
+		if hex.EncodeToString(b.Bytes()) != tc.hex {
+			t.Errorf("%v: expected %s but %x found", tc.value, tc.hex, b.Bytes())
+		}
+	}
+
+	// lists of unknown length are written as indefinite-length arrays
+	var b bytes.Buffer
+	err := RencodeToCBOR(&b, rencode.NewDecoder(bytes.NewReader(encodeRencode(t, rencode.NewList(make([]interface{}, 64)...)))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "9f" + hex.EncodeToString(bytes.Repeat([]byte{0xf6}, 64)) + "ff"; hex.EncodeToString(b.Bytes()) != expected {
+		t.Errorf("expected %s but %x found", expected, b.Bytes())
+	}
+}
+
+func TestCBORToRencode(t *testing.T) {
+	cases := cborCases(t)
+	for _, tc := range []struct {
+		value interface{}
+		hex   string
+	}{
+		{float32(1), "f93c00"},
+		{float32(-4), "f9c400"},
+		{float32(5.960464477539063e-8), "f90001"},
+		{float32(math.Inf(1)), "f97c00"},
+		{rencode.NewList(), "9fff"},
+		{rencode.NewList(1, rencode.NewList(2, 3), rencode.NewList(4, 5)), "9f018202039f0405ffff"},
+		{rencode.NewDictionary("a", 1, "b", rencode.NewList(2, 3)), "bf61610161629f0203ffff"},
+		{"streaming", "7f657374726561646d696e67ff"},
+		{[]byte{1, 2, 3, 4, 5}, "5f42010243030405ff"},
+		{1, "d9d9f701"},
+	} {
+		cases = append(cases, tc)
+	}
+
+	for _, tc := range cases {
+		var b bytes.Buffer
+		e := rencode.NewEncoder(&b)
+		err := CBORToRencode(&e, bytes.NewReader(mustHex(t, tc.hex)))
+		if err != nil {
+			t.Errorf("%s: %v", tc.hex, err)
+			continue
+		}
+		checkRencode(t, b.Bytes(), tc.value)
+	}
+
+	var b bytes.Buffer
+	e := rencode.NewEncoder(&b)
+	err := CBORToRencode(&e, bytes.NewReader(mustHex(t, "f9fc00")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := rencode.NewDecoder(&b).DecodeNext(); v != float32(math.Inf(-1)) {
+		t.Errorf("expected float32 -Inf but %T %v found", v, v)
+	}
+}
+
+func TestCBORErrors(t *testing.T) {
+	for data, expected := range map[string]error{
+		"":       io.EOF,
+		"82":     io.ErrUnexpectedEOF,
+		"9f01":   io.ErrUnexpectedEOF,
+		"1903":   io.ErrUnexpectedEOF,
+		"6261":   io.ErrUnexpectedEOF,
+		"c249ff": io.ErrUnexpectedEOF,
+	} {
+		var b bytes.Buffer
+		e := rencode.NewEncoder(&b)
+		err := CBORToRencode(&e, bytes.NewReader(mustHex(t, data)))
+		if err != expected {
+			t.Errorf("%s: expected %v but %v found", data, expected, err)
+		}
+	}
+
+	for data, unrepresentable := range map[string]bool{
+		"f7":           true,
+		"82f0f8ff":     true,
+		"c11a514b67b0": true,
+		"c25820" + hex.EncodeToString(bytes.Repeat([]byte{0xff}, 32)): true,
+		"ff":             false,
+		"1c":             false,
+		"bf01ff":         false,
+		"7f6161416162ff": false,
+		"fe":             false,
+	} {
+		var b bytes.Buffer
+		e := rencode.NewEncoder(&b)
+		err := CBORToRencode(&e, bytes.NewReader(mustHex(t, data)))
+		var u *UnrepresentableError
+		if err == nil || errors.As(err, &u) != unrepresentable || b.Len() != 0 {
+			t.Errorf("%s: expected an error (unrepresentable %v) and no output but %v and %x found", data, unrepresentable, err, b.Bytes())
+		}
+	}
+}
+
+func TestCBORChunks(t *testing.T) {
+	// lists of unknown length are written as they are converted too
+	chunk := string(bytes.Repeat([]byte("x"), 1000))
+	values := make([]interface{}, 100)
+	for i := range values {
+		values[i] = chunk
+	}
+	value := rencode.NewList(rencode.NewList(values...), rencode.NewDictionary("a", 1))
+	var w countingWriter
+	err := RencodeToCBOR(&w, rencode.NewDecoder(bytes.NewReader(encodeRencode(t, value))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.writes != 4 {
+		t.Errorf("expected 4 writes but %d found", w.writes)
+	}
+
+	var b bytes.Buffer
+	e := rencode.NewEncoder(&b)
+	err = CBORToRencode(&e, &w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := rencode.NewDecoder(&b).DecodeNext(); !rencode.Equal(v, value) {
+		t.Errorf("expected %v but %v found", value, v)
+	}
+}
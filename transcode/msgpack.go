@@ -0,0 +1,410 @@
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+package transcode
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/gdm85/go-rencode"
+)
+
+const msgpackFormat = "MessagePack"
+
+// msgpackFrame is a list or dictionary being converted to MessagePack
+type msgpackFrame struct {
+	dict bool
+	// hole is the index of the header of a container of unknown length, filled once it ends
+	hole int
+	// count is the number of elements, keys and values counted separately, or -1 for a known length
+	count int
+}
+
+// msgpackHole is the position in the output of the header of a container of unknown length
+type msgpackHole struct {
+	at     int
+	header []byte
+}
+
+// RencodeToMsgpack converts the next value read from d to MessagePack and writes it on w.
+// The output is written as it is converted, except for lists and dictionaries of unknown length,
+// which are buffered until their end as MessagePack requires their length upfront; part of
+// the value may thus have been written when the conversion fails.
+// If no more objects are available, an io.EOF error will be returned.
+func RencodeToMsgpack(w io.Writer, d *rencode.Decoder) error {
+	var out []byte
+	var stack []msgpackFrame
+	var holes []msgpackHole
+	// open is the number of containers of unknown length in the stack
+	open := 0
+	// done accounts for a complete element of the current container
+	done := func() {
+		if n := len(stack); n > 0 && stack[n-1].count >= 0 {
+			stack[n-1].count++
+		}
+	}
+	err := eachToken(d, func(tok rencode.Token) error {
+		switch x := tok.(type) {
+		case rencode.ListStart:
+			if x.Length < 0 {
+				stack = append(stack, msgpackFrame{hole: len(holes)})
+				holes = append(holes, msgpackHole{at: len(out)})
+				open++
+				return nil
+			}
+			out = appendMsgpackHeader(out, 0x90, 0xdc, x.Length)
+			stack = append(stack, msgpackFrame{count: -1})
+		case rencode.DictStart:
+			if x.Length < 0 {
+				stack = append(stack, msgpackFrame{dict: true, hole: len(holes)})
+				holes = append(holes, msgpackHole{at: len(out)})
+				open++
+				return nil
+			}
+			out = appendMsgpackHeader(out, 0x80, 0xde, x.Length)
+			stack = append(stack, msgpackFrame{dict: true, count: -1})
+		case rencode.End:
+			f := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if f.count >= 0 {
+				if f.dict {
+					if f.count%2 != 0 {
+						return fmt.Errorf("incomplete key-value pair in dictionary data")
+					}
+					holes[f.hole].header = appendMsgpackHeader(nil, 0x80, 0xde, f.count/2)
+				} else {
+					holes[f.hole].header = appendMsgpackHeader(nil, 0x90, 0xdc, f.count)
+				}
+				open--
+				if open == 0 {
+					out = fillMsgpackHoles(out, holes)
+					holes = holes[:0]
+				}
+			}
+			done()
+		default:
+			var err error
+			out, err = appendMsgpackScalar(out, tok)
+			if err != nil {
+				return err
+			}
+			done()
+		}
+		// the output cannot be written while a header is missing
+		if open == 0 && len(out) >= chunkSize {
+			err := write(w, out)
+			out = out[:0]
+			return err
+		}
+		return nil
+	})
+	if err != nil || len(out) == 0 {
+		return err
+	}
+	return write(w, out)
+}
+
+// fillMsgpackHoles returns out with the headers of the holes inserted at their positions
+func fillMsgpackHoles(out []byte, holes []msgpackHole) []byte {
+	n := len(out)
+	for _, h := range holes {
+		n += len(h.header)
+	}
+	b := make([]byte, 0, n)
+	prev := 0
+	for _, h := range holes {
+		b = append(b, out[prev:h.at]...)
+		b = append(b, h.header...)
+		prev = h.at
+	}
+	return append(b, out[prev:]...)
+}
+
+// appendMsgpackHeader appends the header of an array or map of n elements; fix is the type byte
+// of the fixed-size variant and wide the one of the 16-bit length variant
+func appendMsgpackHeader(b []byte, fix, wide byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, fix|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, wide), uint16(n))
+	}
+	return binary.BigEndian.AppendUint32(append(b, wide+1), uint32(n))
+}
+
+func appendMsgpackUint(b []byte, u uint64) []byte {
+	switch {
+	case u <= math.MaxInt8:
+		return append(b, byte(u))
+	case u <= math.MaxUint8:
+		return append(b, 0xcc, byte(u))
+	case u <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xcd), uint16(u))
+	case u <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, 0xce), uint32(u))
+	}
+	return binary.BigEndian.AppendUint64(append(b, 0xcf), u)
+}
+
+func appendMsgpackInt(b []byte, i int64) []byte {
+	switch {
+	case i >= 0:
+		return appendMsgpackUint(b, uint64(i))
+	case i >= -32:
+		return append(b, byte(i))
+	case i >= math.MinInt8:
+		return append(b, 0xd0, byte(i))
+	case i >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(i))
+	case i >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(i))
+	}
+	return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(i))
+}
+
+// appendMsgpackString appends a str when data is valid UTF-8 and a bin otherwise
+func appendMsgpackString(b []byte, data []byte) []byte {
+	n := len(data)
+	if utf8.Valid(data) {
+		switch {
+		case n < 32:
+			b = append(b, 0xa0|byte(n))
+		case n <= math.MaxUint8:
+			b = append(b, 0xd9, byte(n))
+		case n <= math.MaxUint16:
+			b = binary.BigEndian.AppendUint16(append(b, 0xda), uint16(n))
+		default:
+			b = binary.BigEndian.AppendUint32(append(b, 0xdb), uint32(n))
+		}
+	} else {
+		switch {
+		case n <= math.MaxUint8:
+			b = append(b, 0xc4, byte(n))
+		case n <= math.MaxUint16:
+			b = binary.BigEndian.AppendUint16(append(b, 0xc5), uint16(n))
+		default:
+			b = binary.BigEndian.AppendUint32(append(b, 0xc6), uint32(n))
+		}
+	}
+	return append(b, data...)
+}
+
+func appendMsgpackScalar(b []byte, v interface{}) ([]byte, error) {
+	switch x := v.(type) {
+	case nil:
+		return append(b, 0xc0), nil
+	case bool:
+		if x {
+			return append(b, 0xc3), nil
+		}
+		return append(b, 0xc2), nil
+	case int8:
+		return appendMsgpackInt(b, int64(x)), nil
+	case int16:
+		return appendMsgpackInt(b, int64(x)), nil
+	case int32:
+		return appendMsgpackInt(b, int64(x)), nil
+	case int64:
+		return appendMsgpackInt(b, x), nil
+	case uint64:
+		return appendMsgpackUint(b, x), nil
+	case *big.Int:
+		return nil, &UnrepresentableError{Value: "integer " + x.String(), Format: msgpackFormat}
+	case float32:
+		return binary.BigEndian.AppendUint32(append(b, 0xca), math.Float32bits(x)), nil
+	case float64:
+		return binary.BigEndian.AppendUint64(append(b, 0xcb), math.Float64bits(x)), nil
+	case []byte:
+		return appendMsgpackString(b, x), nil
+	case string:
+		return appendMsgpackString(b, []byte(x)), nil
+	}
+	return nil, fmt.Errorf("transcode: unexpected token %T", v)
+}
+
+// MsgpackToRencode reads the next MessagePack value from r and encodes it with e.
+// No data is read from r beyond the value; r should be buffered as it is read in small parts.
+// If no more objects are available, an io.EOF error will be returned.
+func MsgpackToRencode(e *rencode.Encoder, r io.Reader) error {
+	mr := newReader(r)
+	return e.Encode(encoderFunc(func(e *rencode.Encoder) error {
+		return mr.msgpackValue(e)
+	}))
+}
+
+// readMsgpackLength reads a big-endian length of n bytes
+func (r *reader) readMsgpackLength(n int) (uint64, error) {
+	data, err := r.readFixed(n)
+	if err != nil {
+		return 0, err
+	}
+	switch n {
+	case 1:
+		return uint64(data[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(data)), nil
+	}
+	return uint64(binary.BigEndian.Uint32(data)), nil
+}
+
+func (r *reader) msgpackValue(e *rencode.Encoder) error {
+	t, err := r.readByte()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case t <= 0x7f:
+		return e.EncodeInt(int64(t))
+	case t >= 0xe0:
+		return e.EncodeInt(int64(int8(t)))
+	case t&0xf0 == 0x80:
+		return r.msgpackMap(e, uint64(t&0x0f))
+	case t&0xf0 == 0x90:
+		return r.msgpackArray(e, uint64(t&0x0f))
+	case t&0xe0 == 0xa0:
+		return r.msgpackString(e, uint64(t&0x1f))
+	}
+
+	switch t {
+	case 0xc0:
+		return e.EncodeNone()
+	case 0xc2, 0xc3:
+		return e.EncodeBool(t == 0xc3)
+	case 0xc4, 0xc5, 0xc6:
+		n, err := r.readMsgpackLength(1 << (t - 0xc4))
+		if err != nil {
+			return err
+		}
+		return r.msgpackString(e, n)
+	case 0xd9, 0xda, 0xdb:
+		n, err := r.readMsgpackLength(1 << (t - 0xd9))
+		if err != nil {
+			return err
+		}
+		return r.msgpackString(e, n)
+	case 0xca:
+		data, err := r.readFixed(4)
+		if err != nil {
+			return err
+		}
+		return e.EncodeFloat32(math.Float32frombits(binary.BigEndian.Uint32(data)))
+	case 0xcb:
+		data, err := r.readFixed(8)
+		if err != nil {
+			return err
+		}
+		return e.EncodeFloat64(math.Float64frombits(binary.BigEndian.Uint64(data)))
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		data, err := r.readFixed(1 << (t - 0xcc))
+		if err != nil {
+			return err
+		}
+		var u uint64
+		for _, c := range data {
+			u = u<<8 | uint64(c)
+		}
+		if u > math.MaxInt64 {
+			return e.EncodeBigNumber(strconv.FormatUint(u, 10))
+		}
+		return e.EncodeInt(int64(u))
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		n := 1 << (t - 0xd0)
+		data, err := r.readFixed(n)
+		if err != nil {
+			return err
+		}
+		var u uint64
+		for _, c := range data {
+			u = u<<8 | uint64(c)
+		}
+		// sign extension
+		shift := 64 - 8*n
+		return e.EncodeInt(int64(u<<shift) >> shift)
+	case 0xdc, 0xdd:
+		n, err := r.readMsgpackLength(2 << (t - 0xdc))
+		if err != nil {
+			return err
+		}
+		return r.msgpackArray(e, n)
+	case 0xde, 0xdf:
+		n, err := r.readMsgpackLength(2 << (t - 0xde))
+		if err != nil {
+			return err
+		}
+		return r.msgpackMap(e, n)
+	case 0xc7, 0xc8, 0xc9, 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return &UnrepresentableError{Value: "MessagePack extension type", Format: "rencode"}
+	}
+	return fmt.Errorf("transcode: invalid MessagePack type byte 0x%x", t)
+}
+
+func (r *reader) msgpackString(e *rencode.Encoder, n uint64) error {
+	data, err := r.readString(n)
+	if err != nil {
+		return err
+	}
+	return e.EncodeBytes(data)
+}
+
+func (r *reader) msgpackArray(e *rencode.Encoder, n uint64) error {
+	err := r.enter()
+	if err != nil {
+		return err
+	}
+	defer r.leave()
+
+	err = e.EncodeListStart(int(n))
+	if err != nil {
+		return err
+	}
+	for i := uint64(0); i < n; i++ {
+		err = r.msgpackValue(e)
+		if err != nil {
+			return err
+		}
+	}
+	return e.EncodeListEnd(int(n))
+}
+
+func (r *reader) msgpackMap(e *rencode.Encoder, n uint64) error {
+	err := r.enter()
+	if err != nil {
+		return err
+	}
+	defer r.leave()
+
+	err = e.EncodeDictStart(int(n))
+	if err != nil {
+		return err
+	}
+	for i := uint64(0); i < 2*n; i++ {
+		err = r.msgpackValue(e)
+		if err != nil {
+			return err
+		}
+	}
+	return e.EncodeDictEnd(int(n))
+}
@@ -0,0 +1,285 @@
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+package transcode
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/gdm85/go-rencode"
+)
+
+func encodeRencode(t *testing.T, values ...interface{}) []byte {
+	var b bytes.Buffer
+	e := rencode.NewEncoder(&b)
+	for _, v := range values {
+		err := e.Encode(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return b.Bytes()
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// checkRencode decodes a single rencode value and compares it with expected
+func checkRencode(t *testing.T, data []byte, expected interface{}) {
+	t.Helper()
+	d := rencode.NewDecoder(bytes.NewReader(data))
+	v, err := d.DecodeNext()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rencode.Equal(v, expected) {
+		t.Errorf("expected %v but %v found", expected, v)
+	}
+	if _, err = d.DecodeNext(); err != io.EOF {
+		t.Errorf("expected a single value but %v found after it", err)
+	}
+}
+
+var msgpackCases = []struct {
+	value interface{}
+	hex   string
+}{
+	{nil, "c0"},
+	{true, "c3"},
+	{false, "c2"},
+	{5, "05"},
+	{-1, "ff"},
+	{-32, "e0"},
+	{-33, "d0df"},
+	{200, "ccc8"},
+	{-1000, "d1fc18"},
+	{70000, "ce00011170"},
+	{int64(math.MinInt64), "d38000000000000000"},
+	{uint64(math.MaxUint64), "cfffffffffffffffff"},
+	{float32(1.5), "ca3fc00000"},
+	{1.1, "cb3ff199999999999a"},
+	{"abc", "a3616263"},
+	{[]byte{0xff, 0}, "c402ff00"},
+	{rencode.NewList(1, rencode.NewList(2, 3)), "9201920203"},
+	{rencode.NewDictionary("a", 1, 2, rencode.NewList()), "82a161010290"},
+}
+
+func TestRencodeToMsgpack(t *testing.T) {
+	for _, tc := range msgpackCases {
+		var b bytes.Buffer
+		err := RencodeToMsgpack(&b, rencode.NewDecoder(bytes.NewReader(encodeRencode(t, tc.value))))
+		if err != nil {
+			t.Errorf("%v: %v", tc.value, err)
+			continue
+		}
+		if hex.EncodeToString(b.Bytes()) != tc.hex {
+			t.Errorf("%v: expected %s but %x found", tc.value, tc.hex, b.Bytes())
+		}
+	}
+}
+
+func TestMsgpackToRencode(t *testing.T) {
+	for _, tc := range msgpackCases {
+		var b bytes.Buffer
+		e := rencode.NewEncoder(&b)
+		err := MsgpackToRencode(&e, bytes.NewReader(mustHex(t, tc.hex)))
+		if err != nil {
+			t.Errorf("%s: %v", tc.hex, err)
+			continue
+		}
+		checkRencode(t, b.Bytes(), tc.value)
+	}
+
+	// the widths of floats are kept
+	var b bytes.Buffer
+	e := rencode.NewEncoder(&b)
+	err := MsgpackToRencode(&e, bytes.NewReader(mustHex(t, "ca3fc00000")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := rencode.NewDecoder(&b).DecodeNext(); v != float32(1.5) {
+		t.Errorf("expected float32 1.5 but %T %v found", v, v)
+	}
+}
+
+func TestMsgpackUnknownLength(t *testing.T) {
+	var b bytes.Buffer
+	e := rencode.NewEncoder(&b)
+	e.SetAutoFlush(false)
+	for _, step := range []func() error{
+		func() error { return e.EncodeDictStart(-1) },
+		func() error { return e.Encode("list") },
+		func() error { return e.EncodeListStart(-1) },
+		func() error { return e.Encode(1) },
+		func() error { return e.EncodeListStart(-1) },
+		func() error { return e.EncodeListEnd(-1) },
+		func() error { return e.EncodeListEnd(-1) },
+		func() error { return e.Encode("x") },
+		func() error { return e.Encode(nil) },
+		func() error { return e.EncodeDictEnd(-1) },
+		e.Flush,
+	} {
+		if err := step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out bytes.Buffer
+	err := RencodeToMsgpack(&out, rencode.NewDecoder(&b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "82a46c697374920190a178c0"; hex.EncodeToString(out.Bytes()) != expected {
+		t.Errorf("expected %s but %x found", expected, out.Bytes())
+	}
+}
+
+func TestMsgpackStream(t *testing.T) {
+	values := []interface{}{
+		rencode.NewDictionary("peers", rencode.NewList(rencode.NewDictionary("ip", "10.0.0.1", "progress", 0.5))),
+		rencode.NewList(make([]interface{}, 70)...),
+		"done",
+	}
+	d := rencode.NewDecoder(bytes.NewReader(encodeRencode(t, values...)))
+	var msgpack bytes.Buffer
+	for {
+		err := RencodeToMsgpack(&msgpack, d)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var b bytes.Buffer
+	e := rencode.NewEncoder(&b)
+	r := bufio.NewReader(&msgpack)
+	for {
+		err := MsgpackToRencode(&e, r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	rd := rencode.NewDecoder(&b)
+	for _, expected := range values {
+		v, err := rd.DecodeNext()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !rencode.Equal(v, expected) {
+			t.Errorf("expected %v but %v found", expected, v)
+		}
+	}
+}
+
+// countingWriter counts the calls to Write
+type countingWriter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.Buffer.Write(p)
+}
+
+func TestMsgpackChunks(t *testing.T) {
+	chunk := string(bytes.Repeat([]byte("x"), 1000))
+	nested := rencode.NewList(make([]interface{}, 100)...)
+	for _, tc := range []struct {
+		value  interface{}
+		writes int
+	}{
+		// a list of known length is written as it is converted
+		{rencode.NewList(rencode.NewList(chunk, 1), chunk, chunk, chunk, chunk, chunk, chunk, chunk, chunk, chunk, chunk, chunk, chunk, chunk, chunk, chunk, chunk, chunk, chunk, chunk, chunk, chunk, chunk, chunk, chunk, chunk, chunk, chunk, chunk, chunk, chunk, chunk, chunk, chunk, chunk, chunk, chunk, chunk, chunk, chunk, chunk), 2},
+		// a list of unknown length is buffered until its end
+		{rencode.NewList(make([]interface{}, 100)...), 1},
+		{rencode.NewList(chunk, rencode.NewList(nested, nested, rencode.NewList(nested), rencode.NewList()), rencode.NewDictionary("a", nested)), 1},
+	} {
+		var w countingWriter
+		err := RencodeToMsgpack(&w, rencode.NewDecoder(bytes.NewReader(encodeRencode(t, tc.value))))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w.writes != tc.writes {
+			t.Errorf("expected %d writes but %d found", tc.writes, w.writes)
+		}
+
+		var b bytes.Buffer
+		e := rencode.NewEncoder(&b)
+		err = MsgpackToRencode(&e, &w)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v, _ := rencode.NewDecoder(&b).DecodeNext(); !rencode.Equal(v, tc.value) {
+			t.Errorf("expected %v but %v found", tc.value, v)
+		}
+	}
+}
+
+func TestMsgpackErrors(t *testing.T) {
+	var out bytes.Buffer
+	big70 := new(big.Int).Lsh(big.NewInt(1), 70)
+	err := RencodeToMsgpack(&out, rencode.NewDecoder(bytes.NewReader(encodeRencode(t, rencode.NewList(1, big70)))))
+	var unrepresentable *UnrepresentableError
+	if !errors.As(err, &unrepresentable) || out.Len() != 0 {
+		t.Errorf("expected an UnrepresentableError and no output but %v and %x found", err, out.Bytes())
+	}
+
+	for data, expected := range map[string]error{
+		"":         io.EOF,
+		"92":       io.ErrUnexpectedEOF,
+		"9201":     io.ErrUnexpectedEOF,
+		"a3616263": nil,
+		"a36162":   io.ErrUnexpectedEOF,
+		"cd01":     io.ErrUnexpectedEOF,
+	} {
+		var b bytes.Buffer
+		e := rencode.NewEncoder(&b)
+		err := MsgpackToRencode(&e, bytes.NewReader(mustHex(t, data)))
+		if err != expected {
+			t.Errorf("%s: expected %v but %v found", data, expected, err)
+		}
+	}
+
+	for _, data := range []string{"91d40100", "c1", "c70100"} {
+		var b bytes.Buffer
+		e := rencode.NewEncoder(&b)
+		err := MsgpackToRencode(&e, bytes.NewReader(mustHex(t, data)))
+		if err == nil || b.Len() != 0 {
+			t.Errorf("%s: expected an error and no output but %v and %x found", data, err, b.Bytes())
+		}
+	}
+}
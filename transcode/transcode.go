@@ -0,0 +1,192 @@
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+/*
+Package transcode converts values between rencode and the MessagePack and CBOR formats.
+
+The conversions are streaming: values are read token by token from a rencode.Decoder or from
+a MessagePack or CBOR reader and written as they are read, without building rencode.List or
+rencode.Dictionary values. Each call converts a single value. MessagePack and CBOR output is written
+in chunks as it is converted, so part of a value may have been written when its conversion fails;
+MessagePack output is buffered while a list or dictionary whose length is not known yet is open,
+as MessagePack requires lengths upfront, while CBOR writes those as indefinite-length containers.
+Conversions to rencode are discarded by the rencode.Encoder when they fail.
+
+rencode integers of any width, float32 and float64 values, None, booleans, lists and dictionaries
+with arbitrary keys have a direct equivalent in both formats. rencode strings are written as text
+strings when they are valid UTF-8 and as binary strings otherwise, and both are read back as rencode
+strings. Values without an equivalent, such as MessagePack extension types or integers beyond
+64 bits in MessagePack, are reported with an *UnrepresentableError.
+*/
+package transcode
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/gdm85/go-rencode"
+)
+
+// UnrepresentableError is returned for a value that cannot be represented in the target format
+type UnrepresentableError struct {
+	// Value describes the value
+	Value string
+	// Format is the name of the target format
+	Format string
+}
+
+func (e *UnrepresentableError) Error() string {
+	return fmt.Sprintf("transcode: %s cannot be represented in %s", e.Value, e.Format)
+}
+
+// encoderFunc is a rencode.Marshaler encoding a value with a function, so that a failed
+// conversion is discarded by rencode.Encoder.Encode
+type encoderFunc func(e *rencode.Encoder) error
+
+func (f encoderFunc) EncodeRencode(e *rencode.Encoder) error {
+	return f(e)
+}
+
+// eachToken calls fn with the tokens of the next value read from d
+func eachToken(d *rencode.Decoder, fn func(tok rencode.Token) error) error {
+	depth := 0
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			if depth > 0 && err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		switch tok.(type) {
+		case rencode.ListStart, rencode.DictStart:
+			depth++
+		case rencode.End:
+			depth--
+		}
+		err = fn(tok)
+		if err != nil {
+			return err
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// chunkSize is the size of the converted output above which it is written
+const chunkSize = 32 << 10
+
+// write writes data on w with a single call
+func write(w io.Writer, data []byte) error {
+	n, err := w.Write(data)
+	if err == nil && n < len(data) {
+		err = io.ErrShortWrite
+	}
+	return err
+}
+
+// reader reads the bytes of a MessagePack or CBOR value, never beyond the end of the value
+type reader struct {
+	r     io.Reader
+	br    io.ByteReader
+	depth int
+	// started is set once the first byte of the value is read
+	started bool
+	scratch [8]byte
+}
+
+func newReader(r io.Reader) *reader {
+	br, _ := r.(io.ByteReader)
+	return &reader{r: r, br: br}
+}
+
+// eof returns the error for the end of the input, which is unexpected within a value
+func (r *reader) eof(err error) error {
+	if err == io.EOF && r.started {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func (r *reader) readByte() (byte, error) {
+	var b byte
+	var err error
+	if r.br != nil {
+		b, err = r.br.ReadByte()
+	} else {
+		_, err = io.ReadFull(r.r, r.scratch[:1])
+		b = r.scratch[0]
+	}
+	if err != nil {
+		return 0, r.eof(err)
+	}
+	r.started = true
+	return b, nil
+}
+
+// readFixed reads the next n bytes, n being at most 8; the returned slice is valid until the next read
+func (r *reader) readFixed(n int) ([]byte, error) {
+	_, err := io.ReadFull(r.r, r.scratch[:n])
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return r.scratch[:n], err
+}
+
+// readString reads the next n bytes; the buffer grows as data is read, so that a bogus length
+// does not cause a large allocation
+func (r *reader) readString(n uint64) ([]byte, error) {
+	if n > maxStringLength {
+		return nil, fmt.Errorf("transcode: string of %d bytes is too long", n)
+	}
+	var buf []byte
+	for uint64(len(buf)) < n {
+		chunk := n - uint64(len(buf))
+		if chunk > 64<<10 {
+			chunk = 64 << 10
+		}
+		start := len(buf)
+		buf = append(buf, make([]byte, chunk)...)
+		_, err := io.ReadFull(r.r, buf[start:])
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
+// maxStringLength is the length of the longest string accepted, the largest length of MessagePack strings
+const maxStringLength uint64 = 1<<32 - 1
+
+// enter accounts for a list or dictionary, see leave
+func (r *reader) enter() error {
+	if r.depth >= rencode.DefaultMaxDepth {
+		return fmt.Errorf("transcode: maximum nesting depth of %d exceeded", rencode.DefaultMaxDepth)
+	}
+	r.depth++
+	return nil
+}
+
+func (r *reader) leave() {
+	r.depth--
+}
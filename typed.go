@@ -0,0 +1,251 @@
+package rencode
+
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// TypedList is a list whose values all have type T; it is encoded exactly like a List
+type TypedList[T any] struct {
+	values []T
+}
+
+// Add appends a new value to the list
+func (l *TypedList[T]) Add(value T) {
+	l.values = append(l.values, value)
+}
+
+// Values returns all values in the list
+func (l *TypedList[T]) Values() []T {
+	return l.values
+}
+
+// Get returns the value defined in the list at specific index
+func (l *TypedList[T]) Get(i int) (T, error) {
+	if i < 0 || i >= len(l.values) {
+		var zero T
+		return zero, ErrKeyNotFound
+	}
+
+	return l.values[i], nil
+}
+
+// Length returns the total count of elements
+func (l *TypedList[T]) Length() int {
+	return len(l.values)
+}
+
+// EncodeRencode implements Marshaler
+func (l TypedList[T]) EncodeRencode(e *Encoder) error {
+	err := e.EncodeListStart(len(l.values))
+	if err != nil {
+		return err
+	}
+	for _, v := range l.values {
+		err = e.Encode(v)
+		if err != nil {
+			return err
+		}
+	}
+	return e.EncodeListEnd(len(l.values))
+}
+
+// DecodeRencode implements Unmarshaler; values are converted to T as described by Decoder.Decode
+// and errors report the index of the offending value. A None value decodes as an empty list.
+func (l *TypedList[T]) DecodeRencode(d *Decoder) error {
+	l.values = nil
+	ok, err := d.DecodeListStart()
+	if err != nil || !ok {
+		return err
+	}
+	for i := 0; ; i++ {
+		more, err := d.More()
+		if err != nil || !more {
+			return err
+		}
+		var v T
+		err = d.decodeValue(reflect.ValueOf(&v).Elem())
+		if err != nil {
+			return fmt.Errorf("list value %d: %w", i, err)
+		}
+		l.values = append(l.values, v)
+	}
+}
+
+// ConvertList returns a TypedList with the values of l converted to T as described by Decoder.Decode
+func ConvertList[T any](l *List) (TypedList[T], error) {
+	t := TypedList[T]{values: make([]T, len(l.values))}
+	for i, v := range l.values {
+		err := assign(reflect.ValueOf(&t.values[i]).Elem(), v)
+		if err != nil {
+			return TypedList[T]{}, fmt.Errorf("list value %d: %w", i, err)
+		}
+	}
+	return t, nil
+}
+
+// TypedDict is a dictionary whose keys all have type K and values all have type V;
+// it preserves insertion order and is encoded exactly like a Dictionary
+type TypedDict[K comparable, V any] struct {
+	keys   []K
+	values []V
+}
+
+// Keys returns all defined keys
+func (d *TypedDict[K, V]) Keys() []K {
+	return d.keys
+}
+
+// Values returns all values, in the same order as their keys
+func (d *TypedDict[K, V]) Values() []V {
+	return d.values
+}
+
+// Length returns the total count of (key, value) pairs
+func (d *TypedDict[K, V]) Length() int {
+	return len(d.keys)
+}
+
+// index returns the position of key, or -1 if it is not defined. Keys are compared as in Dictionary,
+// as those of an interface type such as interface{} can hold decoded values that == cannot compare.
+func (d *TypedDict[K, V]) index(key K) int {
+	for i, k := range d.keys {
+		if keyEqual(k, key) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Get returns the value stored for the matching key
+func (d *TypedDict[K, V]) Get(key K) (V, error) {
+	if i := d.index(key); i >= 0 {
+		return d.values[i], nil
+	}
+	var zero V
+	return zero, ErrKeyNotFound
+}
+
+// Set updates or add the specified key with the specified value and returns true if a previous value was overwritten
+func (d *TypedDict[K, V]) Set(key K, value V) bool {
+	if i := d.index(key); i >= 0 {
+		d.values[i] = value
+		return true
+	}
+
+	d.keys = append(d.keys, key)
+	d.values = append(d.values, value)
+	return false
+}
+
+// Delete removes the specified key and its value and returns true if the key was found
+func (d *TypedDict[K, V]) Delete(key K) bool {
+	i := d.index(key)
+	if i < 0 {
+		return false
+	}
+	d.keys = append(d.keys[:i], d.keys[i+1:]...)
+	d.values = append(d.values[:i], d.values[i+1:]...)
+	return true
+}
+
+// Add appends a new (key, value) pair or returns an error if key already exists
+func (d *TypedDict[K, V]) Add(key K, value V) error {
+	if d.index(key) >= 0 {
+		return ErrKeyAlreadyExists
+	}
+
+	d.keys = append(d.keys, key)
+	d.values = append(d.values, value)
+	return nil
+}
+
+// EncodeRencode implements Marshaler
+func (d TypedDict[K, V]) EncodeRencode(e *Encoder) error {
+	err := e.EncodeDictStart(len(d.keys))
+	if err != nil {
+		return err
+	}
+	for i, k := range d.keys {
+		err = e.Encode(k)
+		if err != nil {
+			return err
+		}
+		err = e.Encode(d.values[i])
+		if err != nil {
+			return err
+		}
+	}
+	return e.EncodeDictEnd(len(d.keys))
+}
+
+// DecodeRencode implements Unmarshaler; keys and values are converted to K and V as described by Decoder.Decode
+// and errors report the index of the offending pair. A None value decodes as an empty dictionary.
+func (d *TypedDict[K, V]) DecodeRencode(dec *Decoder) error {
+	d.keys, d.values = nil, nil
+	ok, err := dec.DecodeDictStart()
+	if err != nil || !ok {
+		return err
+	}
+	for i := 0; ; i++ {
+		more, err := dec.More()
+		if err != nil || !more {
+			return err
+		}
+		var k K
+		err = dec.decodeValue(reflect.ValueOf(&k).Elem())
+		if err != nil {
+			return fmt.Errorf("dictionary key %d: %w", i, err)
+		}
+		var v V
+		err = dec.decodeValue(reflect.ValueOf(&v).Elem())
+		if err != nil {
+			return fmt.Errorf("dictionary value %d: %w", i, err)
+		}
+		err = d.Add(k, v)
+		if err != nil {
+			return fmt.Errorf("dictionary key %d: %w", i, err)
+		}
+	}
+}
+
+// ConvertDict returns a TypedDict with the keys and values of d converted to K and V as described by Decoder.Decode
+func ConvertDict[K comparable, V any](d *Dictionary) (TypedDict[K, V], error) {
+	var t TypedDict[K, V]
+	for i, key := range d.keys {
+		var k K
+		err := assign(reflect.ValueOf(&k).Elem(), key)
+		if err != nil {
+			return TypedDict[K, V]{}, fmt.Errorf("dictionary key %d: %w", i, err)
+		}
+		var v V
+		err = assign(reflect.ValueOf(&v).Elem(), d.values[i])
+		if err != nil {
+			return TypedDict[K, V]{}, fmt.Errorf("dictionary value %d: %w", i, err)
+		}
+		err = t.Add(k, v)
+		if err != nil {
+			return TypedDict[K, V]{}, fmt.Errorf("dictionary key %d: %w", i, err)
+		}
+	}
+	return t, nil
+}
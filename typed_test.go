@@ -0,0 +1,191 @@
+package rencode
+
+//
+// go-rencode v0.1.0 - Go implementation of rencode - fast (basic)
+//                  object serialization similar to bencode
+// Copyright (C) 2015 gdm85 - https://github.com/gdm85/go-rencode/
+
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTypedListWireCompatible(t *testing.T) {
+	var typed TypedList[int16]
+	var untyped List
+	for _, v := range []int16{1, -300, 1000} {
+		typed.Add(v)
+		untyped.Add(v)
+	}
+
+	b1, err := Marshal(typed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b2, err := Marshal(untyped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b1, b2) {
+		t.Fatalf("typed encoding %v differs from %v", b1, b2)
+	}
+
+	// decoding converts the narrower wire integers back to int16
+	var found TypedList[int16]
+	err = Unmarshal(b2, &found)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(found.Values(), typed.Values()) {
+		t.Fatalf("expected %v but %v found", typed.Values(), found.Values())
+	}
+
+	converted, err := ConvertList[int64](&untyped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := converted.Get(1); v != -300 {
+		t.Fatalf("unexpected converted value %v", v)
+	}
+}
+
+func TestTypedListElementError(t *testing.T) {
+	data, err := Marshal([]interface{}{1, 2, "three"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var l TypedList[int]
+	err = Unmarshal(data, &l)
+	if err == nil || !strings.Contains(err.Error(), "list value 2") {
+		t.Fatalf("expected an error for list value 2, got %v", err)
+	}
+	var typeErr *UnmarshalTypeError
+	if !errors.As(err, &typeErr) {
+		t.Fatalf("unexpected error type %T", err)
+	}
+
+	var untyped List
+	untyped.Add(int8(1))
+	untyped.Add(int16(300))
+	_, err = ConvertList[uint8](&untyped)
+	if err == nil || !strings.Contains(err.Error(), "list value 1") {
+		t.Fatalf("expected an error for list value 1, got %v", err)
+	}
+}
+
+func TestTypedDict(t *testing.T) {
+	var typed TypedDict[string, []int8]
+	var untyped Dictionary
+	for _, k := range []string{"b", "a", "c"} {
+		v := []int8{int8(len(k)), -1}
+		typed.Add(k, v)
+		untyped.Add(k, v)
+	}
+	if err := typed.Add("a", nil); err != ErrKeyAlreadyExists {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	b1, err := Marshal(typed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b2, err := Marshal(untyped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b1, b2) {
+		t.Fatalf("typed encoding %v differs from %v", b1, b2)
+	}
+
+	// wrapped in a struct and behind a pointer
+	type wrapper struct {
+		D *TypedDict[string, []int8]
+		N *TypedDict[string, []int8]
+	}
+	data, err := Marshal(wrapper{D: &typed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var w wrapper
+	err = Unmarshal(data, &w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.N != nil || !reflect.DeepEqual(w.D.Keys(), []string{"b", "a", "c"}) {
+		t.Fatalf("unexpected value %+v", w)
+	}
+	v, err := w.D.Get("c")
+	if err != nil || !reflect.DeepEqual(v, []int8{1, -1}) {
+		t.Fatalf("unexpected value %v (%v)", v, err)
+	}
+
+	converted, err := ConvertDict[string, []int](&untyped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !converted.Delete("b") || converted.Length() != 2 {
+		t.Fatalf("unexpected dictionary %v", converted.Keys())
+	}
+
+	// the value of the second pair is not a list
+	untyped.Set("a", "x")
+	_, err = ConvertDict[string, []int](&untyped)
+	if err == nil || !strings.Contains(err.Error(), "dictionary value 1") {
+		t.Fatalf("expected an error for dictionary value 1, got %v", err)
+	}
+	data, err = Marshal(untyped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found TypedDict[string, []int]
+	err = Unmarshal(data, &found)
+	if err == nil || !strings.Contains(err.Error(), "dictionary value 1") {
+		t.Fatalf("expected an error for dictionary value 1, got %v", err)
+	}
+}
+
+func TestTypedDictInterfaceKeys(t *testing.T) {
+	data, err := Marshal(NewDictionary("a", 1, "b", 2, NewList(1), 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the keys decode as []byte and List, which == cannot compare
+	var d TypedDict[any, int]
+	err = Unmarshal(data, &d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		key      any
+		expected int
+	}{{"a", 1}, {[]byte("b"), 2}, {NewList(1), 3}} {
+		v, err := d.Get(tc.key)
+		if err != nil || v != tc.expected {
+			t.Errorf("%v: expected %d but %d (%v) found", tc.key, tc.expected, v, err)
+		}
+	}
+	if err := d.Add([]byte("a"), 0); err != ErrKeyAlreadyExists {
+		t.Errorf("expected ErrKeyAlreadyExists but %v found", err)
+	}
+	if !d.Set(NewList(1), 4) || !d.Delete("b") || d.Delete([]int{1}) || d.Length() != 2 {
+		t.Errorf("unexpected dictionary %v", d.Keys())
+	}
+}